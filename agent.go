@@ -0,0 +1,204 @@
+package tcecdsa
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"fmt"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"io"
+	"math/big"
+	"net"
+	"sync"
+)
+
+// sshHashes maps the curve names to the hash functions used by their SSH signature algorithms (RFC 5656).
+var sshHashes = map[string]crypto.Hash{
+	"P-256": crypto.SHA256,
+	"P-384": crypto.SHA384,
+	"P-521": crypto.SHA512,
+}
+
+// SigTransport exchanges the messages of a signing session between the participants of a threshold key.
+// Every Exchange method sends the message of the local participant to the others and returns the messages
+// of all the participants of the session, including the local one.
+type SigTransport interface {
+	// Begin asks the other participants to start a signing session over the hash h.
+	Begin(h []byte) error
+	// ExchangeRound1 exchanges the messages produced by Round1.
+	ExchangeRound1(msg *Round1Message) (Round1MessageList, error)
+	// ExchangeRound2 exchanges the messages produced by Round2.
+	ExchangeRound2(msg *Round2Message) (Round2MessageList, error)
+	// ExchangeRound3 exchanges the messages produced by Round3.
+	ExchangeRound3(msg *Round3Message) (Round3MessageList, error)
+}
+
+// SignWithTransport signs the hash h with the key share, driving a SigSession whose messages are
+// exchanged with the other participants using the transport.
+func (p *KeyShare) SignWithTransport(meta *KeyMeta, h []byte, transport SigTransport) (r, s *big.Int, err error) {
+	state, err := p.NewSigSession(meta, h)
+	if err != nil {
+		return
+	}
+	if err = transport.Begin(h); err != nil {
+		return
+	}
+	msg1, err := state.Round1()
+	if err != nil {
+		return
+	}
+	msgs1, err := transport.ExchangeRound1(msg1)
+	if err != nil {
+		return
+	}
+	msg2, err := state.Round2(msgs1)
+	if err != nil {
+		return
+	}
+	msgs2, err := transport.ExchangeRound2(msg2)
+	if err != nil {
+		return
+	}
+	msg3, err := state.Round3(msgs2)
+	if err != nil {
+		return
+	}
+	msgs3, err := transport.ExchangeRound3(msg3)
+	if err != nil {
+		return
+	}
+	return state.GetSignature(msgs3)
+}
+
+// Agent is an ssh-agent backed by a threshold key. It advertises only the threshold public key and
+// answers sign requests by running a signing session with the other participants through a SigTransport.
+// Keys cannot be added or removed, and the agent cannot be locked.
+// Only one signing session is run at a time.
+type Agent struct {
+	share     *KeyShare
+	meta      *KeyMeta
+	key       ssh.PublicKey
+	comment   string
+	transport SigTransport
+	mutex     sync.Mutex
+}
+
+// NewAgent returns a new ssh-agent for the threshold key. pk is the public key returned by
+// KeyMeta.GetPublicKey, and it must be equal to the public key of the share.
+// Only P-256, P-384 and P-521 curves are supported by SSH.
+func NewAgent(share *KeyShare, meta *KeyMeta, pk *ecdsa.PublicKey, transport SigTransport, comment string) (a *Agent, err error) {
+	if _, ok := sshHashes[meta.CurveName]; !ok {
+		err = fmt.Errorf("curve %s is not supported by SSH", meta.CurveName)
+		return
+	}
+	if err = share.CheckPublicKey(meta, pk); err != nil {
+		return
+	}
+	key, err := ssh.NewPublicKey(pk)
+	if err != nil {
+		return
+	}
+	a = &Agent{
+		share:     share,
+		meta:      meta,
+		key:       key,
+		comment:   comment,
+		transport: transport,
+	}
+	return
+}
+
+// List returns the threshold public key.
+func (a *Agent) List() ([]*agent.Key, error) {
+	return []*agent.Key{
+		{
+			Format:  a.key.Type(),
+			Blob:    a.key.Marshal(),
+			Comment: a.comment,
+		},
+	}, nil
+}
+
+// Sign signs data with the threshold key, returning the signature in SSH format.
+func (a *Agent) Sign(key ssh.PublicKey, data []byte) (*ssh.Signature, error) {
+	if !bytes.Equal(key.Marshal(), a.key.Marshal()) {
+		return nil, fmt.Errorf("key not found")
+	}
+	hash := sshHashes[a.meta.CurveName].New()
+	hash.Write(data)
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	r, s, err := a.share.SignWithTransport(a.meta, hash.Sum(nil), a.transport)
+	if err != nil {
+		return nil, err
+	}
+	blob := ssh.Marshal(struct {
+		R *big.Int
+		S *big.Int
+	}{r, s})
+	return &ssh.Signature{
+		Format: a.key.Type(),
+		Blob:   blob,
+	}, nil
+}
+
+// Add is not supported, because the agent only holds the threshold key.
+func (a *Agent) Add(_ agent.AddedKey) error {
+	return fmt.Errorf("adding keys is not supported by threshold agent")
+}
+
+// Remove is not supported, because the agent only holds the threshold key.
+func (a *Agent) Remove(_ ssh.PublicKey) error {
+	return fmt.Errorf("removing keys is not supported by threshold agent")
+}
+
+// RemoveAll is not supported, because the agent only holds the threshold key.
+func (a *Agent) RemoveAll() error {
+	return fmt.Errorf("removing keys is not supported by threshold agent")
+}
+
+// Lock is not supported by the threshold agent.
+func (a *Agent) Lock(_ []byte) error {
+	return fmt.Errorf("locking is not supported by threshold agent")
+}
+
+// Unlock is not supported by the threshold agent.
+func (a *Agent) Unlock(_ []byte) error {
+	return fmt.Errorf("locking is not supported by threshold agent")
+}
+
+// Signers returns a ssh.Signer that signs using the threshold agent.
+func (a *Agent) Signers() ([]ssh.Signer, error) {
+	return []ssh.Signer{&agentSigner{a}}, nil
+}
+
+// Serve accepts connections on the listener (usually a Unix socket) and serves the ssh-agent protocol
+// on each one of them. It returns when the listener is closed.
+func (a *Agent) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			_ = agent.ServeAgent(a, conn)
+		}()
+	}
+}
+
+// agentSigner is a ssh.Signer that signs using a threshold agent.
+type agentSigner struct {
+	agent *Agent
+}
+
+// PublicKey returns the threshold public key.
+func (s *agentSigner) PublicKey() ssh.PublicKey {
+	return s.agent.key
+}
+
+// Sign signs data using the threshold agent.
+func (s *agentSigner) Sign(_ io.Reader, data []byte) (*ssh.Signature, error) {
+	return s.agent.Sign(s.agent.key, data)
+}
@@ -0,0 +1,163 @@
+package tcecdsa_test
+
+import (
+	"github.com/niclabs/tcecdsa"
+	"github.com/niclabs/tcpaillier"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// localTransport is a SigTransport that runs the sessions of the other participants in the same process.
+type localTransport struct {
+	shares  []*tcecdsa.KeyShare
+	meta    *tcecdsa.KeyMeta
+	states  []*tcecdsa.SigSession
+	round1  tcecdsa.Round1MessageList
+	round2  tcecdsa.Round2MessageList
+	started int
+}
+
+func (t *localTransport) Begin(h []byte) error {
+	t.started++
+	t.states = make([]*tcecdsa.SigSession, 0)
+	for _, share := range t.shares {
+		state, err := share.NewSigSession(t.meta, h)
+		if err != nil {
+			return err
+		}
+		t.states = append(t.states, state)
+	}
+	return nil
+}
+
+func (t *localTransport) ExchangeRound1(msg *tcecdsa.Round1Message) (tcecdsa.Round1MessageList, error) {
+	t.round1 = tcecdsa.Round1MessageList{msg}
+	for _, state := range t.states {
+		msg, err := state.Round1()
+		if err != nil {
+			return nil, err
+		}
+		t.round1 = append(t.round1, msg)
+	}
+	return t.round1, nil
+}
+
+func (t *localTransport) ExchangeRound2(msg *tcecdsa.Round2Message) (tcecdsa.Round2MessageList, error) {
+	t.round2 = tcecdsa.Round2MessageList{msg}
+	for _, state := range t.states {
+		msg, err := state.Round2(t.round1)
+		if err != nil {
+			return nil, err
+		}
+		t.round2 = append(t.round2, msg)
+	}
+	return t.round2, nil
+}
+
+func (t *localTransport) ExchangeRound3(msg *tcecdsa.Round3Message) (tcecdsa.Round3MessageList, error) {
+	round3 := tcecdsa.Round3MessageList{msg}
+	for _, state := range t.states {
+		msg, err := state.Round3(t.round2)
+		if err != nil {
+			return nil, err
+		}
+		round3 = append(round3, msg)
+	}
+	return round3, nil
+}
+
+func TestAgent(t *testing.T) {
+	params := &tcecdsa.NewKeyParams{
+		PaillierFixed: &tcpaillier.FixedParams{
+			P:  p256,
+			P1: p1256,
+			Q:  q256,
+			Q1: q1256,
+		},
+	}
+	shares, keyMeta, err := tcecdsa.NewKey(L, K, "P-256", params)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	pk, err := initKey(shares, keyMeta)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	transport := &localTransport{
+		shares: shares[1:],
+		meta:   keyMeta,
+	}
+	thresholdAgent, err := tcecdsa.NewAgent(shares[0], keyMeta, pk, transport, "tcecdsa")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	dir, err := ioutil.TempDir("", "tcecdsa-agent")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	l, err := net.Listen("unix", filepath.Join(dir, "agent.sock"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer l.Close()
+	go thresholdAgent.Serve(l)
+
+	conn, err := net.Dial("unix", filepath.Join(dir, "agent.sock"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer conn.Close()
+	client := agent.NewClient(conn)
+	keys, err := client.List()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(keys) != 1 {
+		t.Errorf("agent should list one key, but it listed %d", len(keys))
+		return
+	}
+	if keys[0].Type() != ssh.KeyAlgoECDSA256 || keys[0].Comment != "tcecdsa" {
+		t.Errorf("unexpected key %s", keys[0])
+		return
+	}
+	sshKey, err := ssh.NewPublicKey(pk)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if string(keys[0].Marshal()) != string(sshKey.Marshal()) {
+		t.Error("listed key is different to threshold public key")
+		return
+	}
+	data := []byte("session identifier and user authentication request")
+	sig, err := client.Sign(keys[0], data)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if err := sshKey.Verify(data, sig); err != nil {
+		t.Error(err)
+		return
+	}
+	if transport.started != 1 {
+		t.Errorf("transport should have started one session, but it started %d", transport.started)
+		return
+	}
+	if err := client.RemoveAll(); err == nil {
+		t.Error("agent should not allow to remove the threshold key")
+		return
+	}
+}
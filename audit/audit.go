@@ -0,0 +1,230 @@
+// Package audit keeps a tamper-evident log of the signing and key events of a node.
+// Every record is appended as a JSON line, and it contains the hash of the previous record, so modifying,
+// removing or reordering records breaks the chain. Truncating the end of the log can only be detected by
+// comparing its head with a copy kept elsewhere, so the head hash should be exported periodically.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"github.com/niclabs/tcecdsa"
+	"io"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+)
+
+// The following values are the types of the audit records.
+const (
+	KeyGenerated   = "key_generated"   // A key was generated and initialized. It sets KeyID, Curve and PublicKey.
+	SessionCreated = "session_created" // A signing session was created. It sets SessionID, KeyID and Digest.
+	RoundAccepted  = "round_accepted"  // A round was accepted. It sets SessionID, Round and Participants.
+	SignatureMade  = "signature"       // A signature was obtained. It sets SessionID, KeyID, R and S.
+	SessionAborted = "session_aborted" // A signing session failed. It sets SessionID, Reason and Blamed.
+)
+
+// hashPrefix is used to separate the record hashes from other uses of SHA-256.
+const hashPrefix = "tcecdsa audit v1\n"
+
+// Record represents an audit record.
+type Record struct {
+	Seq          uint64    `json:"seq"`  // Position of the record on the log, starting on 0
+	Time         time.Time `json:"time"` // Time the record was appended, in UTC
+	Node         string    `json:"node"` // Node that appended the record
+	Type         string    `json:"type"`
+	KeyID        string    `json:"key_id,omitempty"`
+	SessionID    string    `json:"session_id,omitempty"`
+	Curve        string    `json:"curve,omitempty"`
+	PublicKey    []byte    `json:"public_key,omitempty"` // Uncompressed SEC1 encoding of the public key
+	Digest       []byte    `json:"digest,omitempty"`     // Hash signed on the session
+	Round        string    `json:"round,omitempty"`
+	Participants []int     `json:"participants,omitempty"` // Indexes of the participants accepted on the round
+	R            *big.Int  `json:"r,omitempty"`
+	S            *big.Int  `json:"s,omitempty"`
+	Reason       string    `json:"reason,omitempty"`
+	Blamed       []int     `json:"blamed,omitempty"` // Indexes of the participants blamed for the abort
+	Prev         []byte    `json:"prev"`             // Hash of the previous record, or empty on the first record
+	Hash         []byte    `json:"hash"`             // Hash of this record
+}
+
+// Log is an audit log file, opened for appending records.
+type Log struct {
+	mutex sync.Mutex
+	file  *os.File
+	node  string
+	seq   uint64
+	head  []byte
+}
+
+// Open opens the audit log on the path for appending records as the given node, creating it if it does not
+// exist. The records already on the log are verified, so records are never appended to a broken chain.
+func Open(path, node string) (log *Log, err error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return
+	}
+	records, err := VerifyChain(file)
+	if err != nil {
+		_ = file.Close()
+		err = fmt.Errorf("cannot open audit log %s: %s", path, err)
+		return
+	}
+	log = &Log{
+		file: file,
+		node: node,
+	}
+	if len(records) > 0 {
+		last := records[len(records)-1]
+		log.seq = last.Seq + 1
+		log.head = last.Hash
+	}
+	return
+}
+
+// Append sets the sequence number, time, node and hashes of the record, and appends it to the log.
+// The record is synced to disk before Append returns.
+func (log *Log) Append(rec *Record) (err error) {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	rec.Seq = log.seq
+	rec.Time = time.Now().UTC()
+	rec.Node = log.node
+	rec.Prev = log.head
+	if rec.Hash, err = rec.hash(); err != nil {
+		return
+	}
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return
+	}
+	if _, err = log.file.Write(append(b, '\n')); err != nil {
+		return
+	}
+	if err = log.file.Sync(); err != nil {
+		return
+	}
+	log.seq++
+	log.head = rec.Hash
+	return
+}
+
+// Head returns the number of records on the log and the hash of the last one.
+func (log *Log) Head() (n uint64, hash []byte) {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	return log.seq, log.head
+}
+
+// Close closes the log file.
+func (log *Log) Close() error {
+	return log.file.Close()
+}
+
+// VerifyChain reads the records of a log and checks their sequence numbers and hash chain.
+func VerifyChain(r io.Reader) (records []*Record, err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	var prev []byte
+	for line := 1; scanner.Scan(); line++ {
+		rec := &Record{}
+		if err = json.Unmarshal(scanner.Bytes(), rec); err != nil {
+			err = fmt.Errorf("line %d: %s", line, err)
+			return
+		}
+		if rec.Seq != uint64(len(records)) {
+			err = fmt.Errorf("line %d: record has sequence number %d, but %d was expected", line, rec.Seq, len(records))
+			return
+		}
+		if !bytes.Equal(rec.Prev, prev) {
+			err = fmt.Errorf("record %d: previous hash does not match the hash of the previous record", rec.Seq)
+			return
+		}
+		var hash []byte
+		if hash, err = rec.hash(); err != nil {
+			return
+		}
+		if !bytes.Equal(rec.Hash, hash) {
+			err = fmt.Errorf("record %d: hash does not match its contents", rec.Seq)
+			return
+		}
+		prev = rec.Hash
+		records = append(records, rec)
+	}
+	err = scanner.Err()
+	return
+}
+
+// Verify reads the records of a log, checks their hash chain and checks every signature against the digest
+// of its session and the public key of its key, as recorded on the log.
+func Verify(r io.Reader) (records []*Record, err error) {
+	records, err = VerifyChain(r)
+	if err != nil {
+		return
+	}
+	keys := make(map[string]*ecdsa.PublicKey)
+	sessions := make(map[string]*Record)
+	for _, rec := range records {
+		switch rec.Type {
+		case KeyGenerated:
+			curve, ok := tcecdsa.CurveNameToCurve[rec.Curve]
+			if !ok {
+				err = fmt.Errorf("record %d: unknown curve %s", rec.Seq, rec.Curve)
+				return
+			}
+			x, y := elliptic.Unmarshal(curve, rec.PublicKey)
+			if x == nil {
+				err = fmt.Errorf("record %d: invalid public key", rec.Seq)
+				return
+			}
+			keys[rec.KeyID] = &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+		case SessionCreated:
+			if _, ok := sessions[rec.SessionID]; ok {
+				err = fmt.Errorf("record %d: session %s was already created", rec.Seq, rec.SessionID)
+				return
+			}
+			sessions[rec.SessionID] = rec
+		case RoundAccepted, SessionAborted:
+			if _, ok := sessions[rec.SessionID]; !ok {
+				err = fmt.Errorf("record %d: session %s was not created", rec.Seq, rec.SessionID)
+				return
+			}
+		case SignatureMade:
+			session, ok := sessions[rec.SessionID]
+			if !ok {
+				err = fmt.Errorf("record %d: session %s was not created", rec.Seq, rec.SessionID)
+				return
+			}
+			pk, ok := keys[session.KeyID]
+			if !ok {
+				err = fmt.Errorf("record %d: key %s was not generated", rec.Seq, session.KeyID)
+				return
+			}
+			if rec.R == nil || rec.S == nil || !ecdsa.Verify(pk, session.Digest, rec.R, rec.S) {
+				err = fmt.Errorf("record %d: signature of session %s is not valid for key %s", rec.Seq, rec.SessionID, session.KeyID)
+				return
+			}
+		default:
+			err = fmt.Errorf("record %d: unknown record type %s", rec.Seq, rec.Type)
+			return
+		}
+	}
+	return
+}
+
+// hash returns the hash of the record, computed over its JSON encoding without the Hash field.
+func (rec *Record) hash() ([]byte, error) {
+	unhashed := *rec
+	unhashed.Hash = nil
+	b, err := json.Marshal(&unhashed)
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.Sum256(append([]byte(hashPrefix), b...))
+	return h[:], nil
+}
@@ -0,0 +1,185 @@
+package audit_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"github.com/niclabs/tcecdsa/audit"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeLog writes a log with a key, a signing session and its signature, and returns its path.
+func writeLog(t *testing.T, dir string) string {
+	path := filepath.Join(dir, "audit.log")
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := sha256.Sum256([]byte("hello world"))
+	r, s, err := ecdsa.Sign(rand.Reader, key, h[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	log, err := audit.Open(path, "0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+	records := []*audit.Record{
+		{Type: audit.KeyGenerated, KeyID: "test", Curve: "P-256", PublicKey: elliptic.Marshal(elliptic.P256(), key.X, key.Y)},
+		{Type: audit.SessionCreated, KeyID: "test", SessionID: "s1", Digest: h[:]},
+		{Type: audit.RoundAccepted, SessionID: "s1", Round: "round1", Participants: []int{0, 1, 2}},
+		{Type: audit.SignatureMade, KeyID: "test", SessionID: "s1", R: r, S: s},
+		{Type: audit.SessionCreated, KeyID: "test", SessionID: "s2", Digest: h[:]},
+		{Type: audit.SessionAborted, SessionID: "s2", Reason: "node 1: timeout", Blamed: []int{1}},
+	}
+	for _, rec := range records {
+		if err := log.Append(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+// verifyLines verifies a log made of the given lines.
+func verifyLines(lines [][]byte) error {
+	_, err := audit.Verify(bytes.NewReader(append(bytes.Join(lines, []byte("\n")), '\n')))
+	return err
+}
+
+func TestVerify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tcecdsa-audit")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	path := writeLog(t, dir)
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	lines := bytes.Split(bytes.TrimSuffix(b, []byte("\n")), []byte("\n"))
+
+	t.Run("Valid", func(t *testing.T) {
+		records, err := audit.Verify(bytes.NewReader(b))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if len(records) != 6 || records[5].Blamed[0] != 1 {
+			t.Errorf("unexpected records: %+v", records)
+			return
+		}
+	})
+
+	t.Run("Reopened", func(t *testing.T) {
+		log, err := audit.Open(path, "0")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer log.Close()
+		if err := log.Append(&audit.Record{Type: audit.SessionCreated, KeyID: "test", SessionID: "s3"}); err != nil {
+			t.Error(err)
+			return
+		}
+		if n, _ := log.Head(); n != 7 {
+			t.Errorf("log should have 7 records, but it has %d", n)
+			return
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer f.Close()
+		if _, err := audit.Verify(f); err != nil {
+			t.Error(err)
+			return
+		}
+	})
+
+	t.Run("Modified", func(t *testing.T) {
+		modified := make([][]byte, len(lines))
+		copy(modified, lines)
+		modified[1] = bytes.Replace(lines[1], []byte(`"s1"`), []byte(`"s9"`), 1)
+		if err := verifyLines(modified); err == nil {
+			t.Error("modified record should be rejected")
+			return
+		}
+	})
+
+	t.Run("Removed", func(t *testing.T) {
+		removed := append(append([][]byte{}, lines[:2]...), lines[3:]...)
+		if err := verifyLines(removed); err == nil {
+			t.Error("log with a removed record should be rejected")
+			return
+		}
+	})
+
+	t.Run("Reordered", func(t *testing.T) {
+		reordered := append([][]byte{}, lines...)
+		reordered[4], reordered[5] = reordered[5], reordered[4]
+		if err := verifyLines(reordered); err == nil {
+			t.Error("log with reordered records should be rejected")
+			return
+		}
+	})
+
+	t.Run("ForgedSignature", func(t *testing.T) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		h := sha256.Sum256([]byte("hello world"))
+		r, s, err := ecdsa.Sign(rand.Reader, other, h[:])
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		forgedPath := filepath.Join(dir, "forged.log")
+		log, err := audit.Open(forgedPath, "0")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer log.Close()
+		records := []*audit.Record{
+			{Type: audit.KeyGenerated, KeyID: "test", Curve: "P-256", PublicKey: elliptic.Marshal(elliptic.P256(), key.X, key.Y)},
+			{Type: audit.SessionCreated, KeyID: "test", SessionID: "s1", Digest: h[:]},
+			{Type: audit.SignatureMade, KeyID: "test", SessionID: "s1", R: r, S: s},
+		}
+		for _, rec := range records {
+			if err := log.Append(rec); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+		b, err := ioutil.ReadFile(forgedPath)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if _, err := audit.VerifyChain(bytes.NewReader(b)); err != nil {
+			t.Error(err)
+			return
+		}
+		if _, err := audit.Verify(bytes.NewReader(b)); err == nil {
+			t.Error("signature made with another key should be rejected")
+			return
+		}
+	})
+}
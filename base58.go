@@ -0,0 +1,80 @@
+package tcecdsa
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+)
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var base58Radix = big.NewInt(58)
+
+// Base58Encode encodes a byte array using the Bitcoin base58 alphabet.
+func Base58Encode(b []byte) string {
+	x := new(big.Int).SetBytes(b)
+	mod := new(big.Int)
+	encoded := make([]byte, 0, len(b)*138/100+1)
+	for x.Sign() > 0 {
+		x.DivMod(x, base58Radix, mod)
+		encoded = append(encoded, base58Alphabet[mod.Int64()])
+	}
+	for _, c := range b {
+		if c != 0 {
+			break
+		}
+		encoded = append(encoded, base58Alphabet[0])
+	}
+	for i, j := 0, len(encoded)-1; i < j; i, j = i+1, j-1 {
+		encoded[i], encoded[j] = encoded[j], encoded[i]
+	}
+	return string(encoded)
+}
+
+// Base58Decode decodes a string encoded with the Bitcoin base58 alphabet.
+func Base58Decode(s string) ([]byte, error) {
+	x := new(big.Int)
+	for i := 0; i < len(s); i++ {
+		digit := bytes.IndexByte([]byte(base58Alphabet), s[i])
+		if digit < 0 {
+			return nil, fmt.Errorf("invalid base58 character %q at position %d", s[i], i)
+		}
+		x.Mul(x, base58Radix)
+		x.Add(x, big.NewInt(int64(digit)))
+	}
+	leadingZeros := 0
+	for leadingZeros < len(s) && s[leadingZeros] == base58Alphabet[0] {
+		leadingZeros++
+	}
+	return append(make([]byte, leadingZeros), x.Bytes()...), nil
+}
+
+// Base58CheckEncode appends the first four bytes of the double SHA256 checksum of b and
+// encodes the result using base58.
+func Base58CheckEncode(b []byte) string {
+	return Base58Encode(append(append([]byte{}, b...), base58Checksum(b)...))
+}
+
+// Base58CheckDecode decodes a base58 string and checks and removes its four bytes checksum.
+func Base58CheckDecode(s string) ([]byte, error) {
+	b, err := Base58Decode(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < 4 {
+		return nil, fmt.Errorf("decoded value is too short to contain a checksum")
+	}
+	payload, checksum := b[:len(b)-4], b[len(b)-4:]
+	if !bytes.Equal(checksum, base58Checksum(payload)) {
+		return nil, fmt.Errorf("invalid checksum")
+	}
+	return payload, nil
+}
+
+// base58Checksum returns the first four bytes of SHA256(SHA256(b)).
+func base58Checksum(b []byte) []byte {
+	h := sha256.Sum256(b)
+	h = sha256.Sum256(h[:])
+	return h[:4]
+}
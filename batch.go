@@ -0,0 +1,538 @@
+package tcecdsa
+
+import (
+	"fmt"
+	"github.com/niclabs/tcecdsa/l2fhe"
+	"github.com/niclabs/tcpaillier"
+	"math/big"
+)
+
+// batchWeightBits is the size in bits of the random weights used to combine the equations of a batch.
+const batchWeightBits = 128
+
+// sigZKProofTerms represents the contribution of a SigZKProof to the combined equations of a batch.
+// The Paillier equations are combined as (N+1)^nPlusOneExp * t^N = paillier (mod N^(s+1)), and the
+// NTilde equations as h1^h1Exp * h2^h2Exp = nTilde (mod NTilde).
+type sigZKProofTerms struct {
+	nPlusOneExp, h1Exp, h2Exp *big.Int
+	t, paillier, nTilde       *big.Int
+}
+
+// VerifyBatch verifies the ZKProofs of a list of Round1Messages and returns the error of each message,
+// which is nil if the message is valid.
+//
+// The proofs are verified together: the challenge and the curve equation of every proof are checked
+// individually, but their Paillier and NTilde equations are raised to random weights and multiplied, so the
+// exponentiations with the fixed bases and with N are done only once. If the combined check fails, every
+// proof is verified individually to find the invalid ones.
+//
+// The combined check accepts values that differ from the valid ones by elements of small order, which an
+// individual verification would reject. For this reason it is only used by Join if KeyMeta.BatchVerify is set.
+func (msgs Round1MessageList) VerifyBatch(meta *KeyMeta) (errs []error) {
+	errs = make([]error, len(msgs))
+	complete := make(Round1MessageList, 0)
+	positions := make([]int, 0)
+	for i, msg := range msgs {
+		if msg == nil || msg.Proof == nil || msg.Ri == nil || msg.Ui == nil || msg.Vi == nil || msg.Wi == nil {
+			errs[i] = fmt.Errorf("message fields must be set")
+			continue
+		}
+		complete = append(complete, msg)
+		positions = append(positions, i)
+	}
+	if len(complete) == 0 || batchVerifySigZKProofs(meta, complete) == nil {
+		return
+	}
+	addCounter(CounterBatchFallbacks, 1)
+	completeErrs := meta.verifyAll(len(complete), func(i int) error {
+		return complete[i].Verify(meta)
+	})
+	for i, err := range completeErrs {
+		errs[positions[i]] = err
+	}
+	return
+}
+
+// batchVerifySigZKProofs returns an error if the combined equations of the proofs of a list of complete
+// Round1Messages do not hold.
+func batchVerifySigZKProofs(meta *KeyMeta, msgs Round1MessageList) (err error) {
+	defer startSpan(SpanBatchVerifySigProofs).end(&err)
+	cache := meta.Paillier.Cache()
+	n := meta.Paillier.N
+	nToSPlusOne := cache.NToSPlusOne
+	nTilde := meta.NTilde
+
+	terms := make([]*sigZKProofTerms, len(msgs))
+	errs := meta.verifyAll(len(msgs), func(i int) (err error) {
+		terms[i], err = msgs[i].Proof.batchTerms(meta, msgs[i].Ri, msgs[i].Ui, msgs[i].Vi, msgs[i].Wi)
+		return
+	})
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("proof %d: %s", i, err)
+		}
+	}
+
+	nPlusOneExp, h1Exp, h2Exp := new(big.Int), new(big.Int), new(big.Int)
+	t, paillier, nTildeRight := big.NewInt(1), big.NewInt(1), big.NewInt(1)
+	for _, term := range terms {
+		nPlusOneExp.Add(nPlusOneExp, term.nPlusOneExp)
+		h1Exp.Add(h1Exp, term.h1Exp)
+		h2Exp.Add(h2Exp, term.h2Exp)
+		t.Mul(t, term.t).Mod(t, nToSPlusOne)
+		paillier.Mul(paillier, term.paillier).Mod(paillier, nToSPlusOne)
+		nTildeRight.Mul(nTildeRight, term.nTilde).Mod(nTildeRight, nTilde)
+	}
+
+	paillierLeft := meta.expGenerator(nPlusOneExp)
+	paillierLeft.Mul(paillierLeft, new(big.Int).Exp(t, n, nToSPlusOne)).
+		Mod(paillierLeft, nToSPlusOne)
+	if paillierLeft.Cmp(paillier) != 0 {
+		return fmt.Errorf("batch zkproof failed (U2, U3, U4)")
+	}
+
+	nTildeLeft := meta.expH1H2(h1Exp, h2Exp)
+	if nTildeLeft.Cmp(nTildeRight) != 0 {
+		return fmt.Errorf("batch zkproof failed (V1, V2, V3)")
+	}
+	return nil
+}
+
+// batchTerms checks the challenge and the curve equation of the proof, and returns its contribution to the
+// combined equations of a batch, using a new random weight for each equation.
+func (p *SigZKProof) batchTerms(meta *KeyMeta, r *Point, uiFHE, viFHE, wiFHE *l2fhe.EncryptedL1) (terms *sigZKProofTerms, err error) {
+	cache := meta.Paillier.Cache()
+	n := meta.Paillier.N
+	nToSPlusOne := cache.NToSPlusOne
+	nTilde := meta.NTilde
+
+	ui, err := uiFHE.ToPaillier(meta.PubKey.Paillier)
+	if err != nil {
+		return
+	}
+	vi, err := viFHE.ToPaillier(meta.PubKey.Paillier)
+	if err != nil {
+		return
+	}
+	wi, err := wiFHE.ToPaillier(meta.PubKey.Paillier)
+	if err != nil {
+		return
+	}
+
+	// Values that are not invertible could make both sides of the combined equations zero.
+	for _, v := range []*big.Int{p.U2, p.U3, p.U4, p.T1, p.T2, p.T3, ui, vi, wi} {
+		if !isUnit(v, nToSPlusOne, n) {
+			err = fmt.Errorf("paillier value is not invertible")
+			return
+		}
+	}
+	for _, v := range []*big.Int{p.V1, p.V2, p.V3, p.Z1, p.Z2, p.Z3} {
+		if !isUnit(v, nTilde, nTilde) {
+			err = fmt.Errorf("ntilde value is not invertible")
+			return
+		}
+	}
+	for _, v := range []*big.Int{p.S1, p.S3, p.S4, p.S5, p.S6, p.S7} {
+		if v == nil || v.Sign() < 0 {
+			err = fmt.Errorf("exponents must be set and positive")
+			return
+		}
+	}
+
+	if p.E == nil {
+		err = fmt.Errorf("proof fields must be set")
+		return
+	}
+	if err = r.Validate(meta.Curve()); err != nil {
+		err = fmt.Errorf("invalid Ri: %s", err)
+		return
+	}
+	if err = p.U1.Validate(meta.Curve()); err != nil {
+		err = fmt.Errorf("invalid U1: %s", err)
+		return
+	}
+	if p.E.Cmp(sigZKProofChallenge(meta, p, r, vi, ui, wi)) != 0 {
+		err = fmt.Errorf("zkproof failed (hash)")
+		return
+	}
+	u1 := meta.baseMul(p.S1)
+	pu1 := NewZero().Add(meta.Curve(), p.U1, NewZero().Mul(meta.Curve(), r, p.E))
+	if pu1.Cmp(u1) != 0 {
+		err = fmt.Errorf("zkproof failed (U1)")
+		return
+	}
+
+	terms = &sigZKProofTerms{
+		nPlusOneExp: new(big.Int),
+		h1Exp:       new(big.Int),
+		h2Exp:       new(big.Int),
+		t:           big.NewInt(1),
+		paillier:    big.NewInt(1),
+		nTilde:      big.NewInt(1),
+	}
+	maxWeight := new(big.Int).Lsh(one, batchWeightBits)
+
+	// (N+1)^S * T^N = U * c^E (mod N^(s+1)), raised to c.
+	paillierEqs := []struct{ s, t, c, u *big.Int }{
+		{p.S1, p.T1, vi, p.U2},
+		{p.S4, p.T2, ui, p.U3},
+		{p.S6, p.T3, wi, p.U4},
+	}
+	for _, eq := range paillierEqs {
+		weight, err := RandomInRange(one, maxWeight)
+		if err != nil {
+			return nil, err
+		}
+		terms.nPlusOneExp.Add(terms.nPlusOneExp, new(big.Int).Mul(weight, eq.s))
+		terms.t.Mul(terms.t, new(big.Int).Exp(eq.t, weight, nToSPlusOne)).Mod(terms.t, nToSPlusOne)
+		terms.paillier.Mul(terms.paillier, new(big.Int).Exp(eq.u, weight, nToSPlusOne)).
+			Mul(terms.paillier, new(big.Int).Exp(eq.c, new(big.Int).Mul(weight, p.E), nToSPlusOne)).
+			Mod(terms.paillier, nToSPlusOne)
+	}
+
+	// h1^Sa * h2^Sb = V * Z^E (mod NTilde), raised to c.
+	nTildeEqs := []struct{ sa, sb, z, v *big.Int }{
+		{p.S1, p.S3, p.Z1, p.V1},
+		{p.S4, p.S5, p.Z2, p.V2},
+		{p.S6, p.S7, p.Z3, p.V3},
+	}
+	for _, eq := range nTildeEqs {
+		weight, err := RandomInRange(one, maxWeight)
+		if err != nil {
+			return nil, err
+		}
+		terms.h1Exp.Add(terms.h1Exp, new(big.Int).Mul(weight, eq.sa))
+		terms.h2Exp.Add(terms.h2Exp, new(big.Int).Mul(weight, eq.sb))
+		terms.nTilde.Mul(terms.nTilde, new(big.Int).Exp(eq.v, weight, nTilde)).
+			Mul(terms.nTilde, new(big.Int).Exp(eq.z, new(big.Int).Mul(weight, p.E), nTilde)).
+			Mod(terms.nTilde, nTilde)
+	}
+	return
+}
+
+// isUnit returns true if v is between 1 and modulus (exclusive) and it is coprime with n.
+func isUnit(v, modulus, n *big.Int) bool {
+	if v == nil || v.Sign() <= 0 || v.Cmp(modulus) >= 0 {
+		return false
+	}
+	return new(big.Int).GCD(nil, nil, v, n).Cmp(one) == 0
+}
+
+// DecryptShareCommitment represents the commitments a = (c^4)^r and b = v^r of a DecryptShareZK over c. The
+// challenge of the proof is the hash of them, so they are not needed to verify it, but they let VerifyBatch
+// combine the equations of many proofs instead of recomputing the commitments of each one.
+type DecryptShareCommitment struct {
+	A, B *big.Int
+}
+
+// decryptShareMessage represents the partial decryption of a level 2 value sent on a Round2Message or a
+// Round3Message, with its ZKProof and the commitments of the proofs.
+type decryptShareMessage struct {
+	share       *l2fhe.DecryptedShareL2
+	proof       *l2fhe.DecryptedShareL2ZK
+	commitments []*DecryptShareCommitment
+}
+
+// decryptShareZKTerms represents the contribution of the proofs of a decryptShareMessage to the combined
+// equation of a batch, which is prod (c^4)^cExps * v^vExp = right (mod N^(s+1)).
+type decryptShareZKTerms struct {
+	cExps       []*big.Int
+	vExp, right *big.Int
+}
+
+// VerifyBatch verifies the ZKProofs of a list of Round2Messages and returns the error of each message,
+// which is nil if the message is valid. The Z value required is to check the ZKProofs.
+//
+// The proofs of the messages with commitments are verified together, as in Round1MessageList.VerifyBatch: the
+// equations of every proof are raised to random weights and multiplied, so the exponentiations with the
+// decrypted values and with V are done only once. If the combined check fails, every proof is verified
+// individually to find the invalid ones. The messages without commitments are always verified individually.
+// As the combined check accepts values that differ from the valid ones by elements of small order, it is only
+// used by Join if KeyMeta.BatchVerify is set.
+func (msgs Round2MessageList) VerifyBatch(meta *KeyMeta, z *l2fhe.EncryptedL2) []error {
+	decMsgs := make([]*decryptShareMessage, len(msgs))
+	for i, msg := range msgs {
+		if msg != nil && msg.Proof != nil && msg.PDZ != nil {
+			decMsgs[i] = &decryptShareMessage{msg.PDZ, msg.Proof, msg.Commitments}
+		}
+	}
+	return verifyDecryptSharesBatch(meta, z, decMsgs, func(i int) error {
+		return msgs[i].Verify(meta, z)
+	})
+}
+
+// VerifyBatch verifies the ZKProofs of a list of Round3Messages and returns the error of each message,
+// which is nil if the message is valid. The sigma value required is to check the ZKProofs.
+// It combines the proofs as Round2MessageList.VerifyBatch.
+func (msgs Round3MessageList) VerifyBatch(meta *KeyMeta, sigma *l2fhe.EncryptedL2) []error {
+	decMsgs := make([]*decryptShareMessage, len(msgs))
+	for i, msg := range msgs {
+		if msg != nil && msg.Proof != nil && msg.PDSigma != nil {
+			decMsgs[i] = &decryptShareMessage{msg.PDSigma, msg.Proof, msg.Commitments}
+		}
+	}
+	return verifyDecryptSharesBatch(meta, sigma, decMsgs, func(i int) error {
+		return msgs[i].Verify(meta, sigma)
+	})
+}
+
+// verifyDecryptSharesBatch verifies the partial decryptions of c of a list of messages, where incomplete
+// messages are nil, and returns the error of each message. The messages that cannot be verified together
+// are verified with verify.
+func verifyDecryptSharesBatch(meta *KeyMeta, c *l2fhe.EncryptedL2, msgs []*decryptShareMessage, verify func(i int) error) (errs []error) {
+	errs = make([]error, len(msgs))
+	batch := make([]*decryptShareMessage, 0)
+	positions := make([]int, 0)
+	individual := make([]int, 0)
+	for i, msg := range msgs {
+		switch {
+		case msg == nil:
+			errs[i] = errIncompleteMessage
+		case len(msg.commitments) == 0:
+			individual = append(individual, i)
+		default:
+			batch = append(batch, msg)
+			positions = append(positions, i)
+		}
+	}
+	if len(batch) > 0 && batchVerifyDecryptShareZKs(meta, c, batch) != nil {
+		addCounter(CounterBatchFallbacks, 1)
+		individual = append(individual, positions...)
+	}
+	individualErrs := meta.verifyAll(len(individual), func(j int) error {
+		return verify(individual[j])
+	})
+	for j, err := range individualErrs {
+		errs[individual[j]] = err
+	}
+	return
+}
+
+// batchVerifyDecryptShareZKs returns an error if the combined equation of the proofs of a list of
+// decryptShareMessages with commitments does not hold.
+func batchVerifyDecryptShareZKs(meta *KeyMeta, c *l2fhe.EncryptedL2, msgs []*decryptShareMessage) (err error) {
+	defer startSpan(SpanBatchVerifyDecryptProofs).end(&err)
+	nToSPlusOne := meta.Paillier.Cache().NToSPlusOne
+
+	values := encryptedL2Values(c)
+	cTo4 := make([]*big.Int, len(values))
+	for j, value := range values {
+		cTo4[j] = new(big.Int).Exp(value, big.NewInt(4), nToSPlusOne)
+	}
+
+	terms := make([]*decryptShareZKTerms, len(msgs))
+	errs := meta.verifyAll(len(msgs), func(i int) (err error) {
+		terms[i], err = msgs[i].batchTerms(meta, cTo4)
+		return
+	})
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("proof %d: %s", i, err)
+		}
+	}
+
+	cExps := make([]*big.Int, len(cTo4))
+	for j := range cExps {
+		cExps[j] = new(big.Int)
+	}
+	vExp, right := new(big.Int), big.NewInt(1)
+	for _, term := range terms {
+		for j, exp := range term.cExps {
+			cExps[j].Add(cExps[j], exp)
+		}
+		vExp.Add(vExp, term.vExp)
+		right.Mul(right, term.right).Mod(right, nToSPlusOne)
+	}
+
+	left := new(big.Int).Exp(meta.Paillier.V, vExp, nToSPlusOne)
+	for j, exp := range cExps {
+		left.Mul(left, new(big.Int).Exp(cTo4[j], exp, nToSPlusOne)).Mod(left, nToSPlusOne)
+	}
+	if left.Cmp(right) != 0 {
+		return fmt.Errorf("batch zkproof failed (decryption shares)")
+	}
+	return nil
+}
+
+// batchTerms checks the challenges of the proofs of the message, and returns their contribution to the
+// combined equation of a batch, using a new random weight for each equation. cTo4 are the decrypted values
+// raised to 4, in the order of encryptedL2Values.
+func (msg *decryptShareMessage) batchTerms(meta *KeyMeta, cTo4 []*big.Int) (terms *decryptShareZKTerms, err error) {
+	pk := meta.Paillier
+	n := pk.N
+	nToSPlusOne := pk.Cache().NToSPlusOne
+
+	shares, proofs, err := decryptShareValues(msg.share, msg.proof, len(cTo4))
+	if err != nil {
+		return
+	}
+	if len(msg.commitments) != len(cTo4) {
+		err = fmt.Errorf("commitments length is distinct from the number of decrypted values")
+		return
+	}
+	index := shares[0].Index
+	if index < 1 || int(index) > len(pk.Vi) {
+		err = fmt.Errorf("invalid share index %d", index)
+		return
+	}
+	vi := pk.Vi[index-1]
+
+	terms = &decryptShareZKTerms{
+		cExps: make([]*big.Int, len(cTo4)),
+		vExp:  new(big.Int),
+		right: big.NewInt(1),
+	}
+	viExp := new(big.Int)
+	maxWeight := new(big.Int).Lsh(one, batchWeightBits)
+	for j, share := range shares {
+		proof, commitment := proofs[j], msg.commitments[j]
+		if commitment == nil || proof.E == nil || proof.Z == nil || proof.V == nil || proof.Vi == nil {
+			err = fmt.Errorf("proof fields must be set")
+			return
+		}
+		if share.Index != index {
+			err = fmt.Errorf("decryption shares have distinct indexes")
+			return
+		}
+		// The combined equation uses the verification values of the public key.
+		if proof.V.Cmp(pk.V) != 0 || proof.Vi.Cmp(vi) != 0 {
+			err = fmt.Errorf("proof verification values are not the ones of the public key")
+			return
+		}
+		// Values that are not invertible could make both sides of the combined equation zero.
+		for _, v := range []*big.Int{share.Ci, commitment.A, commitment.B} {
+			if !isUnit(v, nToSPlusOne, n) {
+				err = fmt.Errorf("paillier value is not invertible")
+				return
+			}
+		}
+		if proof.Z.Sign() < 0 {
+			err = fmt.Errorf("exponents must be positive")
+			return
+		}
+		ciTo2 := new(big.Int).Exp(share.Ci, big.NewInt(2), nToSPlusOne)
+		if proof.E.Cmp(l2fhe.DecryptShareChallenge(commitment.A, commitment.B, cTo4[j], ciTo2)) != 0 {
+			err = fmt.Errorf("zkproof failed (hash)")
+			return
+		}
+
+		w1, err := RandomInRange(one, maxWeight)
+		if err != nil {
+			return nil, err
+		}
+		w2, err := RandomInRange(one, maxWeight)
+		if err != nil {
+			return nil, err
+		}
+		// (c^4)^Z = A * Ci^(2E) (mod N^(s+1)), raised to w1.
+		terms.cExps[j] = new(big.Int).Mul(w1, proof.Z)
+		terms.right.Mul(terms.right, new(big.Int).Exp(commitment.A, w1, nToSPlusOne)).
+			Mul(terms.right, new(big.Int).Exp(ciTo2, new(big.Int).Mul(w1, proof.E), nToSPlusOne)).
+			Mod(terms.right, nToSPlusOne)
+		// V^Z = B * Vi^E (mod N^(s+1)), raised to w2.
+		terms.vExp.Add(terms.vExp, new(big.Int).Mul(w2, proof.Z))
+		viExp.Add(viExp, new(big.Int).Mul(w2, proof.E))
+		terms.right.Mul(terms.right, new(big.Int).Exp(commitment.B, w2, nToSPlusOne)).
+			Mod(terms.right, nToSPlusOne)
+	}
+	terms.right.Mul(terms.right, new(big.Int).Exp(vi, viExp, nToSPlusOne)).Mod(terms.right, nToSPlusOne)
+	return
+}
+
+// verifyDecryptShareCommitments returns an error if the commitments of a message are not the ones used to
+// compute the challenges of its proofs. The proofs must have been verified before.
+func verifyDecryptShareCommitments(meta *KeyMeta, c *l2fhe.EncryptedL2, msg *decryptShareMessage) error {
+	nToSPlusOne := meta.Paillier.Cache().NToSPlusOne
+	values := encryptedL2Values(c)
+	shares, proofs, err := decryptShareValues(msg.share, msg.proof, len(values))
+	if err != nil {
+		return err
+	}
+	if len(msg.commitments) != len(values) {
+		return fmt.Errorf("commitments length is distinct from the number of decrypted values")
+	}
+	for j, commitment := range msg.commitments {
+		if commitment == nil || commitment.A == nil || commitment.B == nil {
+			return fmt.Errorf("commitment fields must be set")
+		}
+		cTo4 := new(big.Int).Exp(values[j], big.NewInt(4), nToSPlusOne)
+		ciTo2 := new(big.Int).Exp(shares[j].Ci, big.NewInt(2), nToSPlusOne)
+		if proofs[j].E.Cmp(l2fhe.DecryptShareChallenge(commitment.A, commitment.B, cTo4, ciTo2)) != 0 {
+			return fmt.Errorf("commitments are not the ones of the proof")
+		}
+	}
+	return nil
+}
+
+// partialDecryptL2 decrypts partially an encrypted level 2 value using a key share, as
+// l2fhe.PubKey.PartialDecryptL2, but it also returns the commitments of the proofs, in the order of
+// encryptedL2Values.
+func (meta *KeyMeta) partialDecryptL2(key *tcpaillier.KeyShare, c *l2fhe.EncryptedL2) (share *l2fhe.DecryptedShareL2, zk *l2fhe.DecryptedShareL2ZK, commitments []*DecryptShareCommitment, err error) {
+	defer startSpan(SpanPartialDecryptL2).end(&err)
+	values := encryptedL2Values(c)
+	shares := make([]*tcpaillier.DecryptionShare, len(values))
+	proofs := make([]*tcpaillier.DecryptShareZK, len(values))
+	commitments = make([]*DecryptShareCommitment, len(values))
+	for j, value := range values {
+		commitment := &DecryptShareCommitment{}
+		if shares[j], proofs[j], commitment.A, commitment.B, err = l2fhe.PartialDecryptWithProof(key, value); err != nil {
+			return
+		}
+		commitments[j] = commitment
+	}
+	share = &l2fhe.DecryptedShareL2{
+		Alpha: shares[0],
+		Betas: make([]*l2fhe.DecryptedShareBetas, 0),
+	}
+	zk = &l2fhe.DecryptedShareL2ZK{
+		Alpha: proofs[0],
+		Betas: make([]*l2fhe.BetasZK, 0),
+	}
+	for j := 1; j < len(values); j += 2 {
+		share.Betas = append(share.Betas, &l2fhe.DecryptedShareBetas{
+			Beta1: shares[j],
+			Beta2: shares[j+1],
+		})
+		zk.Betas = append(zk.Betas, &l2fhe.BetasZK{
+			Beta1: proofs[j],
+			Beta2: proofs[j+1],
+		})
+	}
+	return
+}
+
+// encryptedL2Values returns the Paillier values of an encrypted level 2 value, in the order Alpha, and
+// Beta1 and Beta2 of each pair.
+func encryptedL2Values(c *l2fhe.EncryptedL2) []*big.Int {
+	values := []*big.Int{c.Alpha}
+	for _, beta := range c.Betas {
+		values = append(values, beta.Beta1, beta.Beta2)
+	}
+	return values
+}
+
+// decryptShareValues returns the decryption shares and proofs of a partially decrypted level 2 value, in the
+// order of encryptedL2Values. It returns an error if they are not n or if one of them is not set.
+func decryptShareValues(share *l2fhe.DecryptedShareL2, zk *l2fhe.DecryptedShareL2ZK, n int) (shares []*tcpaillier.DecryptionShare, proofs []*tcpaillier.DecryptShareZK, err error) {
+	if share == nil || zk == nil || len(share.Betas) != len(zk.Betas) || 1+2*len(share.Betas) != n {
+		err = fmt.Errorf("decryption share length is distinct from the number of decrypted values")
+		return
+	}
+	shares = []*tcpaillier.DecryptionShare{share.Alpha}
+	proofs = []*tcpaillier.DecryptShareZK{zk.Alpha}
+	for i, beta := range share.Betas {
+		if beta == nil || zk.Betas[i] == nil {
+			err = fmt.Errorf("decryption share fields must be set")
+			return
+		}
+		shares = append(shares, beta.Beta1, beta.Beta2)
+		proofs = append(proofs, zk.Betas[i].Beta1, zk.Betas[i].Beta2)
+	}
+	for j := range shares {
+		if shares[j] == nil || shares[j].Ci == nil || proofs[j] == nil || proofs[j].E == nil {
+			err = fmt.Errorf("decryption share fields must be set")
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,369 @@
+package tcecdsa_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"github.com/niclabs/tcecdsa"
+	"github.com/niclabs/tcecdsa/l2fhe"
+	"github.com/niclabs/tcpaillier"
+	"math/big"
+	"testing"
+)
+
+func TestRound1MessageList_VerifyBatch(t *testing.T) {
+	params := &tcecdsa.NewKeyParams{
+		PaillierFixed: &tcpaillier.FixedParams{
+			P:  p,
+			P1: p1,
+			Q:  q,
+			Q1: q1,
+		},
+	}
+	shares, keyMeta, err := tcecdsa.NewKey(L, K, Curve, params)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := initKey(shares, keyMeta); err != nil {
+		t.Error(err)
+		return
+	}
+	h := sha256.Sum256(exampleText)
+	round1Messages := make(tcecdsa.Round1MessageList, 0)
+	for _, share := range shares {
+		state, err := share.NewSigSession(keyMeta, h[:])
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		msg, err := state.Round1()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		round1Messages = append(round1Messages, msg)
+	}
+	// tampered returns a copy of the messages where the proofs of the given positions are changed by change.
+	tampered := func(change func(proof *tcecdsa.SigZKProof), positions ...int) tcecdsa.Round1MessageList {
+		msgs := make(tcecdsa.Round1MessageList, len(round1Messages))
+		copy(msgs, round1Messages)
+		for _, i := range positions {
+			proof := *msgs[i].Proof
+			change(&proof)
+			msg := *msgs[i]
+			msg.Proof = &proof
+			msgs[i] = &msg
+		}
+		return msgs
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		instr := newRecordingInstrumentation()
+		tcecdsa.SetInstrumentation(instr)
+		defer tcecdsa.SetInstrumentation(nil)
+		for i, err := range round1Messages.VerifyBatch(keyMeta) {
+			if err != nil {
+				t.Errorf("message %d should be valid: %s", i, err)
+				return
+			}
+		}
+		if instr.counters[tcecdsa.CounterBatchFallbacks] != 0 {
+			t.Error("valid batch should not be verified again")
+			return
+		}
+	})
+
+	t.Run("InvalidPaillierEquation", func(t *testing.T) {
+		// T1 is not part of the challenge, so only the combined Paillier equation fails.
+		msgs := tampered(func(proof *tcecdsa.SigZKProof) {
+			proof.T1 = new(big.Int).Add(proof.T1, big.NewInt(1))
+		}, 1, 3)
+		for i, err := range msgs.VerifyBatch(keyMeta) {
+			if (err != nil) != (i == 1 || i == 3) {
+				t.Errorf("unexpected result for message %d: %v", i, err)
+				return
+			}
+		}
+	})
+
+	t.Run("InvalidNTildeEquation", func(t *testing.T) {
+		// S3 is not part of the challenge, so only the combined NTilde equation fails.
+		msgs := tampered(func(proof *tcecdsa.SigZKProof) {
+			proof.S3 = new(big.Int).Add(proof.S3, big.NewInt(1))
+		}, 2)
+		instr := newRecordingInstrumentation()
+		tcecdsa.SetInstrumentation(instr)
+		defer tcecdsa.SetInstrumentation(nil)
+		for i, err := range msgs.VerifyBatch(keyMeta) {
+			if (err != nil) != (i == 2) {
+				t.Errorf("unexpected result for message %d: %v", i, err)
+				return
+			}
+		}
+		if instr.counters[tcecdsa.CounterBatchFallbacks] != 1 {
+			t.Error("invalid batch should be verified again for each proof")
+			return
+		}
+	})
+
+	t.Run("Join", func(t *testing.T) {
+		expected, _, _, _, err := round1Messages.Join(keyMeta)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		keyMeta.BatchVerify = true
+		defer func() { keyMeta.BatchVerify = false }()
+		R, _, _, _, err := round1Messages.Join(keyMeta)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if R.Cmp(expected) != 0 {
+			t.Error("join with batch verification returned another R")
+			return
+		}
+		msgs := tampered(func(proof *tcecdsa.SigZKProof) {
+			proof.T2 = new(big.Int).Add(proof.T2, big.NewInt(1))
+		}, 0)
+		if _, _, _, _, err := msgs.Join(keyMeta); err == nil {
+			t.Error("join with batch verification should reject an invalid proof")
+			return
+		}
+	})
+}
+
+func TestRound2MessageList_VerifyBatch(t *testing.T) {
+	params := &tcecdsa.NewKeyParams{
+		PaillierFixed: &tcpaillier.FixedParams{
+			P:  p,
+			P1: p1,
+			Q:  q,
+			Q1: q1,
+		},
+	}
+	shares, keyMeta, err := tcecdsa.NewKey(L, K, Curve, params)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	pk, err := initKey(shares, keyMeta)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	h := sha256.Sum256(exampleText)
+	states := make([]*tcecdsa.SigSession, 0)
+	round1Messages := make(tcecdsa.Round1MessageList, 0)
+	for _, share := range shares {
+		state, err := share.NewSigSession(keyMeta, h[:])
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		msg, err := state.Round1()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		states = append(states, state)
+		round1Messages = append(round1Messages, msg)
+	}
+	round2Messages := make(tcecdsa.Round2MessageList, 0)
+	for _, state := range states {
+		msg, err := state.Round2(round1Messages)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		round2Messages = append(round2Messages, msg)
+	}
+	// z = u*v + q*w is the value partially decrypted on Round2.
+	_, u, v, w, err := round1Messages.Join(keyMeta)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	uv, err := keyMeta.Mul(v, u)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	qw, err := keyMeta.MulConstL1(w, keyMeta.Q())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	qwL2, err := qw.ToL2(keyMeta.PubKey)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	z, err := keyMeta.AddL2(uv, qwL2)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	// tampered returns a copy of the messages where the messages of the given positions are changed by change.
+	tampered := func(change func(msg *tcecdsa.Round2Message), positions ...int) tcecdsa.Round2MessageList {
+		msgs := make(tcecdsa.Round2MessageList, len(round2Messages))
+		copy(msgs, round2Messages)
+		for _, i := range positions {
+			msg := *msgs[i]
+			change(&msg)
+			msgs[i] = &msg
+		}
+		return msgs
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		instr := newRecordingInstrumentation()
+		tcecdsa.SetInstrumentation(instr)
+		defer tcecdsa.SetInstrumentation(nil)
+		for i, err := range round2Messages.VerifyBatch(keyMeta, z) {
+			if err != nil {
+				t.Errorf("message %d should be valid: %s", i, err)
+				return
+			}
+		}
+		if instr.counters[tcecdsa.CounterBatchFallbacks] != 0 || instr.spans[tcecdsa.SpanVerifyDecryptProofs] != 0 {
+			t.Error("valid batch should not be verified again")
+			return
+		}
+		if instr.spans[tcecdsa.SpanBatchVerifyDecryptProofs] != 1 {
+			t.Error("proofs should be verified with one combined check")
+			return
+		}
+	})
+
+	t.Run("InvalidProof", func(t *testing.T) {
+		// Z is not part of the challenge, so only the combined equation fails.
+		msgs := tampered(func(msg *tcecdsa.Round2Message) {
+			proof := *msg.Proof
+			alpha := *proof.Alpha
+			alpha.Z = new(big.Int).Add(alpha.Z, big.NewInt(1))
+			proof.Alpha = &alpha
+			msg.Proof = &proof
+		}, 1, 3)
+		instr := newRecordingInstrumentation()
+		tcecdsa.SetInstrumentation(instr)
+		defer tcecdsa.SetInstrumentation(nil)
+		for i, err := range msgs.VerifyBatch(keyMeta, z) {
+			if (err != nil) != (i == 1 || i == 3) {
+				t.Errorf("unexpected result for message %d: %v", i, err)
+				return
+			}
+		}
+		if instr.counters[tcecdsa.CounterBatchFallbacks] != 1 {
+			t.Error("invalid batch should be verified again for each proof")
+			return
+		}
+	})
+
+	t.Run("InvalidCommitments", func(t *testing.T) {
+		msgs := tampered(func(msg *tcecdsa.Round2Message) {
+			commitments := make([]*tcecdsa.DecryptShareCommitment, len(msg.Commitments))
+			copy(commitments, msg.Commitments)
+			commitments[0] = &tcecdsa.DecryptShareCommitment{
+				A: commitments[0].B,
+				B: commitments[0].A,
+			}
+			msg.Commitments = commitments
+		}, 2)
+		if err := msgs[2].Verify(keyMeta, z); err == nil {
+			t.Error("message with commitments of another proof should not be valid")
+			return
+		}
+		for i, err := range msgs.VerifyBatch(keyMeta, z) {
+			if (err != nil) != (i == 2) {
+				t.Errorf("unexpected result for message %d: %v", i, err)
+				return
+			}
+		}
+	})
+
+	t.Run("WithoutCommitments", func(t *testing.T) {
+		msgs := tampered(func(msg *tcecdsa.Round2Message) {
+			msg.Commitments = nil
+		}, 0)
+		instr := newRecordingInstrumentation()
+		tcecdsa.SetInstrumentation(instr)
+		defer tcecdsa.SetInstrumentation(nil)
+		for i, err := range msgs.VerifyBatch(keyMeta, z) {
+			if err != nil {
+				t.Errorf("message %d should be valid: %s", i, err)
+				return
+			}
+		}
+		if instr.spans[tcecdsa.SpanVerifyDecryptProofs] != 1 || instr.counters[tcecdsa.CounterBatchFallbacks] != 0 {
+			t.Error("only the message without commitments should be verified individually")
+			return
+		}
+	})
+
+	t.Run("Join", func(t *testing.T) {
+		expected, err := round2Messages.Join(keyMeta, z)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		keyMeta.BatchVerify = true
+		defer func() { keyMeta.BatchVerify = false }()
+		nu, err := round2Messages.Join(keyMeta, z)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if nu.Cmp(expected) != 0 {
+			t.Error("join with batch verification returned another nu")
+			return
+		}
+		msgs := tampered(func(msg *tcecdsa.Round2Message) {
+			msg.PDZ = &l2fhe.DecryptedShareL2{
+				Alpha: round2Messages[1].PDZ.Alpha,
+				Betas: msg.PDZ.Betas,
+			}
+		}, 0)
+		instr := newRecordingInstrumentation()
+		tcecdsa.SetInstrumentation(instr)
+		defer tcecdsa.SetInstrumentation(nil)
+		if nu, err = msgs.Join(keyMeta, z); err != nil {
+			t.Error(err)
+			return
+		}
+		if nu.Cmp(expected) != 0 || instr.counters[tcecdsa.CounterRejectedMessages] != 1 {
+			t.Error("join with batch verification should reject an invalid proof and use the valid messages")
+			return
+		}
+	})
+
+	t.Run("Round3", func(t *testing.T) {
+		keyMeta.BatchVerify = true
+		defer func() { keyMeta.BatchVerify = false }()
+		round3Messages := make(tcecdsa.Round3MessageList, 0)
+		for _, state := range states {
+			msg, err := state.Round3(round2Messages)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			round3Messages = append(round3Messages, msg)
+		}
+		instr := newRecordingInstrumentation()
+		tcecdsa.SetInstrumentation(instr)
+		defer tcecdsa.SetInstrumentation(nil)
+		r, s, err := states[0].GetSignature(round3Messages)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if !ecdsa.Verify(pk, h[:], r, s) {
+			t.Error("signature verification failed")
+			return
+		}
+		if instr.spans[tcecdsa.SpanBatchVerifyDecryptProofs] != 1 || instr.spans[tcecdsa.SpanVerifyDecryptProofs] != 0 {
+			t.Error("round 3 proofs should be verified with one combined check")
+			return
+		}
+	})
+}
@@ -0,0 +1,388 @@
+package tcecdsa
+
+import (
+	"crypto/sha256"
+	"flag"
+	"fmt"
+	"github.com/niclabs/tcecdsa/internal/benchparams"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var benchCurves = flag.String("bench.curves", "P-224,P-256,secp256k1",
+	"comma separated curves used by the benchmarks. Curves without fixed Paillier parameters (P-384 and P-521) generate them, which can take a long time")
+var benchSizes = flag.String("bench.sizes", "3/2,5/3,7/4", "comma separated L/K configurations used by the benchmarks")
+
+// benchConfig represents a curve and a (L, K) configuration of the benchmarks.
+type benchConfig struct {
+	curve string
+	l, k  uint8
+}
+
+// benchFixture represents a key with its public values set, shared by the benchmarks of a configuration.
+type benchFixture struct {
+	shares   []*KeyShare
+	meta     *KeyMeta
+	keyInit  KeyInitMessageList
+	params   *NewKeyParams
+	hash     []byte
+	messages *benchMessages
+}
+
+// benchMessages represents the sessions of the first K shares of a fixture and their messages on each round.
+type benchMessages struct {
+	sessions []*SigSession
+	round1   Round1MessageList
+	round2   Round2MessageList
+	round3   Round3MessageList
+}
+
+var benchFixtures = struct {
+	sync.Mutex
+	m map[benchConfig]*benchFixture
+}{m: make(map[benchConfig]*benchFixture)}
+
+// String returns the name of the sub-benchmark of the configuration.
+func (cfg benchConfig) String() string {
+	return fmt.Sprintf("%s/L=%d/K=%d", cfg.curve, cfg.l, cfg.k)
+}
+
+// benchConfigs returns the configurations set with the -bench.curves and -bench.sizes flags.
+func benchConfigs(b *testing.B) (configs []benchConfig) {
+	for _, curve := range strings.Split(*benchCurves, ",") {
+		for _, size := range strings.Split(*benchSizes, ",") {
+			cfg := benchConfig{curve: curve}
+			if _, err := fmt.Sscanf(size, "%d/%d", &cfg.l, &cfg.k); err != nil {
+				b.Fatalf("invalid size %q: %s", size, err)
+			}
+			configs = append(configs, cfg)
+		}
+	}
+	return
+}
+
+// runConfigs runs a sub-benchmark with the fixture of each configuration.
+func runConfigs(b *testing.B, bench func(b *testing.B, fx *benchFixture)) {
+	for _, cfg := range benchConfigs(b) {
+		b.Run(cfg.String(), func(b *testing.B) {
+			fx := getFixture(b, cfg)
+			b.ResetTimer()
+			bench(b, fx)
+		})
+	}
+}
+
+// getFixture returns the fixture of a configuration, creating it the first time.
+func getFixture(b *testing.B, cfg benchConfig) *benchFixture {
+	benchFixtures.Lock()
+	defer benchFixtures.Unlock()
+	if fx, ok := benchFixtures.m[cfg]; ok {
+		return fx
+	}
+	curve, ok := CurveNameToCurve[cfg.curve]
+	if !ok {
+		b.Fatalf("unsupported curve %s", cfg.curve)
+	}
+	params := &NewKeyParams{PaillierFixed: benchparams.Paillier(curve.Params().BitSize)}
+	shares, meta, err := NewKey(cfg.l, cfg.k, cfg.curve, params)
+	if err != nil {
+		b.Fatal(err)
+	}
+	keyInit := make(KeyInitMessageList, 0)
+	for _, share := range shares {
+		msg, err := share.Init(meta)
+		if err != nil {
+			b.Fatal(err)
+		}
+		keyInit = append(keyInit, msg)
+	}
+	for _, share := range shares {
+		if err := share.SetKey(meta, keyInit); err != nil {
+			b.Fatal(err)
+		}
+	}
+	h := sha256.Sum256([]byte("hello world"))
+	fx := &benchFixture{
+		shares:  shares,
+		meta:    meta,
+		keyInit: keyInit,
+		params:  params,
+		hash:    h[:],
+	}
+	fx.messages = fx.run(b, 3)
+	benchFixtures.m[cfg] = fx
+	return fx
+}
+
+// run creates a session for each of the first K shares and runs the given number of rounds on them.
+func (fx *benchFixture) run(b *testing.B, rounds int) (m *benchMessages) {
+	m = &benchMessages{}
+	for _, share := range fx.shares[:fx.meta.Paillier.K] {
+		session, err := share.NewSigSession(fx.meta, fx.hash)
+		if err != nil {
+			b.Fatal(err)
+		}
+		m.sessions = append(m.sessions, session)
+	}
+	if rounds < 1 {
+		return
+	}
+	for _, session := range m.sessions {
+		msg, err := session.Round1()
+		if err != nil {
+			b.Fatal(err)
+		}
+		m.round1 = append(m.round1, msg)
+	}
+	if rounds < 2 {
+		return
+	}
+	for _, session := range m.sessions {
+		msg, err := session.Round2(m.round1)
+		if err != nil {
+			b.Fatal(err)
+		}
+		m.round2 = append(m.round2, msg)
+	}
+	if rounds < 3 {
+		return
+	}
+	for _, session := range m.sessions {
+		msg, err := session.Round3(m.round2)
+		if err != nil {
+			b.Fatal(err)
+		}
+		m.round3 = append(m.round3, msg)
+	}
+	return
+}
+
+func BenchmarkNewKey(b *testing.B) {
+	runConfigs(b, func(b *testing.B, fx *benchFixture) {
+		for i := 0; i < b.N; i++ {
+			if _, _, err := NewKey(uint8(len(fx.shares)), uint8(fx.meta.Paillier.K), fx.meta.CurveName, fx.params); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkKeyShare_Init(b *testing.B) {
+	runConfigs(b, func(b *testing.B, fx *benchFixture) {
+		for i := 0; i < b.N; i++ {
+			if _, err := fx.shares[0].Init(fx.meta); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkKeyShare_SetKey(b *testing.B) {
+	runConfigs(b, func(b *testing.B, fx *benchFixture) {
+		for i := 0; i < b.N; i++ {
+			if err := fx.shares[0].SetKey(fx.meta, fx.keyInit); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkSigSession_Round1(b *testing.B) {
+	runConfigs(b, func(b *testing.B, fx *benchFixture) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			m := fx.run(b, 0)
+			b.StartTimer()
+			if _, err := m.sessions[0].Round1(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkSigSession_Round2(b *testing.B) {
+	runConfigs(b, func(b *testing.B, fx *benchFixture) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			m := fx.run(b, 1)
+			b.StartTimer()
+			if _, err := m.sessions[0].Round2(m.round1); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkSigSession_Round3(b *testing.B) {
+	runConfigs(b, func(b *testing.B, fx *benchFixture) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			m := fx.run(b, 2)
+			b.StartTimer()
+			if _, err := m.sessions[0].Round3(m.round2); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkSigSession_GetSignature(b *testing.B) {
+	runConfigs(b, func(b *testing.B, fx *benchFixture) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			m := fx.run(b, 3)
+			b.StartTimer()
+			if _, _, err := m.sessions[0].GetSignature(m.round3); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkKeyInitMessageList_Join(b *testing.B) {
+	runConfigs(b, func(b *testing.B, fx *benchFixture) {
+		for i := 0; i < b.N; i++ {
+			if _, _, err := fx.keyInit.Join(fx.meta); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkRound1MessageList_Join(b *testing.B) {
+	runConfigs(b, func(b *testing.B, fx *benchFixture) {
+		for i := 0; i < b.N; i++ {
+			if _, _, _, _, err := fx.messages.round1.Join(fx.meta); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkRound1MessageList_VerifyBatch(b *testing.B) {
+	runConfigs(b, func(b *testing.B, fx *benchFixture) {
+		for i := 0; i < b.N; i++ {
+			for _, err := range fx.messages.round1.VerifyBatch(fx.meta) {
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
+
+func BenchmarkRound2MessageList_Join(b *testing.B) {
+	runConfigs(b, func(b *testing.B, fx *benchFixture) {
+		z := fx.messages.sessions[0].z
+		for i := 0; i < b.N; i++ {
+			if _, err := fx.messages.round2.Join(fx.meta, z); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkRound3MessageList_Join(b *testing.B) {
+	runConfigs(b, func(b *testing.B, fx *benchFixture) {
+		sigma := fx.messages.sessions[0].sigma
+		for i := 0; i < b.N; i++ {
+			if _, err := fx.messages.round3.Join(fx.meta, sigma); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkNewKeyGenZKProof(b *testing.B) {
+	runConfigs(b, func(b *testing.B, fx *benchFixture) {
+		xi, err := RandomFieldElement(fx.meta.Curve())
+		if err != nil {
+			b.Fatal(err)
+		}
+		yi := fx.meta.baseMul(xi)
+		alphaI, r, err := fx.meta.Encrypt(xi)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := newKeyGenZKProof(fx.meta, xi, yi, alphaI, r); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkKeyGenZKProof_Verify(b *testing.B) {
+	runConfigs(b, func(b *testing.B, fx *benchFixture) {
+		msg := fx.keyInit[0]
+		for i := 0; i < b.N; i++ {
+			if err := msg.Proof.Verify(fx.meta, msg.Yi, msg.AlphaI); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkNewSigZKProof(b *testing.B) {
+	runConfigs(b, func(b *testing.B, fx *benchFixture) {
+		meta := fx.meta
+		k, err := RandomInRange(zero, meta.Q())
+		if err != nil {
+			b.Fatal(err)
+		}
+		vi, rvi, err := meta.Encrypt(k)
+		if err != nil {
+			b.Fatal(err)
+		}
+		ui, rui, err := meta.Encrypt(k)
+		if err != nil {
+			b.Fatal(err)
+		}
+		wi, rwi, err := meta.Encrypt(k)
+		if err != nil {
+			b.Fatal(err)
+		}
+		params := &SigZKProofParams{
+			Ri:     meta.baseMul(k),
+			Eta1:   k,
+			Eta2:   k,
+			Eta3:   k,
+			EncUi:  ui,
+			EncVi:  vi,
+			EncWi:  wi,
+			RandUi: rui,
+			RandVi: rvi,
+			RandWi: rwi,
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := NewSigZKProof(meta, params); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkSigZKProof_Verify(b *testing.B) {
+	runConfigs(b, func(b *testing.B, fx *benchFixture) {
+		msg := fx.messages.round1[0]
+		for i := 0; i < b.N; i++ {
+			if err := msg.Proof.Verify(fx.meta, msg.Ri, msg.Ui, msg.Vi, msg.Wi); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkRound2Message_Verify(b *testing.B) {
+	runConfigs(b, func(b *testing.B, fx *benchFixture) {
+		z := fx.messages.sessions[0].z
+		for i := 0; i < b.N; i++ {
+			if err := fx.messages.round2[0].Verify(fx.meta, z); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,311 @@
+package tcecdsa
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"golang.org/x/crypto/ripemd160"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// HardenedKeyStart is the index of the first hardened child key. Hardened derivation requires
+// the private key, so it is not supported for threshold keys.
+const HardenedKeyStart uint32 = 0x80000000
+
+// ChainCodeSize is the size in bytes of a BIP-32 chain code.
+const ChainCodeSize = 32
+
+// extendedKeySize is the size in bytes of a serialized extended key, without checksum.
+const extendedKeySize = 78
+
+// The following values are the version bytes of serialized extended public keys.
+var (
+	XPubVersion = []byte{0x04, 0x88, 0xB2, 0x1E} // Mainnet extended public key (xpub).
+	TPubVersion = []byte{0x04, 0x35, 0x87, 0xCF} // Testnet extended public key (tpub).
+)
+
+// ExtendedKeyShare represents a KeyShare extended with the BIP-32 values needed to derive child keys.
+// Child shares are also ExtendedKeyShares, and they can be used to sign as any other KeyShare.
+type ExtendedKeyShare struct {
+	*KeyShare
+	ChainCode         []byte // Chain code of this key
+	Depth             uint8  // Number of derivations from master key
+	ParentFingerprint []byte // First four bytes of the parent public key Hash160
+	ChildNumber       uint32 // Index used to derive this key from its parent
+}
+
+// ExtendedPublicKey represents a BIP-32 extended public key.
+type ExtendedPublicKey struct {
+	Version           []byte // Version bytes used on serialization
+	Key               *Point // Public Key
+	ChainCode         []byte // Chain code of this key
+	Depth             uint8  // Number of derivations from master key
+	ParentFingerprint []byte // First four bytes of the parent public key Hash160
+	ChildNumber       uint32 // Index used to derive this key from its parent
+}
+
+// NewChainCode returns a new random chain code. All the participants must use the same chain code,
+// so it should be generated by one of them and distributed with the key shares.
+func NewChainCode() (chainCode []byte, err error) {
+	chainCode = make([]byte, ChainCodeSize)
+	_, err = io.ReadFull(rand.Reader, chainCode)
+	return
+}
+
+// Extend returns the master ExtendedKeyShare related to a KeyShare with its public key already set,
+// using the given chain code.
+func (p *KeyShare) Extend(chainCode []byte) (xp *ExtendedKeyShare, err error) {
+	if len(chainCode) != ChainCodeSize {
+		err = fmt.Errorf("chain code should be %d bytes long, but it is %d", ChainCodeSize, len(chainCode))
+		return
+	}
+	if p.Alpha == nil || p.Y == nil {
+		err = fmt.Errorf("key share has not been set")
+		return
+	}
+	xp = &ExtendedKeyShare{
+		KeyShare:          p,
+		ChainCode:         append([]byte{}, chainCode...),
+		ParentFingerprint: make([]byte, 4),
+	}
+	return
+}
+
+// Child derives the non-hardened child key share with the given index. The private key tweak is added
+// homomorphically to Alpha and the public key is tweaked using point addition, so every participant
+// obtains locally its share of the same child key. The key should have been created over secp256k1 curve.
+func (xp *ExtendedKeyShare) Child(meta *KeyMeta, index uint32) (child *ExtendedKeyShare, err error) {
+	curve := meta.Curve()
+	if curve != Secp256k1() {
+		err = fmt.Errorf("BIP-32 derivation is defined only over secp256k1, but the key curve is %s", meta.CurveName)
+		return
+	}
+	il, childChainCode, err := childTweak(curve, xp.Y, xp.ChainCode, index)
+	if err != nil {
+		return
+	}
+	encIL, err := meta.EncryptFixedB(il, one, one)
+	if err != nil {
+		return
+	}
+	alpha, err := meta.AddL1(xp.Alpha, encIL)
+	if err != nil {
+		return
+	}
+	y := NewZero().Add(curve, xp.Y, NewZero().BaseMul(curve, il))
+	if y.X.Sign() == 0 && y.Y.Sign() == 0 {
+		err = fmt.Errorf("child key %d is the point at infinity, use the next index", index)
+		return
+	}
+	child = &ExtendedKeyShare{
+		KeyShare: &KeyShare{
+			Index:         xp.Index,
+			Alpha:         alpha,
+			Y:             y,
+			PaillierShare: xp.PaillierShare,
+		},
+		ChainCode:         childChainCode,
+		Depth:             xp.Depth + 1,
+		ParentFingerprint: fingerprint(curve, xp.Y),
+		ChildNumber:       index,
+	}
+	return
+}
+
+// DerivePath derives the key share for a path of non-hardened indexes, relative to this key.
+func (xp *ExtendedKeyShare) DerivePath(meta *KeyMeta, path []uint32) (child *ExtendedKeyShare, err error) {
+	child = xp
+	for _, index := range path {
+		child, err = child.Child(meta, index)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// PublicKey returns the extended public key related to this key share, using mainnet version bytes.
+// It returns an error if the key was not created over secp256k1 curve.
+func (xp *ExtendedKeyShare) PublicKey(meta *KeyMeta) (xpub *ExtendedPublicKey, err error) {
+	if meta.Curve() != Secp256k1() {
+		err = fmt.Errorf("extended public keys are defined only over secp256k1, but the key curve is %s", meta.CurveName)
+		return
+	}
+	xpub = &ExtendedPublicKey{
+		Version:           XPubVersion,
+		Key:               xp.Y.Clone(),
+		ChainCode:         append([]byte{}, xp.ChainCode...),
+		Depth:             xp.Depth,
+		ParentFingerprint: append([]byte{}, xp.ParentFingerprint...),
+		ChildNumber:       xp.ChildNumber,
+	}
+	return
+}
+
+// Child derives the non-hardened child extended public key with the given index.
+// Extended public keys are defined only over secp256k1.
+func (xpub *ExtendedPublicKey) Child(index uint32) (child *ExtendedPublicKey, err error) {
+	curve := Secp256k1()
+	if err = xpub.checkKey(); err != nil {
+		return
+	}
+	il, childChainCode, err := childTweak(curve, xpub.Key, xpub.ChainCode, index)
+	if err != nil {
+		return
+	}
+	key := NewZero().Add(curve, xpub.Key, NewZero().BaseMul(curve, il))
+	if key.X.Sign() == 0 && key.Y.Sign() == 0 {
+		err = fmt.Errorf("child key %d is the point at infinity, use the next index", index)
+		return
+	}
+	child = &ExtendedPublicKey{
+		Version:           xpub.Version,
+		Key:               key,
+		ChainCode:         childChainCode,
+		Depth:             xpub.Depth + 1,
+		ParentFingerprint: fingerprint(curve, xpub.Key),
+		ChildNumber:       index,
+	}
+	return
+}
+
+// String returns the base58check serialization of the extended public key, or an empty string if its key is
+// not a secp256k1 point. Use Serialize to get the error.
+func (xpub *ExtendedPublicKey) String() string {
+	s, _ := xpub.Serialize()
+	return s
+}
+
+// Serialize returns the base58check serialization of the extended public key. It returns an error if its key
+// is not a secp256k1 point.
+func (xpub *ExtendedPublicKey) Serialize() (s string, err error) {
+	if err = xpub.checkKey(); err != nil {
+		return
+	}
+	b := make([]byte, 0, extendedKeySize)
+	b = append(b, xpub.Version...)
+	b = append(b, xpub.Depth)
+	b = append(b, xpub.ParentFingerprint...)
+	childNumber := make([]byte, 4)
+	binary.BigEndian.PutUint32(childNumber, xpub.ChildNumber)
+	b = append(b, childNumber...)
+	b = append(b, xpub.ChainCode...)
+	b = append(b, xpub.Key.CompressedBytes(Secp256k1())...)
+	s = Base58CheckEncode(b)
+	return
+}
+
+// checkKey returns an error if the key of the extended public key is not a valid secp256k1 point.
+func (xpub *ExtendedPublicKey) checkKey() error {
+	if xpub.Key != nil && xpub.Key.Curve() != nil && xpub.Key.Curve() != Secp256k1() {
+		return fmt.Errorf("extended public keys are defined only over secp256k1, but the key curve is %s", xpub.Key.Curve().Params().Name)
+	}
+	if err := xpub.Key.Validate(Secp256k1()); err != nil {
+		return fmt.Errorf("invalid extended public key: %s", err)
+	}
+	return nil
+}
+
+// ParseExtendedPublicKey parses a base58check serialized extended public key over secp256k1.
+func ParseExtendedPublicKey(s string) (xpub *ExtendedPublicKey, err error) {
+	b, err := Base58CheckDecode(s)
+	if err != nil {
+		return
+	}
+	if len(b) != extendedKeySize {
+		err = fmt.Errorf("extended key should be %d bytes long, but it is %d", extendedKeySize, len(b))
+		return
+	}
+	version := b[0:4]
+	if !bytes.Equal(version, XPubVersion) && !bytes.Equal(version, TPubVersion) {
+		err = fmt.Errorf("unknown extended public key version %x", version)
+		return
+	}
+	key, err := NewZero().SetCompressedBytes(Secp256k1(), b[45:78])
+	if err != nil {
+		return
+	}
+	xpub = &ExtendedPublicKey{
+		Version:           append([]byte{}, version...),
+		Depth:             b[4],
+		ParentFingerprint: append([]byte{}, b[5:9]...),
+		ChildNumber:       binary.BigEndian.Uint32(b[9:13]),
+		ChainCode:         append([]byte{}, b[13:45]...),
+		Key:               key,
+	}
+	return
+}
+
+// ParseDerivationPath parses a BIP-32 path of non-hardened indexes, like "m/0/1".
+// The path could also be relative, omitting the initial "m/".
+func ParseDerivationPath(path string) (indexes []uint32, err error) {
+	indexes = make([]uint32, 0)
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "m"), "/")
+	if path == "" {
+		return
+	}
+	for _, elem := range strings.Split(path, "/") {
+		if strings.HasSuffix(elem, "'") || strings.HasSuffix(elem, "h") {
+			err = fmt.Errorf("hardened derivation (%s) is not supported for threshold keys", elem)
+			return
+		}
+		index, err2 := strconv.ParseUint(elem, 10, 32)
+		if err2 != nil {
+			err = fmt.Errorf("invalid path element %s: %s", elem, err2)
+			return
+		}
+		if uint32(index) >= HardenedKeyStart {
+			err = fmt.Errorf("index %d is hardened, but hardened derivation is not supported", index)
+			return
+		}
+		indexes = append(indexes, uint32(index))
+	}
+	return
+}
+
+// childTweak returns the BIP-32 tweak IL and the child chain code of a non-hardened child with the given index.
+func childTweak(curve elliptic.Curve, key *Point, chainCode []byte, index uint32) (il *big.Int, childChainCode []byte, err error) {
+	if index >= HardenedKeyStart {
+		err = fmt.Errorf("hardened derivation is not supported for threshold keys")
+		return
+	}
+	if len(chainCode) != ChainCodeSize {
+		err = fmt.Errorf("chain code should be %d bytes long, but it is %d", ChainCodeSize, len(chainCode))
+		return
+	}
+	data := key.CompressedBytes(curve)
+	indexBytes := make([]byte, 4)
+	binary.BigEndian.PutUint32(indexBytes, index)
+	data = append(data, indexBytes...)
+	mac := hmac.New(sha512.New, chainCode)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+	il = new(big.Int).SetBytes(sum[:32])
+	if il.Cmp(curve.Params().N) >= 0 {
+		err = fmt.Errorf("tweak of child key %d is not lower than curve order, use the next index", index)
+		return
+	}
+	childChainCode = sum[32:]
+	return
+}
+
+// fingerprint returns the first four bytes of the Hash160 of a compressed public key.
+func fingerprint(curve elliptic.Curve, key *Point) []byte {
+	return hash160(key.CompressedBytes(curve))[:4]
+}
+
+// hash160 returns RIPEMD160(SHA256(b)).
+func hash160(b []byte) []byte {
+	sha := sha256.Sum256(b)
+	h := ripemd160.New()
+	h.Write(sha[:])
+	return h.Sum(nil)
+}
@@ -0,0 +1,163 @@
+package tcecdsa_test
+
+import (
+	"crypto/ecdsa"
+	"github.com/niclabs/tcecdsa"
+	"testing"
+)
+
+// Extended public keys from BIP-32 test vector 2.
+const masterXPub = "xpub661MyMwAqRbcFW31YEwpkMuc5THy2PSt5bDMsktWQcFF8syAmRUapSCGu8ED9W6oDMSgv6Zz8idoc4a6mr8BDzTJY47LJhkJ8UB7WEGuduB"
+const childXPub = "xpub69H7F5d8KSRgmmdJg2KhpAK8SR3DjMwAdkxj3ZuxV27CprR9LgpeyGmXUbC6wb7ERfvrnKZjXoUmmDznezpbZb7ap6r1D3tgFxHmwMkQTPH"
+
+func TestExtendedPublicKey_Child(t *testing.T) {
+	master, err := tcecdsa.ParseExtendedPublicKey(masterXPub)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if master.String() != masterXPub {
+		t.Errorf("serialized master key should be %s, but it is %s", masterXPub, master)
+		return
+	}
+	child, err := master.Child(0)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if child.String() != childXPub {
+		t.Errorf("child key should be %s, but it is %s", childXPub, child)
+		return
+	}
+}
+
+func TestParseDerivationPath(t *testing.T) {
+	path, err := tcecdsa.ParseDerivationPath("m/0/1/2")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(path) != 3 || path[0] != 0 || path[1] != 1 || path[2] != 2 {
+		t.Errorf("path should be [0 1 2], but it is %v", path)
+		return
+	}
+	if _, err := tcecdsa.ParseDerivationPath("m/0'/1"); err == nil {
+		t.Errorf("hardened path should not be accepted")
+		return
+	}
+}
+
+func TestExtendedKeyShare_DerivePath(t *testing.T) {
+	shares, keyMeta, _, err := newKey(SchnorrCurve)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	chainCode, err := tcecdsa.NewChainCode()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	path, err := tcecdsa.ParseDerivationPath("m/0/7")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	childShares := make([]*tcecdsa.KeyShare, 0)
+	var xpub *tcecdsa.ExtendedPublicKey
+	for _, share := range shares {
+		xShare, err := share.Extend(chainCode)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if xpub == nil {
+			xpub, err = xShare.PublicKey(keyMeta)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+		}
+		child, err := xShare.DerivePath(keyMeta, path)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		childShares = append(childShares, child.KeyShare)
+	}
+
+	// The parent xpub should derive the same child public key.
+	exported, err := tcecdsa.ParseExtendedPublicKey(xpub.String())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	childXPub := exported
+	for _, index := range path {
+		childXPub, err = childXPub.Child(index)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	for i, child := range childShares {
+		if child.Y.Cmp(childXPub.Key) != 0 {
+			t.Errorf("child public key of share %d is different to the one derived from xpub", i)
+			return
+		}
+	}
+
+	Hash.Reset()
+	Hash.Write(exampleText)
+	h := Hash.Sum(nil)
+	r, s, err := sign(childShares, keyMeta, h)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	pk := &ecdsa.PublicKey{
+		Curve: keyMeta.Curve(),
+		X:     childXPub.Key.X,
+		Y:     childXPub.Key.Y,
+	}
+	if !ecdsa.Verify(pk, h, r, s) {
+		t.Errorf("verification with child key failed")
+		return
+	}
+}
+
+func TestExtendedKeyShare_OtherCurve(t *testing.T) {
+	shares, keyMeta, _, err := newKey("P-256")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	chainCode, err := tcecdsa.NewChainCode()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	xShare, err := shares[0].Extend(chainCode)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := xShare.PublicKey(keyMeta); err == nil {
+		t.Error("extended public key should not be exported for a P-256 key")
+		return
+	}
+	if _, err := xShare.Child(keyMeta, 0); err == nil {
+		t.Error("child key should not be derived for a P-256 key")
+		return
+	}
+	xpub := &tcecdsa.ExtendedPublicKey{
+		Version:           tcecdsa.XPubVersion,
+		Key:               shares[0].Y,
+		ChainCode:         chainCode,
+		ParentFingerprint: make([]byte, 4),
+	}
+	if _, err := xpub.Serialize(); err == nil || xpub.String() != "" {
+		t.Error("extended public key with a P-256 point should not be serialized")
+		return
+	}
+}
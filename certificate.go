@@ -0,0 +1,126 @@
+package tcecdsa
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// errDigestCaptured is returned by digestSigner when the digest to sign is captured.
+var errDigestCaptured = errors.New("digest captured")
+
+// X509Session represents a threshold signing session over a X.509 structure (a certificate, a
+// certificate request or a revocation list). It is a SigSession, so its rounds are used in the same
+// way, but it returns the DER encoding of the signed structure.
+// The structure is created twice using the Go x509 package: the first time to obtain the digest
+// of the data to be signed, and the second time with the signature produced by the threshold protocol.
+type X509Session struct {
+	*SigSession
+	signer *digestSigner
+	create func(signer crypto.Signer) ([]byte, error)
+}
+
+// digestSigner is a crypto.Signer that captures the digest it should sign on its first use and
+// returns an already known signature on the following ones.
+type digestSigner struct {
+	pub    *ecdsa.PublicKey
+	digest []byte
+	sig    []byte
+}
+
+// Public returns the threshold public key.
+func (s *digestSigner) Public() crypto.PublicKey {
+	return s.pub
+}
+
+// Sign captures the digest if the signature is not known, or returns the known signature if the
+// digest is equal to the captured one.
+func (s *digestSigner) Sign(_ io.Reader, digest []byte, _ crypto.SignerOpts) ([]byte, error) {
+	if s.sig == nil {
+		s.digest = append([]byte{}, digest...)
+		return nil, errDigestCaptured
+	}
+	if !bytes.Equal(s.digest, digest) {
+		return nil, fmt.Errorf("data to be signed changed between creations")
+	}
+	return s.sig, nil
+}
+
+// NewCertificateSession creates a new signing session over a X.509 certificate, issued by the threshold key.
+// The arguments are the same used by x509.CreateCertificate. The template serial number must be set,
+// because all the participants must sign the same certificate.
+func (p *KeyShare) NewCertificateSession(meta *KeyMeta, template, parent *x509.Certificate, pub interface{}) (*X509Session, error) {
+	if template.SerialNumber == nil {
+		return nil, fmt.Errorf("template serial number must be set")
+	}
+	return p.newX509Session(meta, func(signer crypto.Signer) ([]byte, error) {
+		return x509.CreateCertificate(rand.Reader, template, parent, pub, signer)
+	})
+}
+
+// NewCertificateRequestSession creates a new signing session over a PKCS#10 certificate request for
+// the threshold key. The template is the same used by x509.CreateCertificateRequest.
+func (p *KeyShare) NewCertificateRequestSession(meta *KeyMeta, template *x509.CertificateRequest) (*X509Session, error) {
+	return p.newX509Session(meta, func(signer crypto.Signer) ([]byte, error) {
+		return x509.CreateCertificateRequest(rand.Reader, template, signer)
+	})
+}
+
+// NewRevocationListSession creates a new signing session over a X.509 CRL, issued by the threshold key.
+// The arguments are the same used by x509.CreateRevocationList.
+func (p *KeyShare) NewRevocationListSession(meta *KeyMeta, template *x509.RevocationList, issuer *x509.Certificate) (*X509Session, error) {
+	return p.newX509Session(meta, func(signer crypto.Signer) ([]byte, error) {
+		return x509.CreateRevocationList(rand.Reader, template, issuer, signer)
+	})
+}
+
+// newX509Session captures the digest of the structure generated by create and creates a signing session over it.
+func (p *KeyShare) newX509Session(meta *KeyMeta, create func(signer crypto.Signer) ([]byte, error)) (state *X509Session, err error) {
+	pk, err := p.PublicKey(meta)
+	if err != nil {
+		return
+	}
+	signer := &digestSigner{pub: pk}
+	if _, err = create(signer); !errors.Is(err, errDigestCaptured) {
+		if err == nil {
+			err = fmt.Errorf("structure was created without signing it")
+		}
+		return
+	}
+	session, err := p.NewSigSession(meta, signer.digest)
+	if err != nil {
+		return
+	}
+	state = &X509Session{
+		SigSession: session,
+		signer:     signer,
+		create:     create,
+	}
+	return
+}
+
+// GetDER joins the last values and returns the DER encoding of the signed structure.
+func (state *X509Session) GetDER(msgs Round3MessageList) (der []byte, err error) {
+	r, s, err := state.GetSignature(msgs)
+	if err != nil {
+		return
+	}
+	sig, err := MarshalSignature(r, s)
+	if err != nil {
+		return
+	}
+	state.signer.sig = sig
+	return state.create(state.signer)
+}
+
+// NewSerialNumber returns a random 128 bits positive serial number for a certificate.
+func NewSerialNumber() (*big.Int, error) {
+	limit := new(big.Int).Lsh(one, 128)
+	return RandomInRange(one, limit)
+}
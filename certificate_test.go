@@ -0,0 +1,182 @@
+package tcecdsa_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/niclabs/tcecdsa"
+	"github.com/niclabs/tcpaillier"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// signX509 runs the signing protocol between all the sessions and returns the DER signed structure.
+func signX509(shares []*tcecdsa.KeyShare, newSession func(share *tcecdsa.KeyShare) (*tcecdsa.X509Session, error)) ([]byte, error) {
+	states := make([]*tcecdsa.X509Session, 0)
+	sessions := make([]*tcecdsa.SigSession, 0)
+	for _, share := range shares {
+		state, err := newSession(share)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, state)
+		sessions = append(sessions, state.SigSession)
+	}
+	round3Messages, err := runRounds(sessions)
+	if err != nil {
+		return nil, err
+	}
+	return states[0].GetDER(round3Messages)
+}
+
+func TestX509Session(t *testing.T) {
+	params := &tcecdsa.NewKeyParams{
+		PaillierFixed: &tcpaillier.FixedParams{
+			P:  p256,
+			P1: p1256,
+			Q:  q256,
+			Q1: q1256,
+		},
+	}
+	shares, keyMeta, err := tcecdsa.NewKey(L, K, "P-256", params)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	pk, err := initKey(shares, keyMeta)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	now := time.Now()
+	var root *x509.Certificate
+
+	t.Run("RootCertificate", func(t *testing.T) {
+		serial, err := tcecdsa.NewSerialNumber()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		template := &x509.Certificate{
+			SerialNumber:          serial,
+			Subject:               pkix.Name{CommonName: "tcecdsa root"},
+			NotBefore:             now,
+			NotAfter:              now.Add(time.Hour),
+			KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+			BasicConstraintsValid: true,
+			IsCA:                  true,
+		}
+		der, err := signX509(shares, func(share *tcecdsa.KeyShare) (*tcecdsa.X509Session, error) {
+			return share.NewCertificateSession(keyMeta, template, template, pk)
+		})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		root, err = x509.ParseCertificate(der)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if err := root.CheckSignatureFrom(root); err != nil {
+			t.Error(err)
+			return
+		}
+	})
+
+	t.Run("LeafCertificate", func(t *testing.T) {
+		if root == nil {
+			t.Skip("root certificate was not created")
+		}
+		leafKey, err := ecdsa.GenerateKey(keyMeta.Curve(), rand.Reader)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(2),
+			Subject:      pkix.Name{CommonName: "tcecdsa leaf"},
+			DNSNames:     []string{"leaf.example.com"},
+			NotBefore:    now,
+			NotAfter:     now.Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		}
+		der, err := signX509(shares, func(share *tcecdsa.KeyShare) (*tcecdsa.X509Session, error) {
+			return share.NewCertificateSession(keyMeta, template, root, &leafKey.PublicKey)
+		})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		leaf, err := x509.ParseCertificate(der)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if err := leaf.CheckSignatureFrom(root); err != nil {
+			t.Error(err)
+			return
+		}
+		pool := x509.NewCertPool()
+		pool.AddCert(root)
+		if _, err := leaf.Verify(x509.VerifyOptions{DNSName: "leaf.example.com", Roots: pool}); err != nil {
+			t.Error(err)
+			return
+		}
+	})
+
+	t.Run("RevocationList", func(t *testing.T) {
+		if root == nil {
+			t.Skip("root certificate was not created")
+		}
+		template := &x509.RevocationList{
+			Number:     big.NewInt(1),
+			ThisUpdate: now,
+			NextUpdate: now.Add(time.Hour),
+			RevokedCertificates: []pkix.RevokedCertificate{
+				{SerialNumber: big.NewInt(2), RevocationTime: now},
+			},
+		}
+		der, err := signX509(shares, func(share *tcecdsa.KeyShare) (*tcecdsa.X509Session, error) {
+			return share.NewRevocationListSession(keyMeta, template, root)
+		})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		crl, err := x509.ParseRevocationList(der)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if err := crl.CheckSignatureFrom(root); err != nil {
+			t.Error(err)
+			return
+		}
+	})
+
+	t.Run("CertificateRequest", func(t *testing.T) {
+		template := &x509.CertificateRequest{
+			Subject: pkix.Name{CommonName: "tcecdsa request"},
+		}
+		der, err := signX509(shares, func(share *tcecdsa.KeyShare) (*tcecdsa.X509Session, error) {
+			return share.NewCertificateRequestSession(keyMeta, template)
+		})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		csr, err := x509.ParseCertificateRequest(der)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if err := csr.CheckSignature(); err != nil {
+			t.Error(err)
+			return
+		}
+	})
+}
@@ -0,0 +1,250 @@
+package tcecdsa
+
+import (
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+	"io"
+	"math/big"
+	"sync"
+)
+
+// channelDomain is used on the derivation of the channel keys.
+const channelDomain = "tcecdsa channel v2"
+
+// curve25519P is the prime of the field of Curve25519 and Ed25519, 2^255 - 19.
+var curve25519P = new(big.Int).Sub(new(big.Int).Lsh(one, 255), big.NewInt(19))
+
+// SealedMessage is a message encrypted on a Channel.
+type SealedMessage struct {
+	Sender     uint8  // Index of the participant who sent the message
+	Receiver   uint8  // Index of the participant the message is sent to
+	Seq        uint64 // Sequence number of the message, used as nonce
+	Ciphertext []byte // Encrypted and authenticated message
+}
+
+// ChannelHandshake is the message that each side of a Channel sends to the other one when the channel is
+// created, with a new ephemeral X25519 public key.
+type ChannelHandshake struct {
+	Sender    uint8  // Index of the participant who sent the handshake
+	Receiver  uint8  // Index of the participant the handshake is sent to
+	Ephemeral []byte // Ephemeral X25519 public key of the sender
+}
+
+// Channel is an encrypted and authenticated point-to-point channel between two participants, keyed
+// with their identity keys. Each side sends a ChannelHandshake with a new ephemeral X25519 key, and the
+// X25519 shared secrets of the identity keys and of the ephemeral keys are used to derive a
+// ChaCha20-Poly1305 key for each direction of the channel. The identity keys authenticate the channel, and
+// the ephemeral keys make the keys of every channel different, even if a channel ID is reused or a
+// participant restarts, so the sequence numbers, used as nonces, never repeat with the same key.
+// Every message has a sequence number, and messages with a sequence number that is not greater than the
+// last received one are rejected, so they cannot be replayed. The ephemeral private key is discarded when
+// the handshake of the peer is accepted, so the messages cannot be decrypted later with the identity keys.
+type Channel struct {
+	local, peer      uint8
+	channelID        string
+	shared           []byte // X25519 shared secret of the identity keys, discarded after the handshake
+	ephemeral        []byte // Ephemeral X25519 private key, discarded after the handshake
+	handshake        *ChannelHandshake
+	sendKey, recvKey cipher.AEAD
+	mutex            sync.Mutex
+	sendSeq          uint64
+	recvSeq          uint64 // Next sequence number that can be accepted
+}
+
+// NewChannel returns a new channel between the identity and the peer with the given index, whose public
+// key is taken from the roster, and the handshake that must be sent to the peer. The channel can be used
+// after Accept receives the handshake of the peer. Both sides of the channel must use the same channel ID.
+func NewChannel(id *Identity, peer uint8, roster Roster, channelID string) (ch *Channel, handshake *ChannelHandshake, err error) {
+	if peer == id.Index {
+		err = fmt.Errorf("peer cannot be the local participant")
+		return
+	}
+	peerKey, ok := roster[peer]
+	if !ok {
+		err = fmt.Errorf("peer %d is not in the roster", peer)
+		return
+	}
+	peerX25519, err := ed25519PublicKeyToX25519(peerKey)
+	if err != nil {
+		return
+	}
+	shared, err := curve25519.X25519(ed25519PrivateKeyToX25519(id.PrivateKey), peerX25519)
+	if err != nil {
+		return
+	}
+	ephemeral := make([]byte, curve25519.ScalarSize)
+	if _, err = io.ReadFull(rand.Reader, ephemeral); err != nil {
+		return
+	}
+	ephemeralPublic, err := curve25519.X25519(ephemeral, curve25519.Basepoint)
+	if err != nil {
+		return
+	}
+	handshake = &ChannelHandshake{
+		Sender:    id.Index,
+		Receiver:  peer,
+		Ephemeral: ephemeralPublic,
+	}
+	ch = &Channel{
+		local:     id.Index,
+		peer:      peer,
+		channelID: channelID,
+		shared:    shared,
+		ephemeral: ephemeral,
+		handshake: handshake,
+	}
+	return
+}
+
+// Accept receives the handshake of the peer and derives the keys of the channel. It can be called only once.
+func (ch *Channel) Accept(handshake *ChannelHandshake) (err error) {
+	ch.mutex.Lock()
+	defer ch.mutex.Unlock()
+	if ch.ephemeral == nil {
+		err = fmt.Errorf("channel handshake was already accepted")
+		return
+	}
+	if handshake == nil || handshake.Sender != ch.peer || handshake.Receiver != ch.local {
+		err = fmt.Errorf("handshake does not belong to this channel")
+		return
+	}
+	// X25519 returns an error if the peer key has a low order, so the shared secret would be zero.
+	ephemeralShared, err := curve25519.X25519(ch.ephemeral, handshake.Ephemeral)
+	if err != nil {
+		return
+	}
+	secret := append(append([]byte{}, ch.shared...), ephemeralShared...)
+	sendKey, err := channelKey(secret, ch.channelID, ch.handshake, handshake)
+	if err != nil {
+		return
+	}
+	recvKey, err := channelKey(secret, ch.channelID, handshake, ch.handshake)
+	if err != nil {
+		return
+	}
+	ch.sendKey, ch.recvKey = sendKey, recvKey
+	ch.shared, ch.ephemeral = nil, nil
+	return
+}
+
+// Seal encrypts a message to the peer.
+func (ch *Channel) Seal(plaintext []byte) (msg *SealedMessage, err error) {
+	ch.mutex.Lock()
+	defer ch.mutex.Unlock()
+	if ch.sendKey == nil {
+		err = fmt.Errorf("channel handshake has not been accepted")
+		return
+	}
+	if ch.sendSeq == ^uint64(0) {
+		err = fmt.Errorf("channel sequence numbers are exhausted")
+		return
+	}
+	msg = &SealedMessage{
+		Sender:   ch.local,
+		Receiver: ch.peer,
+		Seq:      ch.sendSeq,
+	}
+	msg.Ciphertext = ch.sendKey.Seal(nil, msg.nonce(), plaintext, msg.additionalData())
+	ch.sendSeq++
+	return
+}
+
+// Open decrypts a message from the peer. It returns an error if the message was not sent by the peer to
+// this participant, if it was modified, or if its sequence number was already used.
+func (ch *Channel) Open(msg *SealedMessage) (plaintext []byte, err error) {
+	ch.mutex.Lock()
+	defer ch.mutex.Unlock()
+	if msg.Sender != ch.peer || msg.Receiver != ch.local {
+		err = fmt.Errorf("message from %d to %d does not belong to this channel", msg.Sender, msg.Receiver)
+		return
+	}
+	if ch.recvKey == nil {
+		err = fmt.Errorf("channel handshake has not been accepted")
+		return
+	}
+	if msg.Seq < ch.recvSeq {
+		err = fmt.Errorf("message with sequence number %d was replayed or reordered", msg.Seq)
+		return
+	}
+	plaintext, err = ch.recvKey.Open(nil, msg.nonce(), msg.Ciphertext, msg.additionalData())
+	if err != nil {
+		return
+	}
+	ch.recvSeq = msg.Seq + 1
+	return
+}
+
+// nonce returns the AEAD nonce of the message, built from its sequence number.
+func (msg *SealedMessage) nonce() []byte {
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	binary.BigEndian.PutUint64(nonce[chacha20poly1305.NonceSize-8:], msg.Seq)
+	return nonce
+}
+
+// additionalData returns the data authenticated with the message.
+func (msg *SealedMessage) additionalData() []byte {
+	ad := make([]byte, 10)
+	ad[0], ad[1] = msg.Sender, msg.Receiver
+	binary.BigEndian.PutUint64(ad[2:], msg.Seq)
+	return ad
+}
+
+// channelKey derives the key used by the sender of the first handshake to encrypt messages to its receiver,
+// from the shared secrets of the identity and ephemeral keys.
+func channelKey(secret []byte, channelID string, sender, receiver *ChannelHandshake) (aead cipher.AEAD, err error) {
+	info := append([]byte(channelDomain), sender.Sender, receiver.Sender)
+	info = append(append(info, sender.Ephemeral...), receiver.Ephemeral...)
+	key := make([]byte, chacha20poly1305.KeySize)
+	if _, err = io.ReadFull(hkdf.New(sha256.New, secret, []byte(channelID), info), key); err != nil {
+		return
+	}
+	return chacha20poly1305.New(key)
+}
+
+// ed25519PrivateKeyToX25519 returns the X25519 private key related to an Ed25519 private key, which is
+// the first half of the SHA-512 hash of its seed (RFC 8032).
+func ed25519PrivateKeyToX25519(sk ed25519.PrivateKey) []byte {
+	h := sha512.Sum512(sk.Seed())
+	return h[:curve25519.ScalarSize]
+}
+
+// ed25519PublicKeyToX25519 returns the X25519 public key related to an Ed25519 public key, using the
+// birational map u = (1 + y) / (1 - y) between the Edwards and Montgomery forms of the curve.
+func ed25519PublicKeyToX25519(pk ed25519.PublicKey) ([]byte, error) {
+	if len(pk) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("identity public key should be %d bytes long, but it is %d", ed25519.PublicKeySize, len(pk))
+	}
+	// Ed25519 public keys are little endian encoded y coordinates, with the sign of x in the highest bit.
+	yBytes := reverseBytes(pk)
+	yBytes[0] &= 0x7f
+	y := new(big.Int).SetBytes(yBytes)
+	if y.Cmp(curve25519P) >= 0 {
+		return nil, fmt.Errorf("identity public key is not valid")
+	}
+	den := new(big.Int).Sub(one, y)
+	den.Mod(den, curve25519P)
+	if den.Sign() == 0 {
+		return nil, fmt.Errorf("identity public key is not valid")
+	}
+	u := new(big.Int).Add(one, y)
+	u.Mul(u, den.ModInverse(den, curve25519P))
+	u.Mod(u, curve25519P)
+	return reverseBytes(padBytes(u, curve25519.PointSize)), nil
+}
+
+// reverseBytes returns a reversed copy of b, used to convert between big and little endian.
+func reverseBytes(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+	return r
+}
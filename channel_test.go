@@ -0,0 +1,310 @@
+package tcecdsa_test
+
+import (
+	"bytes"
+	"github.com/niclabs/tcecdsa"
+	"testing"
+)
+
+// memoryNetwork delivers sealed messages between participants in the same process. Its tamper function
+// is called with every sent message, and it returns the messages that are delivered instead.
+type memoryNetwork struct {
+	inboxes map[uint8][]*tcecdsa.SealedMessage
+	tamper  func(msg *tcecdsa.SealedMessage) []*tcecdsa.SealedMessage
+}
+
+func newMemoryNetwork() *memoryNetwork {
+	return &memoryNetwork{
+		inboxes: make(map[uint8][]*tcecdsa.SealedMessage),
+		tamper: func(msg *tcecdsa.SealedMessage) []*tcecdsa.SealedMessage {
+			return []*tcecdsa.SealedMessage{msg}
+		},
+	}
+}
+
+func (n *memoryNetwork) send(msg *tcecdsa.SealedMessage) {
+	for _, delivered := range n.tamper(msg) {
+		n.inboxes[delivered.Receiver] = append(n.inboxes[delivered.Receiver], delivered)
+	}
+}
+
+func (n *memoryNetwork) receive(index uint8) (msg *tcecdsa.SealedMessage) {
+	msg, n.inboxes[index] = n.inboxes[index][0], n.inboxes[index][1:]
+	return
+}
+
+// newRoster returns three identities and their roster.
+func newRoster(t *testing.T) (identities []*tcecdsa.Identity, roster tcecdsa.Roster) {
+	roster = make(tcecdsa.Roster)
+	for i := uint8(0); i < 3; i++ {
+		id, err := tcecdsa.NewIdentity(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		identities = append(identities, id)
+		roster[i] = id.PublicKey()
+	}
+	return
+}
+
+// newChannels returns the channels between participants 0 and 1 of a roster with three participants.
+func newChannels(t *testing.T, channelID string) (a, b *tcecdsa.Channel, roster tcecdsa.Roster) {
+	identities, roster := newRoster(t)
+	a, b = connect(t, identities[0], identities[1], roster, channelID, channelID)
+	return
+}
+
+// connect returns the channels between two identities, after exchanging their handshakes.
+func connect(t *testing.T, idA, idB *tcecdsa.Identity, roster tcecdsa.Roster, channelA, channelB string) (a, b *tcecdsa.Channel) {
+	a, helloA, err := tcecdsa.NewChannel(idA, idB.Index, roster, channelA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, helloB, err := tcecdsa.NewChannel(idB, idA.Index, roster, channelB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Accept(helloB); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Accept(helloA); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestChannel(t *testing.T) {
+	secret := []byte("private key share")
+
+	t.Run("Delivery", func(t *testing.T) {
+		a, b, _ := newChannels(t, "reshare")
+		network := newMemoryNetwork()
+		for i := 0; i < 3; i++ {
+			msg, err := a.Seal(secret)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if bytes.Contains(msg.Ciphertext, secret) {
+				t.Error("ciphertext contains the plaintext")
+				return
+			}
+			network.send(msg)
+			plaintext, err := b.Open(network.receive(1))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if !bytes.Equal(plaintext, secret) {
+				t.Errorf("received %x, but %x was sent", plaintext, secret)
+				return
+			}
+			reply, err := b.Seal(plaintext)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			network.send(reply)
+			if _, err := a.Open(network.receive(0)); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	})
+
+	t.Run("Tampered", func(t *testing.T) {
+		a, b, _ := newChannels(t, "reshare")
+		network := newMemoryNetwork()
+		network.tamper = func(msg *tcecdsa.SealedMessage) []*tcecdsa.SealedMessage {
+			tampered := *msg
+			tampered.Ciphertext = append([]byte{}, msg.Ciphertext...)
+			tampered.Ciphertext[0] ^= 1
+			return []*tcecdsa.SealedMessage{&tampered}
+		}
+		msg, err := a.Seal(secret)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		network.send(msg)
+		if _, err := b.Open(network.receive(1)); err == nil {
+			t.Error("tampered message should not be opened")
+			return
+		}
+	})
+
+	t.Run("Replayed", func(t *testing.T) {
+		a, b, _ := newChannels(t, "reshare")
+		network := newMemoryNetwork()
+		network.tamper = func(msg *tcecdsa.SealedMessage) []*tcecdsa.SealedMessage {
+			return []*tcecdsa.SealedMessage{msg, msg}
+		}
+		msg, err := a.Seal(secret)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		network.send(msg)
+		if _, err := b.Open(network.receive(1)); err != nil {
+			t.Error(err)
+			return
+		}
+		if _, err := b.Open(network.receive(1)); err == nil {
+			t.Error("replayed message should not be opened")
+			return
+		}
+	})
+
+	t.Run("ChangedSequence", func(t *testing.T) {
+		a, b, _ := newChannels(t, "reshare")
+		network := newMemoryNetwork()
+		network.tamper = func(msg *tcecdsa.SealedMessage) []*tcecdsa.SealedMessage {
+			tampered := *msg
+			tampered.Seq += 10
+			return []*tcecdsa.SealedMessage{&tampered}
+		}
+		msg, err := a.Seal(secret)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		network.send(msg)
+		if _, err := b.Open(network.receive(1)); err == nil {
+			t.Error("message with a changed sequence number should not be opened")
+			return
+		}
+	})
+
+	t.Run("Reordered", func(t *testing.T) {
+		a, b, _ := newChannels(t, "reshare")
+		network := newMemoryNetwork()
+		first, err := a.Seal(secret)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		second, err := a.Seal(secret)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		network.send(second)
+		network.send(first)
+		if _, err := b.Open(network.receive(1)); err != nil {
+			t.Error(err)
+			return
+		}
+		if _, err := b.Open(network.receive(1)); err == nil {
+			t.Error("message older than the last received one should not be opened")
+			return
+		}
+	})
+
+	t.Run("Reflected", func(t *testing.T) {
+		a, _, _ := newChannels(t, "reshare")
+		msg, err := a.Seal(secret)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		msg.Sender, msg.Receiver = msg.Receiver, msg.Sender
+		if _, err := a.Open(msg); err == nil {
+			t.Error("message reflected to its sender should not be opened")
+			return
+		}
+	})
+
+	t.Run("OtherChannelID", func(t *testing.T) {
+		identities, roster := newRoster(t)
+		a, b := connect(t, identities[0], identities[1], roster, "reshare", "other")
+		msg, err := a.Seal(secret)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if _, err := b.Open(msg); err == nil {
+			t.Error("message from another channel should not be opened")
+			return
+		}
+	})
+
+	t.Run("Impersonated", func(t *testing.T) {
+		identities, roster := newRoster(t)
+		impostor, err := tcecdsa.NewIdentity(0)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		ch, b := connect(t, impostor, identities[1], roster, "reshare", "reshare")
+		msg, err := ch.Seal(secret)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if _, err := b.Open(msg); err == nil {
+			t.Error("message from an impostor should not be opened")
+			return
+		}
+	})
+
+	t.Run("Restarted", func(t *testing.T) {
+		// A channel ID reused after a restart gets new keys, so sequence numbers starting from zero again
+		// do not repeat nonces.
+		identities, roster := newRoster(t)
+		a, b := connect(t, identities[0], identities[1], roster, "reshare", "reshare")
+		first, err := a.Seal(secret)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		a, b = connect(t, identities[0], identities[1], roster, "reshare", "reshare")
+		second, err := a.Seal(secret)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if first.Seq != second.Seq || bytes.Equal(first.Ciphertext, second.Ciphertext) {
+			t.Error("channels with the same ID should encrypt with different keys")
+			return
+		}
+		if _, err := b.Open(first); err == nil {
+			t.Error("message from the previous channel should not be opened")
+			return
+		}
+	})
+
+	t.Run("Handshake", func(t *testing.T) {
+		identities, roster := newRoster(t)
+		a, helloA, err := tcecdsa.NewChannel(identities[0], 1, roster, "reshare")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if _, err := a.Seal(secret); err == nil {
+			t.Error("channel should not be used before the handshake")
+			return
+		}
+		if err := a.Accept(helloA); err == nil {
+			t.Error("channel should not accept its own handshake")
+			return
+		}
+		_, helloB, err := tcecdsa.NewChannel(identities[1], 0, roster, "reshare")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		lowOrder := &tcecdsa.ChannelHandshake{Sender: 1, Receiver: 0, Ephemeral: make([]byte, 32)}
+		if err := a.Accept(lowOrder); err == nil {
+			t.Error("handshake with a low order key should be rejected")
+			return
+		}
+		if err := a.Accept(helloB); err != nil {
+			t.Error(err)
+			return
+		}
+		if err := a.Accept(helloB); err == nil {
+			t.Error("handshake should be accepted only once")
+			return
+		}
+	})
+}
@@ -0,0 +1,89 @@
+// Command tcecdsa-audit verifies the audit logs written by tcecdsa-node. For each log, it checks the hash chain
+// of its records and every recorded signature against the recorded digest and public key, and prints the
+// number of records and the hash of the last one, which can be compared with a head exported before.
+//
+// Usage:
+//
+//	tcecdsa-audit [-v] log...
+//
+// With -v, every record is printed. The command exits with status 1 if a log is not valid.
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"github.com/niclabs/tcecdsa/audit"
+	"os"
+	"strings"
+)
+
+func main() {
+	verbose := flag.Bool("v", false, "print every record")
+	flag.Parse()
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: tcecdsa-audit [-v] log...")
+		os.Exit(2)
+	}
+	failed := false
+	for _, path := range flag.Args() {
+		if err := verify(path, *verbose); err != nil {
+			fmt.Printf("%s: FAILED: %s\n", path, err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// verify verifies the log on the path and prints its head.
+func verify(path string, verbose bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	records, err := audit.Verify(f)
+	if err != nil {
+		return err
+	}
+	var head []byte
+	for _, rec := range records {
+		if verbose {
+			fmt.Println(describe(rec))
+		}
+		head = rec.Hash
+	}
+	fmt.Printf("%s: OK: %d records, head %x\n", path, len(records), head)
+	return nil
+}
+
+// describe returns a line with the main fields of a record.
+func describe(rec *audit.Record) string {
+	fields := []string{
+		fmt.Sprintf("%d", rec.Seq),
+		rec.Time.Format("2006-01-02T15:04:05.000Z07:00"),
+		"node=" + rec.Node,
+		rec.Type,
+	}
+	if rec.KeyID != "" {
+		fields = append(fields, "key="+rec.KeyID)
+	}
+	if rec.SessionID != "" {
+		fields = append(fields, "session="+rec.SessionID)
+	}
+	if len(rec.Digest) > 0 {
+		fields = append(fields, "digest="+hex.EncodeToString(rec.Digest))
+	}
+	if rec.Round != "" {
+		fields = append(fields, fmt.Sprintf("round=%s participants=%v", rec.Round, rec.Participants))
+	}
+	if rec.R != nil && rec.S != nil {
+		fields = append(fields, fmt.Sprintf("r=%x s=%x", rec.R, rec.S))
+	}
+	if rec.Reason != "" {
+		fields = append(fields, fmt.Sprintf("reason=%q blamed=%v", rec.Reason, rec.Blamed))
+	}
+	return strings.Join(fields, " ")
+}
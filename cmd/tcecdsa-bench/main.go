@@ -0,0 +1,255 @@
+// Command tcecdsa-bench runs the whole protocol in one process, simulating every participant, and prints the
+// latency of each participant on each phase and the size of the messages it sends, in JSON and in the compact
+// encoding.
+//
+// Usage:
+//
+//	tcecdsa-bench [-curve P-256] [-l 5] [-k 3] [-n 10] [-json]
+//
+// The key is generated once, and the signing rounds are run -n times by the first K participants. Latencies
+// are the mean, minimum and maximum time spent by one participant on a phase, and the message size is the
+// mean size of the message sent by one participant in each encoding, so a round broadcasts about K times that size.
+//
+// For P-224, P-256 and secp256k1, the Paillier key is built from fixed public primes, so the generated keys
+// are not secure and must be used only for measurements. With -generate, or on other curves, the Paillier key
+// is generated from new primes, which can take several minutes.
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/niclabs/tcecdsa"
+	"github.com/niclabs/tcecdsa/internal/benchparams"
+	"log"
+	"math/big"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// phase represents the measurements of a phase of the protocol.
+type phase struct {
+	Name         string        `json:"name"`
+	Runs         int           `json:"runs"`
+	Mean         time.Duration `json:"mean_ns"`
+	Min          time.Duration `json:"min_ns"`
+	Max          time.Duration `json:"max_ns"`
+	JSONBytes    int           `json:"json_bytes"`    // Mean size of one JSON encoded message, zero if the phase sends none
+	CompactBytes int           `json:"compact_bytes"` // Mean size of one compact encoded message
+	total        time.Duration
+	size         tcecdsa.MessageSize
+}
+
+// report represents the configuration and the measurements of a run.
+type report struct {
+	Curve      string   `json:"curve"`
+	L          uint8    `json:"l"`
+	K          uint8    `json:"k"`
+	Iterations int      `json:"iterations"`
+	Phases     []*phase `json:"phases"`
+}
+
+func main() {
+	curve := flag.String("curve", "P-256", "curve of the key")
+	l := flag.Uint("l", 5, "number of participants")
+	k := flag.Uint("k", 3, "number of participants needed to sign")
+	n := flag.Int("n", 10, "number of signatures")
+	generate := flag.Bool("generate", false, "generate the Paillier key instead of using fixed parameters")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	flag.Parse()
+	if *l > 255 || *k == 0 || *k > *l || *n < 1 {
+		fmt.Fprintln(os.Stderr, "usage: tcecdsa-bench [-curve P-256] [-l 5] [-k 3] [-n 10] [-generate] [-json]")
+		os.Exit(2)
+	}
+	rep, err := run(*curve, uint8(*l), uint8(*k), *n, *generate)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(rep); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	rep.print()
+}
+
+// run generates a key and signs n messages with it, measuring each phase.
+func run(curveName string, l, k uint8, n int, generate bool) (rep *report, err error) {
+	curve, ok := tcecdsa.CurveNameToCurve[curveName]
+	if !ok {
+		err = fmt.Errorf("unsupported curve %s", curveName)
+		return
+	}
+	params := &tcecdsa.NewKeyParams{}
+	if !generate {
+		params.PaillierFixed = benchparams.Paillier(curve.Params().BitSize)
+	}
+	if params.PaillierFixed == nil {
+		log.Printf("generating Paillier key for %s, this can take several minutes", curveName)
+	}
+	rep = &report{Curve: curveName, L: l, K: k, Iterations: n}
+	newPhase := func(name string) *phase {
+		p := &phase{Name: name}
+		rep.Phases = append(rep.Phases, p)
+		return p
+	}
+	keyGen := newPhase("key_gen")
+	keyInit := newPhase("key_init")
+	keySet := newPhase("key_set")
+	round1 := newPhase("round1")
+	round2 := newPhase("round2")
+	round3 := newPhase("round3")
+	signature := newPhase("signature")
+
+	var shares []*tcecdsa.KeyShare
+	var meta *tcecdsa.KeyMeta
+	if err = keyGen.measure(nil, func() (msg tcecdsa.CompactMessage, err error) {
+		shares, meta, err = tcecdsa.NewKey(l, k, curveName, params)
+		return
+	}); err != nil {
+		return
+	}
+	initMsgs := make(tcecdsa.KeyInitMessageList, 0, l)
+	for _, share := range shares {
+		if err = keyInit.measure(meta, func() (msg tcecdsa.CompactMessage, err error) {
+			m, err := share.Init(meta)
+			initMsgs = append(initMsgs, m)
+			return m, err
+		}); err != nil {
+			return
+		}
+	}
+	for _, share := range shares {
+		if err = keySet.measure(meta, func() (msg tcecdsa.CompactMessage, err error) {
+			return nil, share.SetKey(meta, initMsgs)
+		}); err != nil {
+			return
+		}
+	}
+	pk, err := meta.GetPublicKey(initMsgs)
+	if err != nil {
+		return
+	}
+
+	for i := 0; i < n; i++ {
+		h := sha256.Sum256([]byte(fmt.Sprintf("tcecdsa-bench message %d", i)))
+		sessions := make([]*tcecdsa.SigSession, 0, k)
+		for _, share := range shares[:k] {
+			var session *tcecdsa.SigSession
+			if session, err = share.NewSigSession(meta, h[:]); err != nil {
+				return
+			}
+			sessions = append(sessions, session)
+		}
+		msgs1 := make(tcecdsa.Round1MessageList, 0, k)
+		for _, session := range sessions {
+			if err = round1.measure(meta, func() (msg tcecdsa.CompactMessage, err error) {
+				m, err := session.Round1()
+				msgs1 = append(msgs1, m)
+				return m, err
+			}); err != nil {
+				return
+			}
+		}
+		msgs2 := make(tcecdsa.Round2MessageList, 0, k)
+		for _, session := range sessions {
+			if err = round2.measure(meta, func() (msg tcecdsa.CompactMessage, err error) {
+				m, err := session.Round2(msgs1)
+				msgs2 = append(msgs2, m)
+				return m, err
+			}); err != nil {
+				return
+			}
+		}
+		msgs3 := make(tcecdsa.Round3MessageList, 0, k)
+		for _, session := range sessions {
+			if err = round3.measure(meta, func() (msg tcecdsa.CompactMessage, err error) {
+				m, err := session.Round3(msgs2)
+				msgs3 = append(msgs3, m)
+				return m, err
+			}); err != nil {
+				return
+			}
+		}
+		for _, session := range sessions {
+			var r, s *big.Int
+			if err = signature.measure(meta, func() (msg tcecdsa.CompactMessage, err error) {
+				r, s, err = session.GetSignature(msgs3)
+				return
+			}); err != nil {
+				return
+			}
+			if !ecdsa.Verify(pk, h[:], r, s) {
+				err = fmt.Errorf("invalid signature on iteration %d", i)
+				return
+			}
+		}
+	}
+	for _, p := range rep.Phases {
+		if p.Runs > 0 {
+			p.Mean = p.total / time.Duration(p.Runs)
+			p.JSONBytes = p.size.JSON / p.Runs
+			p.CompactBytes = p.size.Compact / p.Runs
+		}
+	}
+	return
+}
+
+// measure runs fn, adding its duration and the sizes of the message it returns to the phase.
+func (p *phase) measure(meta *tcecdsa.KeyMeta, fn func() (msg tcecdsa.CompactMessage, err error)) (err error) {
+	start := time.Now()
+	msg, err := fn()
+	elapsed := time.Since(start)
+	if err != nil {
+		err = fmt.Errorf("%s: %s", p.Name, err)
+		return
+	}
+	if p.Runs == 0 || elapsed < p.Min {
+		p.Min = elapsed
+	}
+	if elapsed > p.Max {
+		p.Max = elapsed
+	}
+	p.Runs++
+	p.total += elapsed
+	if msg != nil {
+		size, err := tcecdsa.SizeOf(meta, msg)
+		if err != nil {
+			return err
+		}
+		p.size.JSON += size.JSON
+		p.size.Compact += size.Compact
+	}
+	return
+}
+
+// print writes the report as a table on the standard output.
+func (rep *report) print() {
+	fmt.Printf("curve %s, L=%d, K=%d, %d signatures\n\n", rep.Curve, rep.L, rep.K, rep.Iterations)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "phase\truns\tmean\tmin\tmax\tjson bytes\tcompact bytes\t")
+	for _, p := range rep.Phases {
+		jsonSize, compactSize := "-", "-"
+		if p.JSONBytes > 0 {
+			jsonSize, compactSize = fmt.Sprintf("%d", p.JSONBytes), fmt.Sprintf("%d", p.CompactBytes)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t\n", p.Name, p.Runs, round(p.Mean), round(p.Min), round(p.Max), jsonSize, compactSize)
+	}
+	w.Flush()
+}
+
+// round rounds a duration to three significant digits.
+func round(d time.Duration) time.Duration {
+	for unit := time.Duration(1); unit < time.Hour; unit *= 10 {
+		if d < 1000*unit {
+			return d.Round(unit)
+		}
+	}
+	return d
+}
@@ -0,0 +1,219 @@
+// Command tcecdsa-node is a reference node of the threshold ECDSA protocol. It holds the key shares of
+// one participant and talks with the other nodes over HTTP, using JSON encoded messages.
+//
+// The public API has the following endpoints:
+//
+//	GET  /keys             lists the keys held by the node.
+//	POST /keys             generates a new key, using this node as dealer.
+//	POST /keys/{id}/sign   starts a signing session, using this node as coordinator.
+//	GET  /sessions/{id}    returns the status of a signing session, and its signature when it is finished.
+//	GET  /metrics          returns the protocol metrics in the Prometheus text format, if -metrics is set.
+//
+// Requests to the public API must present a bearer token listed on the file set with -api-tokens, which has one
+// "<requester> <token>" line per client. The node refuses to start without it. With -allow-requesters and
+// -rate-limit, every node evaluates a signing policy before taking part in a session, using the requester
+// authenticated by the coordinator.
+//
+// The endpoints under /internal are used between nodes, and they should not be exposed to users. The dealer
+// sends the key shares over them, so they must be authenticated and encrypted: with -tls-cert, -tls-key and
+// -tls-ca, the node serves HTTPS and accepts internal requests only from peers presenting a certificate signed
+// by the CA, and it presents its own certificate to the other nodes. Without TLS, the node refuses to listen
+// on an address that is not a loopback one, unless -insecure-internal is set.
+// Keys are stored encrypted on the directory set with the -keystore flag, using the passphrase defined
+// on the TCECDSA_PASSPHRASE environment variable. If the flag is not set, keys are kept only in memory.
+//
+// If the -audit flag is set, the node appends a hash-chained record of every key generation, signing session,
+// accepted round, signature and abort to the file, which can be verified with tcecdsa-audit.
+//
+// Key generation uses a dealer, so the node receiving the request knows all the key shares while
+// creating them, as NewKey does.
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"github.com/niclabs/tcecdsa"
+	"github.com/niclabs/tcecdsa/audit"
+	"github.com/niclabs/tcecdsa/keystore"
+	"github.com/niclabs/tcecdsa/metrics"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+func main() {
+	index := flag.Uint("index", 0, "index of this node in the peer list")
+	listen := flag.String("listen", ":8080", "address to listen on")
+	peers := flag.String("peers", "", "comma separated base URLs of all the nodes, sorted by index, including this node")
+	keystoreDir := flag.String("keystore", "", "directory where the keys are stored, encrypted with the TCECDSA_PASSPHRASE environment variable")
+	auditPath := flag.String("audit", "", "file where the audit log is appended")
+	serveMetrics := flag.Bool("metrics", false, "serve the protocol metrics on /metrics")
+	tlsCert := flag.String("tls-cert", "", "PEM certificate of this node, used as server and as client of the other nodes")
+	tlsKey := flag.String("tls-key", "", "PEM private key of the certificate of this node")
+	tlsCA := flag.String("tls-ca", "", "PEM certificates of the CA that signs the certificates of the nodes")
+	apiTokens := flag.String("api-tokens", "", "file with one \"<requester> <token>\" line per client allowed to use the public API")
+	allowRequesters := flag.String("allow-requesters", "", "comma separated requesters allowed to sign, if set")
+	rateLimit := flag.Int("rate-limit", 0, "maximum signing sessions per key on each -rate-window, if set")
+	rateWindow := flag.Duration("rate-window", time.Hour, "time window of -rate-limit")
+	insecureInternal := flag.Bool("insecure-internal", false, "serve /internal without TLS on a non-loopback address")
+	readTimeout := flag.Duration("read-timeout", 30*time.Second, "maximum time to read a request")
+	writeTimeout := flag.Duration("write-timeout", 10*time.Minute, "maximum time to answer a request, including key generation")
+	flag.Parse()
+	if *peers == "" {
+		log.Fatal("peers must be set")
+	}
+	if *apiTokens == "" {
+		log.Fatal("api-tokens must be set")
+	}
+	var store keystore.ShareStore = keystore.NewMemoryStore()
+	if *keystoreDir != "" {
+		fileStore, err := keystore.NewFileStore(*keystoreDir, []byte(os.Getenv("TCECDSA_PASSPHRASE")))
+		if err != nil {
+			log.Fatal(err)
+		}
+		store = fileStore
+	}
+	node, err := NewNode(uint8(*index), strings.Split(*peers, ","), store)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if node.APITokens, err = readAPITokens(*apiTokens); err != nil {
+		log.Fatal(err)
+	}
+	var policies []tcecdsa.Policy
+	if *allowRequesters != "" {
+		policies = append(policies, tcecdsa.AllowRequesters(strings.Split(*allowRequesters, ",")...))
+	}
+	if *rateLimit > 0 {
+		policies = append(policies, tcecdsa.NewRateLimit(*rateLimit, *rateWindow))
+	}
+	if len(policies) > 0 {
+		node.Policy = tcecdsa.AllOf(policies...)
+	}
+	if *auditPath != "" {
+		auditLog, err := audit.Open(*auditPath, fmt.Sprintf("%d", node.Index))
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer auditLog.Close()
+		node.Audit = auditLog
+	}
+	if *serveMetrics {
+		exporter := metrics.NewExporter()
+		tcecdsa.SetInstrumentation(exporter)
+		node.Metrics = exporter
+	}
+	server := &http.Server{
+		Addr:              *listen,
+		Handler:           node,
+		ReadHeaderTimeout: *readTimeout,
+		ReadTimeout:       *readTimeout,
+		WriteTimeout:      *writeTimeout,
+		IdleTimeout:       2 * *readTimeout,
+	}
+	node.Client = &http.Client{Timeout: *writeTimeout}
+	if *tlsCert == "" && *tlsKey == "" && *tlsCA == "" {
+		if !*insecureInternal && !isLoopback(*listen) {
+			log.Fatalf("refusing to serve /internal without TLS on %s, set -tls-cert, -tls-key and -tls-ca, or -insecure-internal", *listen)
+		}
+		log.Printf("node %d listening on %s", node.Index, *listen)
+		log.Fatal(server.ListenAndServe())
+	}
+	serverConfig, clientConfig, err := tlsConfigs(*tlsCert, *tlsKey, *tlsCA)
+	if err != nil {
+		log.Fatal(err)
+	}
+	server.TLSConfig = serverConfig
+	node.Client.Transport = &http.Transport{TLSClientConfig: clientConfig}
+	node.RequirePeerCert = true
+	log.Printf("node %d listening on %s with TLS", node.Index, *listen)
+	log.Fatal(server.ListenAndServeTLS("", ""))
+}
+
+// tlsConfigs returns the TLS configuration of the server, which verifies the client certificates signed by the
+// CA, and the one of the client used to talk with the other nodes, which presents the node certificate.
+func tlsConfigs(certFile, keyFile, caFile string) (server, client *tls.Config, err error) {
+	if certFile == "" || keyFile == "" || caFile == "" {
+		err = fmt.Errorf("tls-cert, tls-key and tls-ca must be set together")
+		return
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return
+	}
+	caPEM, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		err = fmt.Errorf("no certificates found on %s", caFile)
+		return
+	}
+	// Client certificates are optional for the public API, and required by the node for /internal.
+	server = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+		MinVersion:   tls.VersionTLS12,
+	}
+	client = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		MinVersion:   tls.VersionTLS12,
+	}
+	return
+}
+
+// readAPITokens reads a file with one "<requester> <token>" line per client, and returns the tokens mapped
+// to their requesters. Empty lines and lines starting with # are ignored.
+func readAPITokens(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	tokens := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected \"<requester> <token>\"", path, line)
+		}
+		if _, ok := tokens[fields[1]]; ok {
+			return nil, fmt.Errorf("%s:%d: token is repeated", path, line)
+		}
+		tokens[fields[1]] = fields[0]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("no API tokens found on %s", path)
+	}
+	return tokens, nil
+}
+
+// isLoopback returns true if the listen address is bound to a loopback interface.
+func isLoopback(listen string) bool {
+	host, _, err := net.SplitHostPort(listen)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
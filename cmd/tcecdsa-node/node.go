@@ -0,0 +1,773 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/niclabs/tcecdsa"
+	"github.com/niclabs/tcecdsa/audit"
+	"github.com/niclabs/tcecdsa/keystore"
+	"github.com/niclabs/tcpaillier"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// The following values are the status of a signing session.
+const (
+	StatusStarted  = "started"
+	StatusRound1   = "round1"
+	StatusRound2   = "round2"
+	StatusRound3   = "round3"
+	StatusFinished = "finished"
+	StatusFailed   = "failed"
+)
+
+// maxBodySize is the maximum size in bytes of the body of a request. It is large enough for the messages of
+// the protocol with big Paillier keys and many nodes, but bounds the memory used by a single request.
+const maxBodySize = 16 << 20
+
+// Node is a participant of the threshold protocol. It holds its key shares and talks with the
+// other nodes over HTTP, using JSON encoded messages.
+type Node struct {
+	Index           uint8               // Index of this node, equal to the index of its key shares
+	Peers           []string            // Base URLs of all the nodes, sorted by index (including this node)
+	Client          *http.Client        // Client used to talk with the other nodes
+	Audit           *audit.Log          // Optional log where the key and signing events are recorded
+	Metrics         http.Handler        // Optional handler served on GET /metrics
+	RequirePeerCert bool                // If true, requests to /internal must present a client certificate verified by the server
+	APITokens       map[string]string   // Bearer tokens accepted on the public API, mapped to the requester using each one
+	Policy          tcecdsa.Policy      // Optional policy that must approve the signing sessions in which the node participates
+	store           keystore.ShareStore // Store where the ready keys are saved
+	mutex           sync.Mutex          // Mutex protecting keys and sessions
+	keys            map[string]*Key     // Keys held by this node
+	sessions        map[string]*Session
+}
+
+// Key represents a key share held by the node.
+type Key struct {
+	ID    string
+	Share *tcecdsa.KeyShare
+	Meta  *tcecdsa.KeyMeta
+	Ready bool // True when the key initialization has finished
+}
+
+// Session represents a signing session in which the node participates.
+type Session struct {
+	ID     string
+	KeyID  string
+	Status string
+	Error  string
+	R, S   string // Signature values, as hex strings
+	DER    string // DER encoded signature, as hex string
+	state  *tcecdsa.SigSession
+	rounds sync.Mutex // Mutex serializing the rounds run on state
+}
+
+// KeyGenRequest is the body of a key generation request.
+type KeyGenRequest struct {
+	ID            string                  `json:"id"`
+	Curve         string                  `json:"curve"`
+	Threshold     uint8                   `json:"threshold"`
+	PaillierFixed *tcpaillier.FixedParams `json:"paillier_fixed,omitempty"` // Only for testing, because generating Paillier keys is slow.
+}
+
+// KeyInfo is the public information of a key.
+type KeyInfo struct {
+	ID        string `json:"id"`
+	Curve     string `json:"curve"`
+	Ready     bool   `json:"ready"`
+	PublicKey string `json:"public_key,omitempty"` // Uncompressed SEC1 encoding, as hex string
+}
+
+// SignRequest is the body of a signature request.
+type SignRequest struct {
+	Hash string `json:"hash"` // Hash to sign, as hex string
+}
+
+// SessionInfo is the public information of a signing session.
+type SessionInfo struct {
+	ID        string `json:"id"`
+	KeyID     string `json:"key_id"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+	R         string `json:"r,omitempty"`
+	S         string `json:"s,omitempty"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// shareMessage is the body used by the dealer to send a key share to a node.
+type shareMessage struct {
+	ID    string
+	Share *tcecdsa.KeyShare
+	Meta  *tcecdsa.KeyMeta
+}
+
+// startMessage is the body used by the coordinator to start a signing session on a node.
+type startMessage struct {
+	ID        string
+	KeyID     string
+	Hash      []byte
+	Requester string // Requester authenticated by the coordinator
+}
+
+// NewNode returns a new node with the given index and peers. The keys are saved on the store when they
+// are ready, and the keys already saved on it are loaded.
+func NewNode(index uint8, peers []string, store keystore.ShareStore) (*Node, error) {
+	if int(index) >= len(peers) {
+		return nil, fmt.Errorf("node index %d is out of the range of %d peers", index, len(peers))
+	}
+	node := &Node{
+		Index:    index,
+		Peers:    peers,
+		Client:   http.DefaultClient,
+		store:    store,
+		keys:     make(map[string]*Key),
+		sessions: make(map[string]*Session),
+	}
+	infos, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range infos {
+		share, meta, err := store.Get(info.ID)
+		if err != nil {
+			return nil, err
+		}
+		if share.Index != index {
+			return nil, fmt.Errorf("key %s has index %d, but node index is %d", info.ID, share.Index, index)
+		}
+		node.keys[info.ID] = &Key{ID: info.ID, Share: share, Meta: meta, Ready: true}
+	}
+	return node, nil
+}
+
+// ServeHTTP routes the requests of the public and internal APIs. Requests to the public API must present
+// one of the API tokens of the node as a bearer token.
+func (n *Node) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if parts[0] == "internal" {
+		if r.Method != http.MethodPost || len(parts) < 2 {
+			writeError(w, http.StatusNotFound, fmt.Errorf("not found"))
+			return
+		}
+		n.handleInternal(w, r, parts[1:])
+		return
+	}
+	requester, ok := n.authenticate(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, fmt.Errorf("a valid API token is required"))
+		return
+	}
+	switch {
+	case len(parts) == 1 && parts[0] == "keys" && r.Method == http.MethodGet:
+		n.handleListKeys(w, r)
+	case len(parts) == 1 && parts[0] == "keys" && r.Method == http.MethodPost:
+		n.handleGenerateKey(w, r)
+	case len(parts) == 3 && parts[0] == "keys" && parts[2] == "sign" && r.Method == http.MethodPost:
+		n.handleSign(w, r, parts[1], requester)
+	case len(parts) == 2 && parts[0] == "sessions" && r.Method == http.MethodGet:
+		n.handleGetSession(w, r, parts[1])
+	case len(parts) == 1 && parts[0] == "metrics" && r.Method == http.MethodGet && n.Metrics != nil:
+		n.Metrics.ServeHTTP(w, r)
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("not found"))
+	}
+}
+
+// authenticate returns the requester using the bearer token of the request, and false if the token is
+// not one of the API tokens of the node.
+func (n *Node) authenticate(r *http.Request) (requester string, ok bool) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return
+	}
+	token := []byte(strings.TrimPrefix(auth, "Bearer "))
+	for t, req := range n.APITokens {
+		if subtle.ConstantTimeCompare([]byte(t), token) == 1 {
+			requester, ok = req, true
+		}
+	}
+	return
+}
+
+// handleInternal routes the requests sent between nodes.
+func (n *Node) handleInternal(w http.ResponseWriter, r *http.Request, parts []string) {
+	if n.RequirePeerCert && (r.TLS == nil || len(r.TLS.VerifiedChains) == 0) {
+		writeError(w, http.StatusForbidden, fmt.Errorf("a verified peer certificate is required"))
+		return
+	}
+	var resp interface{}
+	var err error
+	switch {
+	case len(parts) == 1 && parts[0] == "keys":
+		var msg shareMessage
+		if err = readJSON(w, r, &msg); err == nil {
+			err = n.storeShare(&msg)
+		}
+	case len(parts) == 3 && parts[0] == "keys" && parts[2] == "init":
+		resp, err = n.initKey(parts[1])
+	case len(parts) == 3 && parts[0] == "keys" && parts[2] == "set":
+		var msgs tcecdsa.KeyInitMessageList
+		if err = readJSON(w, r, &msgs); err == nil {
+			err = n.setKey(parts[1], msgs)
+		}
+	case len(parts) == 1 && parts[0] == "sessions":
+		var msg startMessage
+		if err = readJSON(w, r, &msg); err == nil {
+			err = n.startSession(&msg)
+		}
+	case len(parts) == 3 && parts[0] == "sessions":
+		resp, err = n.sessionRound(w, r, parts[1], parts[2])
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("not found"))
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleListKeys returns the public information of the keys held by the node.
+func (n *Node) handleListKeys(w http.ResponseWriter, _ *http.Request) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	infos := make([]*KeyInfo, 0, len(n.keys))
+	for _, key := range n.keys {
+		info := &KeyInfo{
+			ID:    key.ID,
+			Curve: key.Meta.CurveName,
+			Ready: key.Ready,
+		}
+		if key.Ready {
+			sec1, err := key.Share.MarshalSEC1(key.Meta, false)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, err)
+				return
+			}
+			info.PublicKey = hex.EncodeToString(sec1)
+		}
+		infos = append(infos, info)
+	}
+	writeJSON(w, http.StatusOK, infos)
+}
+
+// handleGenerateKey generates a new key, acting as dealer: it creates the key shares, sends one to
+// each node and then runs the key initialization between them. It returns when the key is ready.
+func (n *Node) handleGenerateKey(w http.ResponseWriter, r *http.Request) {
+	var req KeyGenRequest
+	if err := readJSON(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.ID == "" {
+		id, err := randomID()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		req.ID = id
+	}
+	info, err := n.generateKey(&req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, info)
+}
+
+// generateKey creates and distributes the key shares, and then initializes the key on all nodes.
+func (n *Node) generateKey(req *KeyGenRequest) (info *KeyInfo, err error) {
+	var params *tcecdsa.NewKeyParams
+	if req.PaillierFixed != nil {
+		params = &tcecdsa.NewKeyParams{PaillierFixed: req.PaillierFixed}
+	}
+	shares, meta, err := tcecdsa.NewKey(uint8(len(n.Peers)), req.Threshold, req.Curve, params)
+	if err != nil {
+		return
+	}
+	err = n.broadcast(func(i int) (string, interface{}, interface{}) {
+		return "/internal/keys", &shareMessage{ID: req.ID, Share: shares[i], Meta: meta}, nil
+	})
+	if err != nil {
+		return
+	}
+	msgs := make(tcecdsa.KeyInitMessageList, len(n.Peers))
+	err = n.broadcast(func(i int) (string, interface{}, interface{}) {
+		msgs[i] = &tcecdsa.KeyInitMessage{}
+		return "/internal/keys/" + req.ID + "/init", nil, msgs[i]
+	})
+	if err != nil {
+		return
+	}
+	err = n.broadcast(func(i int) (string, interface{}, interface{}) {
+		return "/internal/keys/" + req.ID + "/set", msgs, nil
+	})
+	if err != nil {
+		return
+	}
+	pk, err := meta.GetPublicKey(msgs)
+	if err != nil {
+		return
+	}
+	info = &KeyInfo{
+		ID:        req.ID,
+		Curve:     req.Curve,
+		Ready:     true,
+		PublicKey: hex.EncodeToString(tcecdsa.NewPoint(pk.X, pk.Y).Bytes(meta.Curve())),
+	}
+	return
+}
+
+// handleSign starts a signing session for the requester, acting as its coordinator. The session runs in
+// background, and its status can be fetched using its ID.
+func (n *Node) handleSign(w http.ResponseWriter, r *http.Request, keyID, requester string) {
+	var req SignRequest
+	if err := readJSON(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	h, err := hex.DecodeString(req.Hash)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if _, err := n.getKey(keyID); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	id, err := randomID()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	msg := &startMessage{ID: id, KeyID: keyID, Hash: h, Requester: requester}
+	err = n.broadcast(func(i int) (string, interface{}, interface{}) {
+		return "/internal/sessions", msg, nil
+	})
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	go n.coordinate(id)
+	writeJSON(w, http.StatusAccepted, &SessionInfo{ID: id, KeyID: keyID, Status: StatusStarted})
+}
+
+// coordinate runs the rounds of a signing session on all the nodes.
+func (n *Node) coordinate(id string) {
+	path := "/internal/sessions/" + id
+	msgs1 := make(tcecdsa.Round1MessageList, len(n.Peers))
+	err := n.broadcast(func(i int) (string, interface{}, interface{}) {
+		msgs1[i] = &tcecdsa.Round1Message{}
+		return path + "/round1", nil, msgs1[i]
+	})
+	if err != nil {
+		n.failSession(id, err)
+		return
+	}
+	msgs2 := make(tcecdsa.Round2MessageList, len(n.Peers))
+	err = n.broadcast(func(i int) (string, interface{}, interface{}) {
+		msgs2[i] = &tcecdsa.Round2Message{}
+		return path + "/round2", msgs1, msgs2[i]
+	})
+	if err != nil {
+		n.failSession(id, err)
+		return
+	}
+	msgs3 := make(tcecdsa.Round3MessageList, len(n.Peers))
+	err = n.broadcast(func(i int) (string, interface{}, interface{}) {
+		msgs3[i] = &tcecdsa.Round3Message{}
+		return path + "/round3", msgs2, msgs3[i]
+	})
+	if err != nil {
+		n.failSession(id, err)
+		return
+	}
+	err = n.broadcast(func(i int) (string, interface{}, interface{}) {
+		return path + "/finish", msgs3, nil
+	})
+	if err != nil {
+		n.failSession(id, err)
+	}
+}
+
+// handleGetSession returns the status of a signing session.
+func (n *Node) handleGetSession(w http.ResponseWriter, _ *http.Request, id string) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	session, ok := n.sessions[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("session %s not found", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, &SessionInfo{
+		ID:        session.ID,
+		KeyID:     session.KeyID,
+		Status:    session.Status,
+		Error:     session.Error,
+		R:         session.R,
+		S:         session.S,
+		Signature: session.DER,
+	})
+}
+
+// storeShare stores a key share sent by the dealer.
+func (n *Node) storeShare(msg *shareMessage) error {
+	if msg.Share == nil || msg.Meta == nil {
+		return fmt.Errorf("key share and meta must be set")
+	}
+	if msg.Share.Index != n.Index {
+		return fmt.Errorf("key share index is %d, but node index is %d", msg.Share.Index, n.Index)
+	}
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if _, ok := n.keys[msg.ID]; ok {
+		return fmt.Errorf("key %s already exists", msg.ID)
+	}
+	n.keys[msg.ID] = &Key{ID: msg.ID, Share: msg.Share, Meta: msg.Meta}
+	return nil
+}
+
+// initKey returns the key initialization message of this node.
+func (n *Node) initKey(id string) (*tcecdsa.KeyInitMessage, error) {
+	key, err := n.getKey(id)
+	if err != nil {
+		return nil, err
+	}
+	return key.Share.Init(key.Meta)
+}
+
+// setKey sets the key of this node using the key initialization messages of all the nodes, and saves it on the store.
+func (n *Node) setKey(id string, msgs tcecdsa.KeyInitMessageList) error {
+	key, err := n.getKey(id)
+	if err != nil {
+		return err
+	}
+	if err := key.Share.SetKey(key.Meta, msgs); err != nil {
+		return err
+	}
+	if err := n.store.Put(id, key.Share, key.Meta); err != nil {
+		return err
+	}
+	pk, err := key.Share.MarshalSEC1(key.Meta, false)
+	if err != nil {
+		return err
+	}
+	err = n.audit(&audit.Record{
+		Type:      audit.KeyGenerated,
+		KeyID:     id,
+		Curve:     key.Meta.CurveName,
+		PublicKey: pk,
+	})
+	if err != nil {
+		return err
+	}
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	key.Ready = true
+	return nil
+}
+
+// startSession creates a new signing session on this node. If the node has a policy, the session
+// evaluates it before its first round.
+func (n *Node) startSession(msg *startMessage) error {
+	key, err := n.getKey(msg.KeyID)
+	if err != nil {
+		return err
+	}
+	if !key.Ready {
+		return fmt.Errorf("key %s is not ready", msg.KeyID)
+	}
+	state, err := key.Share.NewSigSession(key.Meta, msg.Hash)
+	if err != nil {
+		return err
+	}
+	if n.Policy != nil {
+		req := &tcecdsa.SignRequest{KeyID: msg.KeyID, Digest: msg.Hash, Requester: msg.Requester}
+		if err := state.SetPolicy(n.Policy, req); err != nil {
+			return err
+		}
+	}
+	n.mutex.Lock()
+	if _, ok := n.sessions[msg.ID]; ok {
+		n.mutex.Unlock()
+		return fmt.Errorf("session %s already exists", msg.ID)
+	}
+	n.sessions[msg.ID] = &Session{
+		ID:     msg.ID,
+		KeyID:  msg.KeyID,
+		Status: StatusStarted,
+		state:  state,
+	}
+	n.mutex.Unlock()
+	err = n.audit(&audit.Record{
+		Type:      audit.SessionCreated,
+		KeyID:     msg.KeyID,
+		SessionID: msg.ID,
+		Digest:    msg.Hash,
+	})
+	if err != nil {
+		n.mutex.Lock()
+		delete(n.sessions, msg.ID)
+		n.mutex.Unlock()
+	}
+	return err
+}
+
+// previousStatus maps each round of a signing session to the status the session must have to run it.
+var previousStatus = map[string]string{
+	"round1": StatusStarted,
+	"round2": StatusRound1,
+	"round3": StatusRound2,
+	"finish": StatusRound3,
+}
+
+// sessionRound runs a round of a signing session on this node, using the messages of the previous round.
+// The rounds of a session run one at a time and in order, and a round requested out of order is rejected
+// without changing the session.
+func (n *Node) sessionRound(w http.ResponseWriter, r *http.Request, id, round string) (resp interface{}, err error) {
+	previous, ok := previousStatus[round]
+	if !ok {
+		err = fmt.Errorf("unknown round %s", round)
+		return
+	}
+	n.mutex.Lock()
+	session, ok := n.sessions[id]
+	n.mutex.Unlock()
+	if !ok {
+		err = fmt.Errorf("session %s not found", id)
+		return
+	}
+	session.rounds.Lock()
+	defer session.rounds.Unlock()
+	n.mutex.Lock()
+	current := session.Status
+	n.mutex.Unlock()
+	if current != previous {
+		err = fmt.Errorf("session %s is %s, so it cannot run %s", id, current, round)
+		return
+	}
+	var status, accepted string
+	var participants []int
+	var sigR, sigS *big.Int
+	switch round {
+	case "round1":
+		status = StatusRound1
+		resp, err = session.state.Round1()
+	case "round2":
+		var msgs tcecdsa.Round1MessageList
+		if err = readJSON(w, r, &msgs); err != nil {
+			return
+		}
+		status, accepted = StatusRound2, "round1"
+		if resp, err = session.state.Round2(msgs); err == nil {
+			participants = session.state.Signers().Round1
+		}
+	case "round3":
+		var msgs tcecdsa.Round2MessageList
+		if err = readJSON(w, r, &msgs); err != nil {
+			return
+		}
+		status, accepted = StatusRound3, "round2"
+		if resp, err = session.state.Round3(msgs); err == nil {
+			participants = session.state.Signers().Round2
+		}
+	case "finish":
+		var msgs tcecdsa.Round3MessageList
+		if err = readJSON(w, r, &msgs); err != nil {
+			return
+		}
+		status, accepted = StatusFinished, "round3"
+		if sigR, sigS, err = n.finishSession(session, msgs); err == nil {
+			participants = session.state.Signers().Round3
+		}
+	}
+	if err == nil && accepted != "" {
+		err = n.audit(&audit.Record{
+			Type:         audit.RoundAccepted,
+			SessionID:    id,
+			Round:        accepted,
+			Participants: participants,
+		})
+	}
+	if err == nil && status == StatusFinished {
+		err = n.audit(&audit.Record{
+			Type:      audit.SignatureMade,
+			KeyID:     session.KeyID,
+			SessionID: id,
+			R:         sigR,
+			S:         sigS,
+		})
+	}
+	if err != nil {
+		n.failSession(id, err)
+		return
+	}
+	n.mutex.Lock()
+	session.Status = status
+	n.mutex.Unlock()
+	return
+}
+
+// finishSession obtains the signature of a session using the messages of the last round.
+func (n *Node) finishSession(session *Session, msgs tcecdsa.Round3MessageList) (r, s *big.Int, err error) {
+	r, s, err = session.state.GetSignature(msgs)
+	if err != nil {
+		return
+	}
+	der, err := tcecdsa.MarshalSignature(r, s)
+	if err != nil {
+		return
+	}
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	session.R = hex.EncodeToString(r.Bytes())
+	session.S = hex.EncodeToString(s.Bytes())
+	session.DER = hex.EncodeToString(der)
+	return
+}
+
+// failSession marks a session as failed, and records the abort with the node blamed for it, if it is known.
+func (n *Node) failSession(id string, err error) {
+	n.mutex.Lock()
+	session, ok := n.sessions[id]
+	if !ok || session.Status == StatusFailed {
+		n.mutex.Unlock()
+		return
+	}
+	session.Status = StatusFailed
+	session.Error = err.Error()
+	n.mutex.Unlock()
+	var blamed []int
+	var peerErr *peerError
+	if errors.As(err, &peerErr) {
+		blamed = []int{peerErr.index}
+	}
+	_ = n.audit(&audit.Record{
+		Type:      audit.SessionAborted,
+		SessionID: id,
+		Reason:    err.Error(),
+		Blamed:    blamed,
+	})
+}
+
+// audit appends a record to the audit log of the node, if it is set.
+func (n *Node) audit(rec *audit.Record) error {
+	if n.Audit == nil {
+		return nil
+	}
+	return n.Audit.Append(rec)
+}
+
+// getKey returns the key with the given ID.
+func (n *Node) getKey(id string) (*Key, error) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	key, ok := n.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("key %s not found", id)
+	}
+	return key, nil
+}
+
+// peerError is an error returned by a node to a request.
+type peerError struct {
+	index int
+	err   error
+}
+
+// Error returns the index of the node and its error.
+func (e *peerError) Error() string {
+	return fmt.Sprintf("node %d: %s", e.index, e.err)
+}
+
+// broadcast sends a request to every node at the same time. The request function returns, for each
+// node index, the path, the body (or nil) and the value where the response is decoded (or nil).
+// It returns the first error found.
+func (n *Node) broadcast(request func(i int) (path string, body, resp interface{})) error {
+	errs := make([]error, len(n.Peers))
+	var wg sync.WaitGroup
+	for i := range n.Peers {
+		path, body, resp := request(i)
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = n.post(n.Peers[i]+path, body, resp)
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return &peerError{i, err}
+		}
+	}
+	return nil
+}
+
+// post sends a JSON encoded body to the url and decodes the response in resp, if it is not nil.
+func (n *Node) post(url string, body, resp interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	res, err := n.Client.Post(url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		var errResp errorResponse
+		if err := json.NewDecoder(res.Body).Decode(&errResp); err != nil {
+			return fmt.Errorf("unexpected status %s", res.Status)
+		}
+		return fmt.Errorf("%s", errResp.Error)
+	}
+	if resp == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(resp)
+}
+
+// errorResponse is the body of an error response.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// readJSON decodes the JSON body of a request into v. It fails if the body is larger than maxBodySize.
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	b, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// writeJSON writes v as a JSON response with the given status code.
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error as a JSON response with the given status code.
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, &errorResponse{Error: err.Error()})
+}
+
+// randomID returns a new random identifier.
+func randomID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,564 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"github.com/niclabs/tcecdsa"
+	"github.com/niclabs/tcecdsa/audit"
+	"github.com/niclabs/tcecdsa/keystore"
+	"github.com/niclabs/tcpaillier"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var p256, _ = new(big.Int).SetString("137537413420762547650493449893260858917800237153163144023736310003651436274145088983133654061063082464994185960314187752168804446390815366271979811577969538698776031062862677455983392561329766291346225778385930191175906902426248344178361969094907274847853172862307239782873996608540554379871730524796395787247", 10)
+var p1256, _ = new(big.Int).SetString("68768706710381273825246724946630429458900118576581572011868155001825718137072544491566827030531541232497092980157093876084402223195407683135989905788984769349388015531431338727991696280664883145673112889192965095587953451213124172089180984547453637423926586431153619891436998304270277189935865262398197893623", 10)
+var q256, _ = new(big.Int).SetString("155023296754007244089528221677469556667119528650199642609409577564109526126683690043302787241624003158964758573520637795429011770537460107983462221613420743461825559166142342076795339979845533939313389392767803764678383759133574074970235524519083017279806360059710695964336383022336657693956448087509984830363", 10)
+var q1256, _ = new(big.Int).SetString("77511648377003622044764110838734778333559764325099821304704788782054763063341845021651393620812001579482379286760318897714505885268730053991731110806710371730912779583071171038397669989922766969656694696383901882339191879566787037485117762259541508639903180029855347982168191511168328846978224043754992415181", 10)
+
+// testToken is the API token of the "tester" requester on the nodes started by startNodes.
+const testToken = "test-token"
+
+// startNodes starts l nodes listening on localhost, using in-memory stores.
+func startNodes(t *testing.T, l int) (nodes []*Node, servers []*httptest.Server, stores []keystore.ShareStore) {
+	peers := make([]string, l)
+	nodes = make([]*Node, l)
+	for i := 0; i < l; i++ {
+		i := i
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			nodes[i].ServeHTTP(w, r)
+		}))
+		servers = append(servers, server)
+		peers[i] = server.URL
+	}
+	for i := 0; i < l; i++ {
+		store := keystore.NewMemoryStore()
+		stores = append(stores, store)
+		node, err := NewNode(uint8(i), peers, store)
+		if err != nil {
+			t.Fatal(err)
+		}
+		node.APITokens = map[string]string{testToken: "tester"}
+		nodes[i] = node
+	}
+	return
+}
+
+// doJSON sends a request with a JSON body to url, authenticated with testToken, and decodes the response in resp.
+func doJSON(method, url string, body, resp interface{}) (int, error) {
+	var b []byte
+	if body != nil {
+		var err error
+		if b, err = json.Marshal(body); err != nil {
+			return 0, err
+		}
+	}
+	req, err := http.NewRequest(method, url, bytes.NewReader(b))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	return res.StatusCode, json.NewDecoder(res.Body).Decode(resp)
+}
+
+func TestNode(t *testing.T) {
+	nodes, servers, stores := startNodes(t, 3)
+	for _, server := range servers {
+		defer server.Close()
+	}
+	dir, err := ioutil.TempDir("", "tcecdsa-node")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	auditPaths := make([]string, len(nodes))
+	for i, node := range nodes {
+		auditPaths[i] = filepath.Join(dir, fmt.Sprintf("node%d.log", i))
+		log, err := audit.Open(auditPaths[i], fmt.Sprintf("%d", i))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer log.Close()
+		node.Audit = log
+	}
+	var key KeyInfo
+	code, err := doJSON(http.MethodPost, servers[0].URL+"/keys", &KeyGenRequest{
+		ID:        "test",
+		Curve:     "P-256",
+		Threshold: 2,
+		PaillierFixed: &tcpaillier.FixedParams{
+			P:  p256,
+			P1: p1256,
+			Q:  q256,
+			Q1: q1256,
+		},
+	}, &key)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if code != http.StatusCreated || !key.Ready {
+		t.Errorf("unexpected key generation response %d: %+v", code, key)
+		return
+	}
+	pkBytes, err := hex.DecodeString(key.PublicKey)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	x, y := elliptic.Unmarshal(elliptic.P256(), pkBytes)
+	if x == nil {
+		t.Error("cannot parse public key")
+		return
+	}
+	pk := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+
+	for i, node := range nodes {
+		var keys []*KeyInfo
+		if _, err := doJSON(http.MethodGet, servers[i].URL+"/keys", nil, &keys); err != nil {
+			t.Error(err)
+			return
+		}
+		if len(keys) != 1 || keys[0].PublicKey != key.PublicKey {
+			t.Errorf("node %d has unexpected keys %+v", node.Index, keys)
+			return
+		}
+	}
+
+	for _, node := range nodes {
+		node.Policy = tcecdsa.AllowRequesters("tester")
+	}
+	h := sha256.Sum256([]byte("hello world"))
+	var session SessionInfo
+	code, err = doJSON(http.MethodPost, servers[1].URL+"/keys/test/sign", &SignRequest{Hash: hex.EncodeToString(h[:])}, &session)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if code != http.StatusAccepted {
+		t.Errorf("unexpected sign response %d: %+v", code, session)
+		return
+	}
+	deadline := time.Now().Add(5 * time.Minute)
+	for session.Status != StatusFinished {
+		if session.Status == StatusFailed || time.Now().After(deadline) {
+			t.Errorf("session did not finish: %+v", session)
+			return
+		}
+		time.Sleep(500 * time.Millisecond)
+		if _, err := doJSON(http.MethodGet, servers[1].URL+"/sessions/"+session.ID, nil, &session); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	der, err := hex.DecodeString(session.Signature)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	r, s, err := tcecdsa.UnmarshalSignature(der)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !ecdsa.Verify(pk, h[:], r, s) {
+		t.Error("signature verification failed")
+		return
+	}
+	if new(big.Int).SetBytes(mustHex(t, session.R)).Cmp(r) != 0 {
+		t.Error("r is different to DER signature r")
+		return
+	}
+	var other SessionInfo
+	if _, err := doJSON(http.MethodGet, servers[2].URL+"/sessions/"+session.ID, nil, &other); err != nil {
+		t.Error(err)
+		return
+	}
+	if other.Status != StatusFinished || other.Signature != session.Signature {
+		t.Errorf("node 2 has a different session status: %+v", other)
+		return
+	}
+	var errResp errorResponse
+	code, err = doJSON(http.MethodPost, servers[2].URL+"/internal/sessions/"+session.ID+"/round1", nil, &errResp)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if code != http.StatusBadRequest {
+		t.Errorf("replaying a round of a finished session should return %d, but it returned %d", http.StatusBadRequest, code)
+		return
+	}
+	if _, err := doJSON(http.MethodGet, servers[2].URL+"/sessions/"+session.ID, nil, &other); err != nil {
+		t.Error(err)
+		return
+	}
+	if other.Status != StatusFinished {
+		t.Errorf("replaying a round should not change the session, but it is %+v", other)
+		return
+	}
+
+	for i, node := range nodes {
+		n, head := node.Audit.Head()
+		records, err := audit.Verify(bytes.NewReader(mustRead(t, auditPaths[i])))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if uint64(len(records)) != n || !bytes.Equal(records[n-1].Hash, head) {
+			t.Errorf("audit log of node %d does not match its head", node.Index)
+			return
+		}
+		for _, rec := range records {
+			// The three nodes answer every round, but only the first K (2) messages are combined.
+			if rec.Type == audit.RoundAccepted && fmt.Sprint(rec.Participants) != "[0 1]" {
+				t.Errorf("audit record of node %d should accept the first 2 messages, but it is %+v", node.Index, rec)
+				return
+			}
+		}
+		last := records[n-1]
+		if last.Type != audit.SignatureMade || last.SessionID != session.ID || last.R.Cmp(r) != 0 || last.S.Cmp(s) != 0 {
+			t.Errorf("last audit record of node %d should be the signature, but it is %+v", node.Index, last)
+			return
+		}
+	}
+
+	nodes[2].Policy = tcecdsa.AllowRequesters("admin")
+	code, err = doJSON(http.MethodPost, servers[0].URL+"/keys/test/sign", &SignRequest{Hash: hex.EncodeToString(h[:])}, &session)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if code != http.StatusAccepted {
+		t.Errorf("unexpected sign response %d: %+v", code, session)
+		return
+	}
+	for session.Status != StatusFailed {
+		if session.Status == StatusFinished || time.Now().After(deadline) {
+			t.Errorf("session denied by node 2 should fail: %+v", session)
+			return
+		}
+		time.Sleep(500 * time.Millisecond)
+		if _, err := doJSON(http.MethodGet, servers[0].URL+"/sessions/"+session.ID, nil, &session); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	if !strings.Contains(session.Error, `node 2: policy requester allow-list denied the request`) {
+		t.Errorf("session should fail with the denial of node 2, but it failed with %q", session.Error)
+		return
+	}
+
+	restarted, err := NewNode(0, nodes[0].Peers, stores[0])
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if key, err := restarted.getKey("test"); err != nil || !key.Ready || key.Share.Y.Cmp(tcecdsa.NewPoint(x, y)) != 0 {
+		t.Errorf("restarted node should load the stored key: %v", err)
+		return
+	}
+
+	code, err = doJSON(http.MethodPost, servers[0].URL+"/keys/unknown/sign", &SignRequest{Hash: hex.EncodeToString(h[:])}, &session)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if code != http.StatusNotFound {
+		t.Errorf("signing with an unknown key should return %d, but it returned %d", http.StatusNotFound, code)
+		return
+	}
+}
+
+func TestNode_PeerAuthentication(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tcecdsa-node-tls")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	caFile, certFile, keyFile := writeTestCertificates(t, dir)
+	serverConfig, clientConfig, err := tlsConfigs(certFile, keyFile, caFile)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	node, err := NewNode(0, []string{""}, keystore.NewMemoryStore())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	node.RequirePeerCert = true
+	server := httptest.NewUnstartedServer(node)
+	server.TLS = serverConfig
+	server.StartTLS()
+	defer server.Close()
+	body := []byte(`{"ID":"session","KeyID":"unknown"}`)
+
+	// The request reaches the handler, which fails because the key does not exist.
+	peer := &http.Client{Transport: &http.Transport{TLSClientConfig: clientConfig}}
+	res, err := peer.Post(server.URL+"/internal/sessions", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("request of a peer should return %d, but it returned %d", http.StatusBadRequest, res.StatusCode)
+		return
+	}
+
+	anonymousConfig := clientConfig.Clone()
+	anonymousConfig.Certificates = nil
+	anonymous := &http.Client{Transport: &http.Transport{TLSClientConfig: anonymousConfig}}
+	res, err = anonymous.Post(server.URL+"/internal/sessions", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusForbidden {
+		t.Errorf("request without a certificate should return %d, but it returned %d", http.StatusForbidden, res.StatusCode)
+		return
+	}
+	node.APITokens = map[string]string{testToken: "tester"}
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/keys", nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	res, err = anonymous.Do(req)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("public API should not require a certificate, but it returned %d", res.StatusCode)
+		return
+	}
+}
+
+func TestNode_APIAuthentication(t *testing.T) {
+	_, servers, _ := startNodes(t, 1)
+	defer servers[0].Close()
+	for auth, expected := range map[string]int{
+		"":                          http.StatusUnauthorized,
+		testToken:                   http.StatusUnauthorized,
+		"Bearer other-token":        http.StatusUnauthorized,
+		"Bearer " + testToken:       http.StatusOK,
+		"Bearer " + testToken + "x": http.StatusUnauthorized,
+	} {
+		for _, path := range []string{"/keys", "/sessions/unknown"} {
+			req, err := http.NewRequest(http.MethodGet, servers[0].URL+path, nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if auth != "" {
+				req.Header.Set("Authorization", auth)
+			}
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			res.Body.Close()
+			// An authenticated request for an unknown session reaches the handler, which does not find it.
+			code := expected
+			if expected == http.StatusOK && path == "/sessions/unknown" {
+				code = http.StatusNotFound
+			}
+			if res.StatusCode != code {
+				t.Errorf("GET %s with authorization %q should return %d, but it returned %d", path, auth, code, res.StatusCode)
+				return
+			}
+		}
+	}
+}
+
+func TestReadAPITokens(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tcecdsa-node-tokens")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	for content, expected := range map[string]string{
+		"# clients\nalice token-a\n\nbob token-b\n": "map[token-a:alice token-b:bob]",
+		"alice token-a\nbob token-a\n":              "error",
+		"alice\n":                                   "error",
+		"# no clients\n":                            "error",
+	} {
+		path := filepath.Join(dir, "tokens")
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Error(err)
+			return
+		}
+		tokens, err := readAPITokens(path)
+		if (err != nil) != (expected == "error") || err == nil && fmt.Sprint(tokens) != expected {
+			t.Errorf("readAPITokens(%q) should return %s, but it returned %v, %v", content, expected, tokens, err)
+			return
+		}
+	}
+}
+
+func TestNode_BodySize(t *testing.T) {
+	nodes, servers, _ := startNodes(t, 1)
+	defer servers[0].Close()
+	body := append([]byte(`{"ID":"`), bytes.Repeat([]byte("a"), maxBodySize)...)
+	body = append(body, []byte(`","KeyID":"unknown"}`)...)
+	res, err := http.Post(servers[0].URL+"/internal/sessions", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer res.Body.Close()
+	var errResp errorResponse
+	if err := json.NewDecoder(res.Body).Decode(&errResp); err != nil {
+		t.Error(err)
+		return
+	}
+	if res.StatusCode != http.StatusBadRequest || errResp.Error != "http: request body too large" {
+		t.Errorf("a body larger than %d bytes should be rejected, but node %d returned %d: %s", maxBodySize, nodes[0].Index, res.StatusCode, errResp.Error)
+		return
+	}
+}
+
+func TestNode_RoundOrder(t *testing.T) {
+	nodes, servers, _ := startNodes(t, 1)
+	defer servers[0].Close()
+	// The session has no state, so the test fails with a panic if a round reaches it.
+	nodes[0].sessions["session"] = &Session{ID: "session", KeyID: "test", Status: StatusStarted}
+	for _, round := range []string{"round2", "round3", "finish", "unknown"} {
+		var errResp errorResponse
+		code, err := doJSON(http.MethodPost, servers[0].URL+"/internal/sessions/session/"+round, tcecdsa.Round1MessageList{}, &errResp)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if code != http.StatusBadRequest {
+			t.Errorf("%s before round1 should return %d, but it returned %d", round, http.StatusBadRequest, code)
+			return
+		}
+	}
+	if status := nodes[0].sessions["session"].Status; status != StatusStarted {
+		t.Errorf("rounds out of order should not change the session, but its status is %s", status)
+		return
+	}
+}
+
+func TestIsLoopback(t *testing.T) {
+	for listen, expected := range map[string]bool{
+		"127.0.0.1:8080": true,
+		"[::1]:8080":     true,
+		"localhost:8080": true,
+		":8080":          false,
+		"0.0.0.0:8080":   false,
+		"10.0.0.1:8080":  false,
+		"invalid":        false,
+	} {
+		if isLoopback(listen) != expected {
+			t.Errorf("isLoopback(%q) should be %t", listen, expected)
+			return
+		}
+	}
+}
+
+// writeTestCertificates writes on dir a CA certificate and a node certificate for 127.0.0.1 signed by it,
+// returning the paths of the CA certificate, the node certificate and its private key.
+func writeTestCertificates(t *testing.T, dir string) (caFile, certFile, keyFile string) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "tcecdsa test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodeKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodeTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "node 0"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	nodeDER, err := x509.CreateCertificate(rand.Reader, nodeTemplate, caTemplate, &nodeKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodeKeyDER, err := x509.MarshalECPrivateKey(nodeKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caFile = filepath.Join(dir, "ca.pem")
+	certFile = filepath.Join(dir, "node.pem")
+	keyFile = filepath.Join(dir, "node.key")
+	for path, block := range map[string]*pem.Block{
+		caFile:   {Type: "CERTIFICATE", Bytes: caDER},
+		certFile: {Type: "CERTIFICATE", Bytes: nodeDER},
+		keyFile:  {Type: "EC PRIVATE KEY", Bytes: nodeKeyDER},
+	} {
+		if err := ioutil.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return
+}
+
+// mustHex decodes a hex string, failing the test if it is not valid.
+func mustHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// mustRead reads a file, failing the test if it cannot be read.
+func mustRead(t *testing.T, path string) []byte {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
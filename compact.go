@@ -0,0 +1,580 @@
+package tcecdsa
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"github.com/niclabs/tcecdsa/l2fhe"
+	"github.com/niclabs/tcpaillier"
+	"math/big"
+)
+
+// The following values are the tags written on the first byte of each compact encoded message.
+const (
+	compactKeyInit byte = iota + 1
+	compactRound1
+	compactRound2
+	compactRound3
+	compactSchnorrRound2
+)
+
+// challengeSize is the size in bytes of the challenges of the ZKProofs, which are SHA-256 hashes.
+const challengeSize = sha256.Size
+
+// CompactMessage is implemented by the messages of the protocol that can be encoded in the compact binary format.
+// The compact format depends on the key, so the same key metainfo must be used to encode and decode a message.
+type CompactMessage interface {
+	// MarshalCompact returns the compact encoding of the message.
+	MarshalCompact(meta *KeyMeta) ([]byte, error)
+	// UnmarshalCompact sets the message to the value of its compact encoding.
+	UnmarshalCompact(meta *KeyMeta, b []byte) error
+}
+
+// MessageSize represents the size in bytes of a message in each encoding.
+type MessageSize struct {
+	JSON    int `json:"json"`
+	Compact int `json:"compact"`
+}
+
+// SizeOf returns the size of the JSON and compact encodings of a message.
+func SizeOf(meta *KeyMeta, msg CompactMessage) (size MessageSize, err error) {
+	encoded, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	size.JSON = len(encoded)
+	if encoded, err = msg.MarshalCompact(meta); err != nil {
+		return
+	}
+	size.Compact = len(encoded)
+	return
+}
+
+// compactWidths groups the sizes in bytes of the fixed-width values of a key.
+type compactWidths struct {
+	ciphertext int // Values modulo N^(s+1)
+	n          int // Values modulo N
+	nTilde     int // Values modulo NTilde
+}
+
+// compactEncoder appends values to a compact encoding. The first error is kept and the next writes are ignored.
+type compactEncoder struct {
+	meta   *KeyMeta
+	widths compactWidths
+	buf    []byte
+	err    error
+}
+
+// compactDecoder reads values from a compact encoding. The first error is kept and the next reads return zero
+// values.
+type compactDecoder struct {
+	meta   *KeyMeta
+	widths compactWidths
+	buf    []byte
+	err    error
+}
+
+// compactWidths returns the sizes of the fixed-width values of the key.
+func (meta *KeyMeta) compactWidths() compactWidths {
+	cache := meta.Paillier.Cache()
+	widths := compactWidths{
+		ciphertext: modulusBytes(cache.NToSPlusOne),
+		n:          modulusBytes(meta.Paillier.N),
+	}
+	if meta.ZKProofMeta != nil {
+		widths.nTilde = modulusBytes(meta.NTilde)
+	}
+	return widths
+}
+
+// modulusBytes returns the number of bytes needed to write the values lower than a modulus.
+func modulusBytes(modulus *big.Int) int {
+	return (new(big.Int).Sub(modulus, one).BitLen() + 7) / 8
+}
+
+// newCompactEncoder returns an encoder of a message with the given tag.
+func newCompactEncoder(meta *KeyMeta, tag byte) *compactEncoder {
+	return &compactEncoder{meta: meta, widths: meta.compactWidths(), buf: []byte{tag}}
+}
+
+// newCompactDecoder returns a decoder of a message with the given tag.
+func newCompactDecoder(meta *KeyMeta, tag byte, b []byte) *compactDecoder {
+	d := &compactDecoder{meta: meta, widths: meta.compactWidths()}
+	if len(b) == 0 || b[0] != tag {
+		d.err = fmt.Errorf("unexpected message tag")
+		return d
+	}
+	d.buf = b[1:]
+	return d
+}
+
+// bytes returns the encoding, or the first error.
+func (e *compactEncoder) bytes() ([]byte, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	return e.buf, nil
+}
+
+// fail sets the error of the encoder if it has none.
+func (e *compactEncoder) fail(format string, a ...interface{}) {
+	if e.err == nil {
+		e.err = fmt.Errorf(format, a...)
+	}
+}
+
+// fixed writes a non-negative value using width bytes.
+func (e *compactEncoder) fixed(v *big.Int, width int) {
+	if e.err != nil {
+		return
+	}
+	if v == nil || v.Sign() < 0 || (v.BitLen()+7)/8 > width {
+		e.fail("value does not fit in %d bytes", width)
+		return
+	}
+	e.buf = append(e.buf, padBytes(v, width)...)
+}
+
+// length writes a non-negative integer as an unsigned varint.
+func (e *compactEncoder) length(n int) {
+	if e.err != nil {
+		return
+	}
+	var b [binary.MaxVarintLen64]byte
+	e.buf = append(e.buf, b[:binary.PutUvarint(b[:], uint64(n))]...)
+}
+
+// unsigned writes a non-negative value of variable size, prefixed by its length.
+func (e *compactEncoder) unsigned(v *big.Int) {
+	if v == nil || v.Sign() < 0 {
+		e.fail("value must be non-negative")
+		return
+	}
+	b := v.Bytes()
+	e.length(len(b))
+	e.raw(b)
+}
+
+// signed writes a value of variable size, prefixed by its length and its sign on the lowest bit.
+func (e *compactEncoder) signed(v *big.Int) {
+	if v == nil {
+		e.fail("value must be set")
+		return
+	}
+	b := v.Bytes()
+	sign := 0
+	if v.Sign() < 0 {
+		sign = 1
+	}
+	e.length(len(b)<<1 | sign)
+	e.raw(b)
+}
+
+// point writes a point of the curve of the key, compressed.
+func (e *compactEncoder) point(p *Point) {
+	if p == nil || p.X == nil || p.Y == nil {
+		e.fail("point must be set")
+		return
+	}
+	e.raw(p.CompressedBytes(e.meta.Curve()))
+}
+
+// raw writes bytes without a prefix.
+func (e *compactEncoder) raw(b []byte) {
+	if e.err != nil {
+		return
+	}
+	e.buf = append(e.buf, b...)
+}
+
+// encryptedL1 writes a level-1 encrypted value.
+func (e *compactEncoder) encryptedL1(c *l2fhe.EncryptedL1) {
+	if c == nil {
+		e.fail("encrypted value must be set")
+		return
+	}
+	e.signed(c.Alpha)
+	e.fixed(c.Beta, e.widths.ciphertext)
+}
+
+// decryptionShare writes a decryption share and its proof. The index of the share and the verification
+// values of the proof are omitted, because the receiver gets them from the key and the index of the message.
+func (e *compactEncoder) decryptionShare(index uint8, ds *tcpaillier.DecryptionShare, zk *tcpaillier.DecryptShareZK) {
+	if ds == nil || zk == nil {
+		e.fail("decryption share and proof must be set")
+		return
+	}
+	if ds.Index != index {
+		e.fail("decryption shares must have the same index")
+		return
+	}
+	e.fixed(ds.Ci, e.widths.ciphertext)
+	e.unsigned(zk.Z)
+	e.fixed(zk.E, challengeSize)
+}
+
+// done returns an error if the decoder failed or if there are bytes left.
+func (d *compactDecoder) done() error {
+	if d.err == nil && len(d.buf) > 0 {
+		d.err = fmt.Errorf("%d unexpected bytes at the end of the message", len(d.buf))
+	}
+	return d.err
+}
+
+// fail sets the error of the decoder if it has none.
+func (d *compactDecoder) fail(format string, a ...interface{}) {
+	if d.err == nil {
+		d.err = fmt.Errorf(format, a...)
+	}
+}
+
+// raw reads n bytes.
+func (d *compactDecoder) raw(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(d.buf) {
+		d.fail("message is too short")
+		return nil
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+// fixed reads a value written with width bytes, and returns an error if it is not lower than modulus.
+// If modulus is nil, any value is accepted.
+func (d *compactDecoder) fixed(width int, modulus *big.Int) *big.Int {
+	b := d.raw(width)
+	if d.err != nil {
+		return nil
+	}
+	v := new(big.Int).SetBytes(b)
+	if modulus != nil && v.Cmp(modulus) >= 0 {
+		d.fail("value is not lower than its modulus")
+		return nil
+	}
+	return v
+}
+
+// length reads an unsigned varint, which must not be greater than the bytes left times perByte.
+func (d *compactDecoder) length(perByte int) int {
+	if d.err != nil {
+		return 0
+	}
+	n, read := binary.Uvarint(d.buf)
+	if read <= 0 {
+		d.fail("invalid length")
+		return 0
+	}
+	d.buf = d.buf[read:]
+	if n > uint64(len(d.buf))*uint64(perByte) {
+		d.fail("message is too short")
+		return 0
+	}
+	return int(n)
+}
+
+// unsigned reads a non-negative value of variable size.
+func (d *compactDecoder) unsigned() *big.Int {
+	b := d.raw(d.length(1))
+	if d.err != nil {
+		return nil
+	}
+	return new(big.Int).SetBytes(b)
+}
+
+// signed reads a value of variable size and its sign.
+func (d *compactDecoder) signed() *big.Int {
+	n := d.length(2)
+	b := d.raw(n >> 1)
+	if d.err != nil {
+		return nil
+	}
+	v := new(big.Int).SetBytes(b)
+	if n&1 == 1 {
+		v.Neg(v)
+	}
+	return v
+}
+
+// point reads a compressed point of the curve of the key.
+func (d *compactDecoder) point() *Point {
+	if d.err != nil {
+		return nil
+	}
+	curve := d.meta.Curve()
+	size := 1 + (curve.Params().BitSize+7)/8
+	if len(d.buf) > 0 && d.buf[0] == 0 {
+		size = 1 // Point at infinity
+	}
+	p, err := NewZero().SetCompressedBytes(curve, d.raw(size))
+	if d.err != nil {
+		return nil
+	}
+	if err != nil {
+		d.fail("%s", err)
+		return nil
+	}
+	return p
+}
+
+// encryptedL1 reads a level-1 encrypted value.
+func (d *compactDecoder) encryptedL1() *l2fhe.EncryptedL1 {
+	alpha := d.signed()
+	beta := d.fixed(d.widths.ciphertext, d.meta.Paillier.Cache().NToSPlusOne)
+	if d.err != nil {
+		return nil
+	}
+	return &l2fhe.EncryptedL1{Alpha: alpha, Beta: beta}
+}
+
+// decryptionShare reads a decryption share and its proof, setting the values omitted by the encoder.
+func (d *compactDecoder) decryptionShare(index uint8) (*tcpaillier.DecryptionShare, *tcpaillier.DecryptShareZK) {
+	ci := d.fixed(d.widths.ciphertext, d.meta.Paillier.Cache().NToSPlusOne)
+	z := d.unsigned()
+	e := d.fixed(challengeSize, nil)
+	if d.err != nil {
+		return nil, nil
+	}
+	return &tcpaillier.DecryptionShare{Index: index, Ci: ci}, &tcpaillier.DecryptShareZK{
+		V:  d.meta.Paillier.V,
+		Vi: d.meta.Paillier.Vi[index-1],
+		Z:  z,
+		E:  e,
+	}
+}
+
+// index reads the index of a participant of the key.
+func (d *compactDecoder) index() uint8 {
+	b := d.raw(1)
+	if d.err != nil {
+		return 0
+	}
+	if b[0] == 0 || b[0] > d.meta.Paillier.L || int(b[0]) > len(d.meta.Paillier.Vi) {
+		d.fail("invalid participant index %d", b[0])
+		return 0
+	}
+	return b[0]
+}
+
+// MarshalCompact returns the compact encoding of the message.
+func (msg *KeyInitMessage) MarshalCompact(meta *KeyMeta) ([]byte, error) {
+	if msg.Proof == nil {
+		return nil, fmt.Errorf("message fields must be set")
+	}
+	e := newCompactEncoder(meta, compactKeyInit)
+	e.encryptedL1(msg.AlphaI)
+	e.point(msg.Yi)
+	p := msg.Proof
+	e.point(p.U1)
+	e.fixed(p.U2, e.widths.ciphertext)
+	e.fixed(p.U3, e.widths.nTilde)
+	e.unsigned(p.S1)
+	e.fixed(p.S2, e.widths.n)
+	e.unsigned(p.S3)
+	e.fixed(p.E, challengeSize)
+	e.fixed(p.Z, e.widths.nTilde)
+	return e.bytes()
+}
+
+// UnmarshalCompact sets the message to the value of its compact encoding.
+func (msg *KeyInitMessage) UnmarshalCompact(meta *KeyMeta, b []byte) error {
+	d := newCompactDecoder(meta, compactKeyInit, b)
+	nToSPlusOne, n, nTilde := meta.Paillier.Cache().NToSPlusOne, meta.Paillier.N, meta.NTilde
+	alphaI := d.encryptedL1()
+	yi := d.point()
+	p := &KeyGenZKProof{}
+	p.U1 = d.point()
+	p.U2 = d.fixed(d.widths.ciphertext, nToSPlusOne)
+	p.U3 = d.fixed(d.widths.nTilde, nTilde)
+	p.S1 = d.unsigned()
+	p.S2 = d.fixed(d.widths.n, n)
+	p.S3 = d.unsigned()
+	p.E = d.fixed(challengeSize, nil)
+	p.Z = d.fixed(d.widths.nTilde, nTilde)
+	if err := d.done(); err != nil {
+		return err
+	}
+	msg.AlphaI, msg.Yi, msg.Proof = alphaI, yi, p
+	return nil
+}
+
+// MarshalCompact returns the compact encoding of the message.
+func (msg *Round1Message) MarshalCompact(meta *KeyMeta) ([]byte, error) {
+	if msg.Proof == nil {
+		return nil, fmt.Errorf("message fields must be set")
+	}
+	e := newCompactEncoder(meta, compactRound1)
+	e.point(msg.Ri)
+	e.encryptedL1(msg.Ui)
+	e.encryptedL1(msg.Vi)
+	e.encryptedL1(msg.Wi)
+	p := msg.Proof
+	e.point(p.U1)
+	for _, v := range []*big.Int{p.U2, p.U3, p.U4} {
+		e.fixed(v, e.widths.ciphertext)
+	}
+	for _, v := range []*big.Int{p.Z1, p.Z2, p.Z3, p.V1, p.V2, p.V3} {
+		e.fixed(v, e.widths.nTilde)
+	}
+	for _, v := range []*big.Int{p.S1, p.S3, p.S4, p.S5, p.S6, p.S7} {
+		e.unsigned(v)
+	}
+	for _, v := range []*big.Int{p.T1, p.T2, p.T3} {
+		e.fixed(v, e.widths.n)
+	}
+	e.fixed(p.E, challengeSize)
+	return e.bytes()
+}
+
+// UnmarshalCompact sets the message to the value of its compact encoding.
+func (msg *Round1Message) UnmarshalCompact(meta *KeyMeta, b []byte) error {
+	d := newCompactDecoder(meta, compactRound1, b)
+	nToSPlusOne, n, nTilde := meta.Paillier.Cache().NToSPlusOne, meta.Paillier.N, meta.NTilde
+	ri := d.point()
+	ui := d.encryptedL1()
+	vi := d.encryptedL1()
+	wi := d.encryptedL1()
+	p := &SigZKProof{}
+	p.U1 = d.point()
+	for _, v := range []**big.Int{&p.U2, &p.U3, &p.U4} {
+		*v = d.fixed(d.widths.ciphertext, nToSPlusOne)
+	}
+	for _, v := range []**big.Int{&p.Z1, &p.Z2, &p.Z3, &p.V1, &p.V2, &p.V3} {
+		*v = d.fixed(d.widths.nTilde, nTilde)
+	}
+	for _, v := range []**big.Int{&p.S1, &p.S3, &p.S4, &p.S5, &p.S6, &p.S7} {
+		*v = d.unsigned()
+	}
+	for _, v := range []**big.Int{&p.T1, &p.T2, &p.T3} {
+		*v = d.fixed(d.widths.n, n)
+	}
+	p.E = d.fixed(challengeSize, nil)
+	if err := d.done(); err != nil {
+		return err
+	}
+	msg.Ri, msg.Ui, msg.Vi, msg.Wi, msg.Proof = ri, ui, vi, wi, p
+	return nil
+}
+
+// marshalDecryptedShareL2 returns the compact encoding of a level-2 decryption share, its proof and the
+// commitments of the proof, which can be empty.
+func marshalDecryptedShareL2(meta *KeyMeta, tag byte, ds *l2fhe.DecryptedShareL2, zk *l2fhe.DecryptedShareL2ZK, commitments []*DecryptShareCommitment) ([]byte, error) {
+	if ds == nil || zk == nil || ds.Alpha == nil || len(ds.Betas) != len(zk.Betas) {
+		return nil, fmt.Errorf("message fields must be set")
+	}
+	e := newCompactEncoder(meta, tag)
+	index := ds.Alpha.Index
+	e.raw([]byte{index})
+	e.length(len(ds.Betas))
+	e.decryptionShare(index, ds.Alpha, zk.Alpha)
+	for i, beta := range ds.Betas {
+		if beta == nil || zk.Betas[i] == nil {
+			return nil, fmt.Errorf("message fields must be set")
+		}
+		e.decryptionShare(index, beta.Beta1, zk.Betas[i].Beta1)
+		e.decryptionShare(index, beta.Beta2, zk.Betas[i].Beta2)
+	}
+	e.length(len(commitments))
+	for _, commitment := range commitments {
+		if commitment == nil {
+			return nil, fmt.Errorf("message fields must be set")
+		}
+		e.fixed(commitment.A, e.widths.ciphertext)
+		e.fixed(commitment.B, e.widths.ciphertext)
+	}
+	return e.bytes()
+}
+
+// unmarshalDecryptedShareL2 reads a level-2 decryption share, its proof and the commitments of the proof from
+// their compact encoding.
+func unmarshalDecryptedShareL2(meta *KeyMeta, tag byte, b []byte) (ds *l2fhe.DecryptedShareL2, zk *l2fhe.DecryptedShareL2ZK, commitments []*DecryptShareCommitment, err error) {
+	d := newCompactDecoder(meta, tag, b)
+	index := d.index()
+	betas := d.length(1)
+	ds = &l2fhe.DecryptedShareL2{Betas: make([]*l2fhe.DecryptedShareBetas, 0)}
+	zk = &l2fhe.DecryptedShareL2ZK{Betas: make([]*l2fhe.BetasZK, 0)}
+	ds.Alpha, zk.Alpha = d.decryptionShare(index)
+	for i := 0; i < betas && d.err == nil; i++ {
+		beta, betaZK := &l2fhe.DecryptedShareBetas{}, &l2fhe.BetasZK{}
+		beta.Beta1, betaZK.Beta1 = d.decryptionShare(index)
+		beta.Beta2, betaZK.Beta2 = d.decryptionShare(index)
+		ds.Betas = append(ds.Betas, beta)
+		zk.Betas = append(zk.Betas, betaZK)
+	}
+	// The commitments are optional, but if they are set there is one for each decryption share.
+	if n := d.length(1); n != 0 && n != 1+2*betas {
+		d.fail("invalid number of commitments %d", n)
+	} else {
+		nToSPlusOne := meta.Paillier.Cache().NToSPlusOne
+		for i := 0; i < n && d.err == nil; i++ {
+			commitments = append(commitments, &DecryptShareCommitment{
+				A: d.fixed(d.widths.ciphertext, nToSPlusOne),
+				B: d.fixed(d.widths.ciphertext, nToSPlusOne),
+			})
+		}
+	}
+	if err = d.done(); err != nil {
+		ds, zk, commitments = nil, nil, nil
+	}
+	return
+}
+
+// MarshalCompact returns the compact encoding of the message.
+func (msg *Round2Message) MarshalCompact(meta *KeyMeta) ([]byte, error) {
+	return marshalDecryptedShareL2(meta, compactRound2, msg.PDZ, msg.Proof, msg.Commitments)
+}
+
+// UnmarshalCompact sets the message to the value of its compact encoding.
+func (msg *Round2Message) UnmarshalCompact(meta *KeyMeta, b []byte) error {
+	pdz, proof, commitments, err := unmarshalDecryptedShareL2(meta, compactRound2, b)
+	if err != nil {
+		return err
+	}
+	msg.PDZ, msg.Proof, msg.Commitments = pdz, proof, commitments
+	return nil
+}
+
+// MarshalCompact returns the compact encoding of the message.
+func (msg *Round3Message) MarshalCompact(meta *KeyMeta) ([]byte, error) {
+	return marshalDecryptedShareL2(meta, compactRound3, msg.PDSigma, msg.Proof, msg.Commitments)
+}
+
+// UnmarshalCompact sets the message to the value of its compact encoding.
+func (msg *Round3Message) UnmarshalCompact(meta *KeyMeta, b []byte) error {
+	pdSigma, proof, commitments, err := unmarshalDecryptedShareL2(meta, compactRound3, b)
+	if err != nil {
+		return err
+	}
+	msg.PDSigma, msg.Proof, msg.Commitments = pdSigma, proof, commitments
+	return nil
+}
+
+// MarshalCompact returns the compact encoding of the message.
+func (msg *SchnorrRound2Message) MarshalCompact(meta *KeyMeta) ([]byte, error) {
+	if msg.PDS == nil || msg.PDS.Beta == nil || msg.Proof == nil {
+		return nil, fmt.Errorf("message fields must be set")
+	}
+	e := newCompactEncoder(meta, compactSchnorrRound2)
+	index := msg.PDS.Beta.Index
+	e.raw([]byte{index})
+	e.signed(msg.PDS.Alpha)
+	e.decryptionShare(index, msg.PDS.Beta, msg.Proof.Beta)
+	return e.bytes()
+}
+
+// UnmarshalCompact sets the message to the value of its compact encoding.
+func (msg *SchnorrRound2Message) UnmarshalCompact(meta *KeyMeta, b []byte) error {
+	d := newCompactDecoder(meta, compactSchnorrRound2, b)
+	index := d.index()
+	alpha := d.signed()
+	beta, proof := d.decryptionShare(index)
+	if err := d.done(); err != nil {
+		return err
+	}
+	msg.PDS = &l2fhe.DecryptedShareL1{Alpha: alpha, Beta: beta}
+	msg.Proof = &l2fhe.DecryptedShareL1ZK{Beta: proof}
+	return nil
+}
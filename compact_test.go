@@ -0,0 +1,189 @@
+package tcecdsa_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"github.com/niclabs/tcecdsa"
+	"github.com/niclabs/tcpaillier"
+	"testing"
+)
+
+func TestCompactMessages(t *testing.T) {
+	params := &tcecdsa.NewKeyParams{
+		PaillierFixed: &tcpaillier.FixedParams{
+			P:  p,
+			P1: p1,
+			Q:  q,
+			Q1: q1,
+		},
+	}
+	shares, keyMeta, err := tcecdsa.NewKey(L, K, Curve, params)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	keyInitMessages := make(tcecdsa.KeyInitMessageList, 0)
+	for _, share := range shares {
+		msg, err := share.Init(keyMeta)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		keyInitMessages = append(keyInitMessages, msg)
+	}
+	for _, share := range shares {
+		if err := share.SetKey(keyMeta, keyInitMessages); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	h := sha256.Sum256(exampleText)
+	states := make([]*tcecdsa.SigSession, 0)
+	for _, share := range shares {
+		state, err := share.NewSigSession(keyMeta, h[:])
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		states = append(states, state)
+	}
+	round1Messages := make(tcecdsa.Round1MessageList, 0)
+	for _, state := range states {
+		msg, err := state.Round1()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		round1Messages = append(round1Messages, msg)
+	}
+	round2Messages := make(tcecdsa.Round2MessageList, 0)
+	for _, state := range states {
+		msg, err := state.Round2(round1Messages)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		round2Messages = append(round2Messages, msg)
+	}
+	// The first session runs Round3 with the decoded Round2 messages.
+	round3Messages := make(tcecdsa.Round3MessageList, 0)
+	for _, state := range states[1:] {
+		msg, err := state.Round3(round2Messages)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		round3Messages = append(round3Messages, msg)
+	}
+
+	// roundTrip encodes msg, decodes it on decoded and checks that both have the same JSON encoding.
+	roundTrip := func(t *testing.T, msg, decoded tcecdsa.CompactMessage) {
+		b, err := msg.MarshalCompact(keyMeta)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if err := decoded.UnmarshalCompact(keyMeta, b); err != nil {
+			t.Error(err)
+			return
+		}
+		expected, err := json.Marshal(msg)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		actual, err := json.Marshal(decoded)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if !bytes.Equal(expected, actual) {
+			t.Error("decoded message differs from the original one")
+			return
+		}
+		size, err := tcecdsa.SizeOf(keyMeta, msg)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if size.Compact != len(b) || size.Compact >= size.JSON {
+			t.Errorf("unexpected sizes %+v", size)
+			return
+		}
+	}
+
+	t.Run("KeyInitMessage", func(t *testing.T) {
+		decoded := make(tcecdsa.KeyInitMessageList, 0)
+		for _, msg := range keyInitMessages {
+			d := &tcecdsa.KeyInitMessage{}
+			roundTrip(t, msg, d)
+			decoded = append(decoded, d)
+		}
+		if _, _, err := decoded.Join(keyMeta); err != nil {
+			t.Error(err)
+			return
+		}
+	})
+
+	t.Run("Round1Message", func(t *testing.T) {
+		decoded := make(tcecdsa.Round1MessageList, 0)
+		for _, msg := range round1Messages {
+			d := &tcecdsa.Round1Message{}
+			roundTrip(t, msg, d)
+			decoded = append(decoded, d)
+		}
+		if _, _, _, _, err := decoded.Join(keyMeta); err != nil {
+			t.Error(err)
+			return
+		}
+	})
+
+	t.Run("Round2Message", func(t *testing.T) {
+		decoded := make(tcecdsa.Round2MessageList, 0)
+		for _, msg := range round2Messages {
+			d := &tcecdsa.Round2Message{}
+			roundTrip(t, msg, d)
+			decoded = append(decoded, d)
+		}
+		if _, err := states[0].Round3(decoded); err != nil {
+			t.Error(err)
+			return
+		}
+	})
+
+	t.Run("Round3Message", func(t *testing.T) {
+		decoded := make(tcecdsa.Round3MessageList, 0)
+		for _, msg := range round3Messages {
+			d := &tcecdsa.Round3Message{}
+			roundTrip(t, msg, d)
+			decoded = append(decoded, d)
+		}
+		if _, _, err := states[0].GetSignature(decoded); err != nil {
+			t.Error(err)
+			return
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		b, err := round2Messages[0].MarshalCompact(keyMeta)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		outOfRange := append([]byte{}, b...)
+		outOfRange[1] = L + 1
+		for name, invalid := range map[string][]byte{
+			"Empty":      {},
+			"Truncated":  b[:len(b)-1],
+			"Trailing":   append(append([]byte{}, b...), 0),
+			"OtherRound": append([]byte{b[0] + 1}, b[1:]...),
+			"BadIndex":   outOfRange,
+		} {
+			if err := (&tcecdsa.Round2Message{}).UnmarshalCompact(keyMeta, invalid); err == nil {
+				t.Errorf("%s encoding should not be decoded", name)
+				return
+			}
+		}
+	})
+}
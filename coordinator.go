@@ -0,0 +1,323 @@
+package tcecdsa
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"github.com/niclabs/tcecdsa/l2fhe"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultRoundTimeout is the time a Coordinator waits for the messages of a round, if it is not set.
+const DefaultRoundTimeout = 30 * time.Second
+
+// Participant represents a participant of a signing session, as seen by the Coordinator.
+// Its methods are usually remote calls to the node holding the key share, and they should
+// return when the context is done.
+type Participant interface {
+	// Begin starts a signing session for the request. The participant can evaluate its own
+	// policy over the request, and deny it returning a *PolicyError before or on Round1.
+	Begin(ctx context.Context, req *SignRequest) error
+	// Round1 executes the first round of the signing session.
+	Round1(ctx context.Context) (*Round1Message, error)
+	// Round2 executes the second round of the signing session, using the accepted Round1 messages.
+	Round2(ctx context.Context, msgs Round1MessageList) (*Round2Message, error)
+	// Round3 executes the third round of the signing session, using the accepted Round2 messages.
+	Round3(ctx context.Context, msgs Round2MessageList) (*Round3Message, error)
+}
+
+// Coordinator orchestrates signing sessions, acting as the broadcast channel between the participants.
+// It collects the messages of each round, verifies their proofs once and sends the accepted set to the
+// participants, so they do not need to exchange messages between them.
+// A participant that does not answer a round before the round timeout, or whose message is not valid, is
+// excluded from the rest of the session. The session fails only if less than K participants remain.
+// The coordinator does not need a private key share, so it can be run by a party that does not hold one.
+type Coordinator struct {
+	RoundTimeout time.Duration // Time to wait for the messages of a round
+	Policy       Policy        // Optional policy evaluated by the coordinator before starting a session
+	meta         *KeyMeta
+	alpha        *l2fhe.EncryptedL1
+	y            *Point
+}
+
+// NewCoordinator returns a new coordinator for the key of the share. Only the public values of the share
+// (Alpha and Y) are used, so its PaillierShare could be nil.
+func NewCoordinator(meta *KeyMeta, share *KeyShare) (*Coordinator, error) {
+	if share.Alpha == nil || share.Y == nil {
+		return nil, fmt.Errorf("key share has not been set")
+	}
+	return &Coordinator{
+		RoundTimeout: DefaultRoundTimeout,
+		meta:         meta,
+		alpha:        share.Alpha,
+		y:            share.Y,
+	}, nil
+}
+
+// Sign runs a signing session for the request between the participants, and returns the signature of
+// its digest. If a round fails because too many participants denied the request or failed, the
+// returned error is a *SessionError with the error of each participant.
+func (c *Coordinator) Sign(ctx context.Context, req *SignRequest, participants []Participant) (r, s *big.Int, err error) {
+	k := int(c.meta.Paillier.K)
+	if len(participants) < k {
+		err = fmt.Errorf("number of participants should be at least K (%d)", k)
+		return
+	}
+	if req == nil || len(req.Digest) == 0 {
+		err = fmt.Errorf("empty hash")
+		return
+	}
+	h := req.Digest
+	if c.Policy != nil {
+		if err = c.Policy.Evaluate(req); err != nil {
+			return
+		}
+	}
+	encM, err := c.meta.EncryptFixedB(HashToInt(h, c.meta.Curve()), one, one)
+	if err != nil {
+		return
+	}
+
+	members := make([]member, len(participants))
+	for i, p := range participants {
+		members[i] = member{i, p}
+	}
+	members, _, err = c.round(ctx, "begin", members, func(ctx context.Context, p Participant) (interface{}, error) {
+		return nil, p.Begin(ctx, req)
+	})
+	if err != nil {
+		return
+	}
+
+	members, results, err := c.round(ctx, "round 1", members, func(ctx context.Context, p Participant) (interface{}, error) {
+		msg, err := p.Round1(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return msg, msg.Verify(c.meta)
+	})
+	if err != nil {
+		return
+	}
+	msgs1 := make(Round1MessageList, 0, k)
+	for _, result := range results[:k] {
+		msgs1 = append(msgs1, result.(*Round1Message))
+	}
+	R, u, v, w, err := msgs1.join(c.meta)
+	if err != nil {
+		return
+	}
+	z, err := newZ(c.meta, u, v, w)
+	if err != nil {
+		return
+	}
+
+	members, results, err = c.round(ctx, "round 2", members, func(ctx context.Context, p Participant) (interface{}, error) {
+		msg, err := p.Round2(ctx, msgs1)
+		if err != nil {
+			return nil, err
+		}
+		return msg, msg.Verify(c.meta, z)
+	})
+	if err != nil {
+		return
+	}
+	msgs2 := make(Round2MessageList, 0, k)
+	for _, result := range results[:k] {
+		msgs2 = append(msgs2, result.(*Round2Message))
+	}
+	nu, err := msgs2.join(c.meta)
+	if err != nil {
+		return
+	}
+	r = new(big.Int).Mod(R.X, c.meta.Q())
+	sigma, err := newSigma(c.meta, c.alpha, encM, u, r, nu)
+	if err != nil {
+		return
+	}
+
+	_, results, err = c.round(ctx, "round 3", members, func(ctx context.Context, p Participant) (interface{}, error) {
+		msg, err := p.Round3(ctx, msgs2)
+		if err != nil {
+			return nil, err
+		}
+		return msg, msg.Verify(c.meta, sigma)
+	})
+	if err != nil {
+		return
+	}
+	msgs3 := make(Round3MessageList, 0, k)
+	for _, result := range results[:k] {
+		msgs3 = append(msgs3, result.(*Round3Message))
+	}
+	s, err = msgs3.join(c.meta)
+	if err != nil {
+		return
+	}
+	pk := &ecdsa.PublicKey{Curve: c.meta.Curve(), X: c.y.X, Y: c.y.Y}
+	if !ecdsa.Verify(pk, h, r, s) {
+		err = fmt.Errorf("signature verification failed")
+		return
+	}
+	return
+}
+
+// member is a participant of a session, with its position in the participant list given to Sign.
+type member struct {
+	index int
+	Participant
+}
+
+// SessionError is returned by Coordinator.Sign when less than K participants answered a round without errors.
+type SessionError struct {
+	Round    string        // Round that failed
+	Accepted int           // Number of participants that answered the round without errors
+	Needed   int           // Number of participants needed (K)
+	Errors   map[int]error // Errors of the participants, by their position in the participant list
+}
+
+// Error returns the round that failed and the errors of the participants, sorted by position.
+func (e *SessionError) Error() string {
+	indexes := make([]int, 0, len(e.Errors))
+	for i := range e.Errors {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	errs := make([]string, len(indexes))
+	for j, i := range indexes {
+		errs[j] = fmt.Sprintf("participant %d: %s", i, e.Errors[i])
+	}
+	return fmt.Sprintf("%s: only %d participants answered, but at least K (%d) are needed (%s)", e.Round, e.Accepted, e.Needed, strings.Join(errs, "; "))
+}
+
+// Denials returns the policy errors of the participants that denied the request, by their position in the participant list.
+func (e *SessionError) Denials() map[int]*PolicyError {
+	denials := make(map[int]*PolicyError)
+	for i, err := range e.Errors {
+		var denial *PolicyError
+		if errors.As(err, &denial) {
+			denials[i] = denial
+		}
+	}
+	return denials
+}
+
+// round calls every member at the same time and waits until all of them answer, or until the round
+// timeout. It returns the members that answered without errors, sorted as in the input, with their results.
+// It returns a *SessionError if less than K members answered without errors.
+func (c *Coordinator) round(ctx context.Context, name string, members []member, call func(ctx context.Context, p Participant) (interface{}, error)) (accepted []member, results []interface{}, err error) {
+	timeout := c.RoundTimeout
+	if timeout <= 0 {
+		timeout = DefaultRoundTimeout
+	}
+	roundCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	type answer struct {
+		i      int
+		result interface{}
+		err    error
+	}
+	answers := make(chan *answer, len(members))
+	for i, m := range members {
+		go func(i int, p Participant) {
+			result, err := call(roundCtx, p)
+			answers <- &answer{i, result, err}
+		}(i, m.Participant)
+	}
+	received := make([]*answer, len(members))
+	for pending := len(members); pending > 0; pending-- {
+		select {
+		case a := <-answers:
+			received[a.i] = a
+		case <-roundCtx.Done():
+			pending = 0
+		}
+	}
+	errs := make(map[int]error)
+	for i, a := range received {
+		if a == nil {
+			errs[members[i].index] = fmt.Errorf("did not answer before timeout")
+			continue
+		}
+		if a.err != nil {
+			errs[members[i].index] = a.err
+			continue
+		}
+		accepted = append(accepted, members[i])
+		results = append(results, a.result)
+	}
+	if k := int(c.meta.Paillier.K); len(accepted) < k {
+		err = &SessionError{
+			Round:    name,
+			Accepted: len(accepted),
+			Needed:   k,
+			Errors:   errs,
+		}
+		return
+	}
+	return
+}
+
+// LocalParticipant is a Participant that runs a signing session with a key share in the same process.
+// If its Policy is set, it must approve every request before Round1.
+// It trusts the Coordinator of the same process, so the proofs of the message lists it receives on Round2
+// and Round3, which the coordinator has already verified, are not verified again.
+type LocalParticipant struct {
+	Policy Policy
+	share  *KeyShare
+	meta   *KeyMeta
+	state  *SigSession
+}
+
+// NewLocalParticipant returns a new participant that signs with the key share.
+func NewLocalParticipant(share *KeyShare, meta *KeyMeta) *LocalParticipant {
+	return &LocalParticipant{
+		share: share,
+		meta:  meta,
+	}
+}
+
+// Begin starts a signing session for the request, setting the participant policy on it.
+func (p *LocalParticipant) Begin(_ context.Context, req *SignRequest) (err error) {
+	state, err := p.share.NewSigSession(p.meta, req.Digest)
+	if err != nil {
+		return
+	}
+	if p.Policy != nil {
+		if err = state.SetPolicy(p.Policy, req); err != nil {
+			return
+		}
+	}
+	p.state = state
+	return
+}
+
+// Round1 executes the first round of the signing session.
+func (p *LocalParticipant) Round1(_ context.Context) (*Round1Message, error) {
+	if p.state == nil {
+		return nil, fmt.Errorf("session has not begun")
+	}
+	return p.state.Round1()
+}
+
+// Round2 executes the second round of the signing session, with Round1 messages verified by the coordinator.
+func (p *LocalParticipant) Round2(_ context.Context, msgs Round1MessageList) (msg *Round2Message, err error) {
+	defer startSpan(SpanRound2).end(&err)
+	if p.state == nil {
+		return nil, fmt.Errorf("session has not begun")
+	}
+	return p.state.round2(msgs, true)
+}
+
+// Round3 executes the third round of the signing session, with Round2 messages verified by the coordinator.
+func (p *LocalParticipant) Round3(_ context.Context, msgs Round2MessageList) (msg *Round3Message, err error) {
+	defer startSpan(SpanRound3).end(&err)
+	if p.state == nil {
+		return nil, fmt.Errorf("session has not begun")
+	}
+	return p.state.round3(msgs, true)
+}
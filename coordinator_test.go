@@ -0,0 +1,222 @@
+package tcecdsa_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"fmt"
+	"github.com/niclabs/tcecdsa"
+	"github.com/niclabs/tcpaillier"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// failingParticipant is a participant that returns an error on Round1.
+type failingParticipant struct {
+	tcecdsa.Participant
+}
+
+func (p *failingParticipant) Round1(_ context.Context) (*tcecdsa.Round1Message, error) {
+	return nil, fmt.Errorf("participant is down")
+}
+
+// stalledParticipant is a participant that does not answer until the context is done.
+type stalledParticipant struct {
+	tcecdsa.Participant
+}
+
+func (p *stalledParticipant) Begin(ctx context.Context, _ *tcecdsa.SignRequest) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestCoordinator_Sign(t *testing.T) {
+	params := &tcecdsa.NewKeyParams{
+		PaillierFixed: &tcpaillier.FixedParams{
+			P:  p256,
+			P1: p1256,
+			Q:  q256,
+			Q1: q1256,
+		},
+	}
+	shares, keyMeta, err := tcecdsa.NewKey(L, K, "P-256", params)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	pk, err := initKey(shares, keyMeta)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	coordinator, err := tcecdsa.NewCoordinator(keyMeta, &tcecdsa.KeyShare{Alpha: shares[0].Alpha, Y: shares[0].Y})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	h := sha256.Sum256([]byte("hello world"))
+	req := &tcecdsa.SignRequest{KeyID: "test", Digest: h[:], Requester: "alice"}
+
+	t.Run("AllParticipants", func(t *testing.T) {
+		participants := make([]tcecdsa.Participant, 0)
+		for _, share := range shares {
+			participants = append(participants, tcecdsa.NewLocalParticipant(share, keyMeta))
+		}
+		r, s, err := coordinator.Sign(context.Background(), req, participants)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if !ecdsa.Verify(pk, h[:], r, s) {
+			t.Error("signature verification failed")
+			return
+		}
+	})
+
+	t.Run("ProofsVerifiedOnce", func(t *testing.T) {
+		participants := make([]tcecdsa.Participant, 0)
+		for _, share := range shares {
+			participants = append(participants, tcecdsa.NewLocalParticipant(share, keyMeta))
+		}
+		instr := newRecordingInstrumentation()
+		tcecdsa.SetInstrumentation(instr)
+		defer tcecdsa.SetInstrumentation(nil)
+		if _, _, err := coordinator.Sign(context.Background(), req, participants); err != nil {
+			t.Error(err)
+			return
+		}
+		if instr.spans[tcecdsa.SpanVerifySigProof] != L || instr.spans[tcecdsa.SpanVerifyDecryptProofs] != 2*L {
+			t.Errorf("every proof should be verified once, but spans are %v", instr.spans)
+			return
+		}
+	})
+
+	t.Run("ExcludedParticipants", func(t *testing.T) {
+		participants := make([]tcecdsa.Participant, 0)
+		for _, share := range shares {
+			participants = append(participants, tcecdsa.NewLocalParticipant(share, keyMeta))
+		}
+		participants[0] = &stalledParticipant{participants[0]}
+		participants[3] = &failingParticipant{participants[3]}
+		coordinator.RoundTimeout = 20 * time.Second
+		r, s, err := coordinator.Sign(context.Background(), req, participants)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if !ecdsa.Verify(pk, h[:], r, s) {
+			t.Error("signature verification failed")
+			return
+		}
+	})
+
+	t.Run("NotEnoughParticipants", func(t *testing.T) {
+		participants := make([]tcecdsa.Participant, 0)
+		for i, share := range shares {
+			var participant tcecdsa.Participant = tcecdsa.NewLocalParticipant(share, keyMeta)
+			if i < L-K+1 {
+				participant = &failingParticipant{participant}
+			}
+			participants = append(participants, participant)
+		}
+		if _, _, err := coordinator.Sign(context.Background(), req, participants); err == nil {
+			t.Error("signing should fail with less than K participants")
+			return
+		}
+	})
+
+	t.Run("DeniedByPolicy", func(t *testing.T) {
+		participants := make([]tcecdsa.Participant, 0)
+		for _, share := range shares {
+			participant := tcecdsa.NewLocalParticipant(share, keyMeta)
+			participant.Policy = tcecdsa.AllowRequesters("alice")
+			participants = append(participants, participant)
+		}
+		denied := &tcecdsa.SignRequest{KeyID: "test", Digest: h[:], Requester: "mallory"}
+		_, _, err := coordinator.Sign(context.Background(), denied, participants)
+		sessionErr, ok := err.(*tcecdsa.SessionError)
+		if !ok {
+			t.Errorf("error should be a session error, but it is %v", err)
+			return
+		}
+		denials := sessionErr.Denials()
+		if len(denials) != L {
+			t.Errorf("%d participants should deny the request, but %d did", L, len(denials))
+			return
+		}
+		for i, denial := range denials {
+			if denial.Rule != "requester allow-list" {
+				t.Errorf("participant %d denied the request with an unexpected rule: %s", i, denial)
+				return
+			}
+		}
+	})
+
+	t.Run("DeniedSession", func(t *testing.T) {
+		states := make([]*tcecdsa.SigSession, 0)
+		for _, share := range shares {
+			state, err := share.NewSigSession(keyMeta, h[:])
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			states = append(states, state)
+		}
+		evaluations := 0
+		deny := tcecdsa.PolicyFunc(func(req *tcecdsa.SignRequest) error {
+			evaluations++
+			return &tcecdsa.PolicyError{Rule: "deny", Reason: "denied by test"}
+		})
+		if err := states[2].SetPolicy(deny, &tcecdsa.SignRequest{Digest: h[:]}); err != nil {
+			t.Error(err)
+			return
+		}
+		round1Messages := make(tcecdsa.Round1MessageList, 0)
+		for i, state := range states {
+			msg, err := state.Round1()
+			if i == 2 {
+				if err == nil {
+					t.Error("session with a deny policy should fail on Round1")
+					return
+				}
+				continue
+			}
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			round1Messages = append(round1Messages, msg)
+		}
+		if _, err := states[2].Round1(); err == nil || evaluations != 1 {
+			t.Errorf("denied session should not evaluate its policy again, but it was evaluated %d times", evaluations)
+			return
+		}
+		if _, err := states[2].Round2(round1Messages); err == nil {
+			t.Error("denied session should reject Round2")
+			return
+		}
+	})
+
+	t.Run("PayloadDigest", func(t *testing.T) {
+		tx := &tcecdsa.LegacyTx{ChainID: big.NewInt(1), To: make([]byte, 20), Value: big.NewInt(1)}
+		state, err := shares[0].NewSigSession(keyMeta, h[:])
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if err := state.SetPolicy(tcecdsa.AllowRequesters("alice"), &tcecdsa.SignRequest{Payload: tx, Requester: "alice"}); err == nil {
+			t.Error("request with a payload that does not hash to the session hash should be rejected")
+			return
+		}
+		state, err = shares[0].NewSigSession(keyMeta, tx.SigningHash())
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if err := state.SetPolicy(tcecdsa.AllowRequesters("alice"), &tcecdsa.SignRequest{Payload: tx, Requester: "alice"}); err != nil {
+			t.Error(err)
+			return
+		}
+	})
+}
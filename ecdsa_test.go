@@ -26,16 +26,21 @@ var p1, _ = new(big.Int).SetString("24092157799367390962023561650940929122014419
 var q, _ = new(big.Int).SetString("412869555449418513088610723546515649758113679263497461143281964942533362549552387321171889833327035679850480855648596001543734177165640501544385552969893524814969820967880299485349586412916667152003711405804484319055891168516277276341589468276521027239786422345706510127", 10)
 var q1, _ = new(big.Int).SetString("206434777724709256544305361773257824879056839631748730571640982471266681274776193660585944916663517839925240427824298000771867088582820250772192776484946762407484910483940149742674793206458333576001855702902242159527945584258138638170794734138260513619893211172853255063", 10)
 
-func TestNewKey(t *testing.T) {
-	params := &tcecdsa.NewKeyParams{
-		PaillierFixed: &tcpaillier.FixedParams{
-			P:  p,
-			P1: p1,
-			Q:  q,
-			Q1: q1,
-		},
-	}
+// Keys for curve_bitsize <= 256 (2048 bits, because paillier is 8*curve_bitsize)
+var p256, _ = new(big.Int).SetString("137537413420762547650493449893260858917800237153163144023736310003651436274145088983133654061063082464994185960314187752168804446390815366271979811577969538698776031062862677455983392561329766291346225778385930191175906902426248344178361969094907274847853172862307239782873996608540554379871730524796395787247", 10)
+var p1256, _ = new(big.Int).SetString("68768706710381273825246724946630429458900118576581572011868155001825718137072544491566827030531541232497092980157093876084402223195407683135989905788984769349388015531431338727991696280664883145673112889192965095587953451213124172089180984547453637423926586431153619891436998304270277189935865262398197893623", 10)
+var q256, _ = new(big.Int).SetString("155023296754007244089528221677469556667119528650199642609409577564109526126683690043302787241624003158964758573520637795429011770537460107983462221613420743461825559166142342076795339979845533939313389392767803764678383759133574074970235524519083017279806360059710695964336383022336657693956448087509984830363", 10)
+var q1256, _ = new(big.Int).SetString("77511648377003622044764110838734778333559764325099821304704788782054763063341845021651393620812001579482379286760318897714505885268730053991731110806710371730912779583071171038397669989922766969656694696383901882339191879566787037485117762259541508639903180029855347982168191511168328846978224043754992415181", 10)
+
+// paillierParams are the fixed Paillier parameters of the test keys on each curve.
+var paillierParams = map[string]*tcpaillier.FixedParams{
+	"P-224":     {P: p, P1: p1, Q: q, Q1: q1},
+	"P-256":     {P: p256, P1: p1256, Q: q256, Q1: q1256},
+	"secp256k1": {P: p256, P1: p1256, Q: q256, Q1: q1256},
+}
 
+func TestNewKey(t *testing.T) {
+	params := &tcecdsa.NewKeyParams{PaillierFixed: paillierParams[Curve]}
 	shares, keyMeta, err := tcecdsa.NewKey(L, K, Curve, params)
 	if err != nil {
 		t.Error(err)
@@ -151,4 +156,92 @@ func TestNewKey(t *testing.T) {
 			}
 		}
 	})
+
+	t.Run("LowS", func(t *testing.T) {
+		r, s, err := states[0].GetLowSSignature(round3Messages)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if err := tcecdsa.VerifyCanonical(pk, h, r, s); err != nil {
+			t.Error(err)
+			return
+		}
+	})
+}
+
+// newKey creates a key with L shares and threshold K on the curve, using its fixed Paillier parameters,
+// and runs the key initialization protocol between the shares.
+func newKey(curve string) (shares []*tcecdsa.KeyShare, keyMeta *tcecdsa.KeyMeta, pk *ecdsa.PublicKey, err error) {
+	params := &tcecdsa.NewKeyParams{PaillierFixed: paillierParams[curve]}
+	if shares, keyMeta, err = tcecdsa.NewKey(L, K, curve, params); err != nil {
+		return
+	}
+	pk, err = initKey(shares, keyMeta)
+	return
+}
+
+// initKey runs the key initialization protocol between all the shares and returns the public key.
+func initKey(shares []*tcecdsa.KeyShare, keyMeta *tcecdsa.KeyMeta) (pk *ecdsa.PublicKey, err error) {
+	keyInitMessages := make(tcecdsa.KeyInitMessageList, 0)
+	for _, share := range shares {
+		keyInitMessage, err := share.Init(keyMeta)
+		if err != nil {
+			return nil, err
+		}
+		keyInitMessages = append(keyInitMessages, keyInitMessage)
+	}
+	for _, share := range shares {
+		if err := share.SetKey(keyMeta, keyInitMessages); err != nil {
+			return nil, err
+		}
+	}
+	return keyMeta.GetPublicKey(keyInitMessages)
+}
+
+// sign runs the signing protocol between all the shares and returns the signature of the first one.
+func sign(shares []*tcecdsa.KeyShare, keyMeta *tcecdsa.KeyMeta, h []byte) (r, s *big.Int, err error) {
+	states := make([]*tcecdsa.SigSession, 0)
+	for _, share := range shares {
+		state, err := share.NewSigSession(keyMeta, h)
+		if err != nil {
+			return nil, nil, err
+		}
+		states = append(states, state)
+	}
+	round3Messages, err := runRounds(states)
+	if err != nil {
+		return
+	}
+	return states[0].GetSignature(round3Messages)
+}
+
+// runRounds runs the three rounds of the signing protocol between the sessions and returns
+// the Round3 messages, needed to get the signature.
+func runRounds(states []*tcecdsa.SigSession) (round3Messages tcecdsa.Round3MessageList, err error) {
+	round1Messages := make(tcecdsa.Round1MessageList, 0)
+	round2Messages := make(tcecdsa.Round2MessageList, 0)
+	round3Messages = make(tcecdsa.Round3MessageList, 0)
+	for _, state := range states {
+		msg, err := state.Round1()
+		if err != nil {
+			return nil, err
+		}
+		round1Messages = append(round1Messages, msg)
+	}
+	for _, state := range states {
+		msg, err := state.Round2(round1Messages)
+		if err != nil {
+			return nil, err
+		}
+		round2Messages = append(round2Messages, msg)
+	}
+	for _, state := range states {
+		msg, err := state.Round3(round2Messages)
+		if err != nil {
+			return nil, err
+		}
+		round3Messages = append(round3Messages, msg)
+	}
+	return
 }
@@ -0,0 +1,201 @@
+package tcecdsa
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// envelopeDomain is prepended to the signed data of every envelope, so the identity keys cannot be
+// used to sign other kinds of data.
+const envelopeDomain = "tcecdsa envelope v1"
+
+// MessageRound identifies the protocol step of a message wrapped in an Envelope.
+type MessageRound uint8
+
+// The following consts represent the protocol steps whose messages can be wrapped in an Envelope.
+const (
+	KeyInitRound     MessageRound = iota + 1 // KeyInitMessage
+	SigRound1                                // Round1Message
+	SigRound2                                // Round2Message
+	SigRound3                                // Round3Message
+	SchnorrSigRound2                         // SchnorrRound2Message
+	SchnorrSigCommit                         // SchnorrCommitMessage
+)
+
+// Identity represents the long-term identity key of a participant, used to sign the envelopes it sends.
+type Identity struct {
+	Index      uint8              // Participant Index
+	PrivateKey ed25519.PrivateKey // Ed25519 identity private key
+}
+
+// Roster maps the participant indexes to their identity public keys. It is used to open envelopes.
+type Roster map[uint8]ed25519.PublicKey
+
+// Envelope is a signed protocol message. The signature covers the sender index, the session ID, the round
+// and the JSON encoded payload, so a message cannot be injected by a party outside the roster or replayed
+// on another session or round.
+type Envelope struct {
+	Sender    uint8        // Index of the participant who sent the message
+	SessionID string       // ID of the session (or key generation) the message belongs to
+	Round     MessageRound // Protocol step of the message
+	Payload   []byte       // JSON encoded message
+	Signature []byte       // Ed25519 signature of the envelope, using the identity key of the sender
+}
+
+// NewIdentity returns a new random identity for the participant with the given index.
+func NewIdentity(index uint8) (*Identity, error) {
+	_, sk, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &Identity{
+		Index:      index,
+		PrivateKey: sk,
+	}, nil
+}
+
+// PublicKey returns the identity public key, which must be added to the roster of the other participants.
+func (id *Identity) PublicKey() ed25519.PublicKey {
+	return id.PrivateKey.Public().(ed25519.PublicKey)
+}
+
+// Seal wraps a protocol message in an envelope signed with the identity key.
+func (id *Identity) Seal(sessionID string, round MessageRound, msg interface{}) (env *Envelope, err error) {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	env = &Envelope{
+		Sender:    id.Index,
+		SessionID: sessionID,
+		Round:     round,
+		Payload:   payload,
+	}
+	env.Signature = ed25519.Sign(id.PrivateKey, env.signedData())
+	return
+}
+
+// signedData returns the data covered by the envelope signature.
+func (env *Envelope) signedData() []byte {
+	var b bytes.Buffer
+	b.WriteString(envelopeDomain)
+	b.WriteByte(env.Sender)
+	length := make([]byte, 4)
+	binary.BigEndian.PutUint32(length, uint32(len(env.SessionID)))
+	b.Write(length)
+	b.WriteString(env.SessionID)
+	b.WriteByte(byte(env.Round))
+	binary.BigEndian.PutUint32(length, uint32(len(env.Payload)))
+	b.Write(length)
+	b.Write(env.Payload)
+	return b.Bytes()
+}
+
+// Open verifies an envelope against the roster and decodes its payload into msg. It returns an error if the
+// sender is not in the roster, if the session ID or round are not the expected ones, or if the signature is
+// not valid.
+func (roster Roster) Open(env *Envelope, sessionID string, round MessageRound, msg interface{}) error {
+	pk, ok := roster[env.Sender]
+	if !ok {
+		return fmt.Errorf("sender %d is not in the roster", env.Sender)
+	}
+	if env.SessionID != sessionID {
+		return fmt.Errorf("envelope session is %s, but it should be %s", env.SessionID, sessionID)
+	}
+	if env.Round != round {
+		return fmt.Errorf("envelope round is %d, but it should be %d", env.Round, round)
+	}
+	if len(pk) != ed25519.PublicKeySize || !ed25519.Verify(pk, env.signedData(), env.Signature) {
+		return fmt.Errorf("invalid signature from sender %d", env.Sender)
+	}
+	return json.Unmarshal(env.Payload, msg)
+}
+
+// openAll opens a list of envelopes of the same session and round, calling newMsg to obtain the value where
+// each payload is decoded. The envelopes are opened in sender order, so every participant obtains the same
+// message list, and selects the same messages when joining it, regardless of the order the envelopes arrived.
+// It returns an error if any envelope is not valid or if a sender is repeated.
+func (roster Roster) openAll(envs []*Envelope, sessionID string, round MessageRound, newMsg func() interface{}) error {
+	sorted := append([]*Envelope{}, envs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Sender < sorted[j].Sender
+	})
+	senders := make(map[uint8]bool)
+	for _, env := range sorted {
+		if senders[env.Sender] {
+			return fmt.Errorf("sender %d sent more than one envelope", env.Sender)
+		}
+		senders[env.Sender] = true
+		if err := roster.Open(env, sessionID, round, newMsg()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OpenKeyInit opens a list of envelopes with KeyInitMessages, which can be used in KeyShare.SetKey.
+func (roster Roster) OpenKeyInit(envs []*Envelope, sessionID string) (msgs KeyInitMessageList, err error) {
+	err = roster.openAll(envs, sessionID, KeyInitRound, func() interface{} {
+		msg := &KeyInitMessage{}
+		msgs = append(msgs, msg)
+		return msg
+	})
+	return
+}
+
+// OpenRound1 opens a list of envelopes with Round1Messages, which can be used in SigSession.Round2.
+func (roster Roster) OpenRound1(envs []*Envelope, sessionID string) (msgs Round1MessageList, err error) {
+	err = roster.openAll(envs, sessionID, SigRound1, func() interface{} {
+		msg := &Round1Message{}
+		msgs = append(msgs, msg)
+		return msg
+	})
+	return
+}
+
+// OpenRound2 opens a list of envelopes with Round2Messages, which can be used in SigSession.Round3.
+func (roster Roster) OpenRound2(envs []*Envelope, sessionID string) (msgs Round2MessageList, err error) {
+	err = roster.openAll(envs, sessionID, SigRound2, func() interface{} {
+		msg := &Round2Message{}
+		msgs = append(msgs, msg)
+		return msg
+	})
+	return
+}
+
+// OpenRound3 opens a list of envelopes with Round3Messages, which can be used in SigSession.GetSignature.
+func (roster Roster) OpenRound3(envs []*Envelope, sessionID string) (msgs Round3MessageList, err error) {
+	err = roster.openAll(envs, sessionID, SigRound3, func() interface{} {
+		msg := &Round3Message{}
+		msgs = append(msgs, msg)
+		return msg
+	})
+	return
+}
+
+// OpenSchnorrCommit opens a list of envelopes with SchnorrCommitMessages, which can be used in
+// SchnorrSession.Round1.
+func (roster Roster) OpenSchnorrCommit(envs []*Envelope, sessionID string) (msgs SchnorrCommitMessageList, err error) {
+	err = roster.openAll(envs, sessionID, SchnorrSigCommit, func() interface{} {
+		msg := &SchnorrCommitMessage{}
+		msgs = append(msgs, msg)
+		return msg
+	})
+	return
+}
+
+// OpenSchnorrRound2 opens a list of envelopes with SchnorrRound2Messages, which can be used in
+// SchnorrSession.GetSignature.
+func (roster Roster) OpenSchnorrRound2(envs []*Envelope, sessionID string) (msgs SchnorrRound2MessageList, err error) {
+	err = roster.openAll(envs, sessionID, SchnorrSigRound2, func() interface{} {
+		msg := &SchnorrRound2Message{}
+		msgs = append(msgs, msg)
+		return msg
+	})
+	return
+}
@@ -0,0 +1,218 @@
+package tcecdsa_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"github.com/niclabs/tcecdsa"
+	"github.com/niclabs/tcpaillier"
+	"testing"
+)
+
+func TestRoster_Open(t *testing.T) {
+	params := &tcecdsa.NewKeyParams{
+		PaillierFixed: &tcpaillier.FixedParams{
+			P:  p256,
+			P1: p1256,
+			Q:  q256,
+			Q1: q1256,
+		},
+	}
+	shares, keyMeta, err := tcecdsa.NewKey(L, K, "P-256", params)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	identities := make([]*tcecdsa.Identity, 0)
+	roster := make(tcecdsa.Roster)
+	for _, share := range shares {
+		id, err := tcecdsa.NewIdentity(share.Index)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		identities = append(identities, id)
+		roster[id.Index] = id.PublicKey()
+	}
+
+	// Key initialization
+	keyEnvs := make([]*tcecdsa.Envelope, 0)
+	for i, share := range shares {
+		msg, err := share.Init(keyMeta)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		env, err := identities[i].Seal("keygen", tcecdsa.KeyInitRound, msg)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		keyEnvs = append(keyEnvs, env)
+	}
+	keyInitMsgs, err := roster.OpenKeyInit(keyEnvs, "keygen")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for _, share := range shares {
+		if err := share.SetKey(keyMeta, keyInitMsgs); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	pk, err := keyMeta.GetPublicKey(keyInitMsgs)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Signing
+	h := sha256.Sum256([]byte("hello world"))
+	states := make([]*tcecdsa.SigSession, 0)
+	for _, share := range shares {
+		state, err := share.NewSigSession(keyMeta, h[:])
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		states = append(states, state)
+	}
+	round1Envs := make([]*tcecdsa.Envelope, 0)
+	for i, state := range states {
+		msg, err := state.Round1()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		env, err := identities[i].Seal("session", tcecdsa.SigRound1, msg)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		round1Envs = append(round1Envs, env)
+	}
+	round1Msgs, err := roster.OpenRound1(round1Envs, "session")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	round2Envs := make([]*tcecdsa.Envelope, 0)
+	for i, state := range states {
+		msg, err := state.Round2(round1Msgs)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		env, err := identities[i].Seal("session", tcecdsa.SigRound2, msg)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		round2Envs = append(round2Envs, env)
+	}
+	round2Msgs, err := roster.OpenRound2(round2Envs, "session")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	round3Envs := make([]*tcecdsa.Envelope, 0)
+	for i, state := range states {
+		msg, err := state.Round3(round2Msgs)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		env, err := identities[i].Seal("session", tcecdsa.SigRound3, msg)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		round3Envs = append(round3Envs, env)
+	}
+	round3Msgs, err := roster.OpenRound3(round3Envs, "session")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	r, s, err := states[0].GetSignature(round3Msgs)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !ecdsa.Verify(pk, h[:], r, s) {
+		t.Error("signature verification failed")
+		return
+	}
+
+	t.Run("WrongSession", func(t *testing.T) {
+		if _, err := roster.OpenRound1(round1Envs, "other"); err == nil {
+			t.Error("envelopes from another session should not be opened")
+			return
+		}
+	})
+
+	t.Run("WrongRound", func(t *testing.T) {
+		if _, err := roster.OpenRound2(round1Envs, "session"); err == nil {
+			t.Error("envelopes from another round should not be opened")
+			return
+		}
+	})
+
+	t.Run("TamperedPayload", func(t *testing.T) {
+		env := *round1Envs[1]
+		env.Payload = append([]byte{}, env.Payload...)
+		env.Payload[len(env.Payload)-2] ^= 1
+		if err := roster.Open(&env, "session", tcecdsa.SigRound1, &tcecdsa.Round1Message{}); err == nil {
+			t.Error("tampered envelope should not be opened")
+			return
+		}
+	})
+
+	t.Run("UnknownSender", func(t *testing.T) {
+		outsider, err := tcecdsa.NewIdentity(L)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		env, err := outsider.Seal("session", tcecdsa.SigRound1, round1Msgs[0])
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if err := roster.Open(env, "session", tcecdsa.SigRound1, &tcecdsa.Round1Message{}); err == nil {
+			t.Error("envelope from a sender outside the roster should not be opened")
+			return
+		}
+		env.Sender = 0
+		if err := roster.Open(env, "session", tcecdsa.SigRound1, &tcecdsa.Round1Message{}); err == nil {
+			t.Error("envelope impersonating a sender should not be opened")
+			return
+		}
+	})
+
+	t.Run("ArrivalOrder", func(t *testing.T) {
+		reversed := make([]*tcecdsa.Envelope, 0, len(round1Envs))
+		for i := len(round1Envs) - 1; i >= 0; i-- {
+			reversed = append(reversed, round1Envs[i])
+		}
+		msgs, err := roster.OpenRound1(reversed, "session")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		for i, msg := range msgs {
+			if msg.Ri.Cmp(round1Msgs[i].Ri) != 0 {
+				t.Errorf("message %d is not sorted by sender", i)
+				return
+			}
+		}
+	})
+
+	t.Run("RepeatedSender", func(t *testing.T) {
+		envs := append([]*tcecdsa.Envelope{round1Envs[0]}, round1Envs...)
+		if _, err := roster.OpenRound1(envs, "session"); err == nil {
+			t.Error("envelopes with a repeated sender should not be opened")
+			return
+		}
+	})
+}
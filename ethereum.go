@@ -0,0 +1,112 @@
+package tcecdsa
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"fmt"
+	"golang.org/x/crypto/sha3"
+	"math/big"
+	"strings"
+)
+
+// EthereumAddressSize is the size in bytes of an Ethereum address.
+const EthereumAddressSize = 20
+
+// Keccak256 returns the legacy Keccak-256 hash of the concatenation of data, as used by Ethereum.
+func Keccak256(data ...[]byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	for _, b := range data {
+		h.Write(b)
+	}
+	return h.Sum(nil)
+}
+
+// EthereumAddress returns the 20 bytes Ethereum address of a secp256k1 public key, defined as the
+// last 20 bytes of the Keccak-256 hash of its uncompressed coordinates.
+func EthereumAddress(pk *ecdsa.PublicKey) []byte {
+	coords := append(fieldBytes(pk.X), fieldBytes(pk.Y)...)
+	return Keccak256(coords)[32-EthereumAddressSize:]
+}
+
+// EthereumAddressHex returns the EIP-55 mixed-case checksum hex encoding of an Ethereum address,
+// including the 0x prefix.
+func EthereumAddressHex(address []byte) string {
+	lower := hex.EncodeToString(address)
+	hash := Keccak256([]byte(lower))
+	encoded := []byte(lower)
+	for i, c := range encoded {
+		if c < 'a' {
+			continue // digits are not capitalized
+		}
+		nibble := hash[i/2] >> 4
+		if i%2 == 1 {
+			nibble = hash[i/2] & 0x0f
+		}
+		if nibble >= 8 {
+			encoded[i] = c - 'a' + 'A'
+		}
+	}
+	return "0x" + string(encoded)
+}
+
+// ParseEthereumAddress parses a hex encoded Ethereum address, with or without 0x prefix.
+// The checksum is verified only if the address has mixed case.
+func ParseEthereumAddress(s string) (address []byte, err error) {
+	s = strings.TrimPrefix(s, "0x")
+	address, err = hex.DecodeString(s)
+	if err != nil {
+		return
+	}
+	if len(address) != EthereumAddressSize {
+		err = fmt.Errorf("address should be %d bytes long, but it is %d", EthereumAddressSize, len(address))
+		return
+	}
+	if s != strings.ToLower(s) && s != strings.ToUpper(s) && "0x"+s != EthereumAddressHex(address) {
+		err = fmt.Errorf("invalid address checksum")
+		return
+	}
+	return
+}
+
+// RecoverPublicKey returns the secp256k1 public key that generated a signature (r, s) with recovery id v
+// over a hash h.
+func RecoverPublicKey(h []byte, r, s *big.Int, v byte) (pk *ecdsa.PublicKey, err error) {
+	curve := Secp256k1()
+	n := curve.Params().N
+	if v > 3 {
+		err = fmt.Errorf("recovery id should be between 0 and 3, but it is %d", v)
+		return
+	}
+	if r.Sign() <= 0 || r.Cmp(n) >= 0 || s.Sign() <= 0 || s.Cmp(n) >= 0 {
+		err = fmt.Errorf("r and s should be between 1 and curve order")
+		return
+	}
+	x := new(big.Int).Set(r)
+	if v&2 != 0 {
+		x.Add(x, n)
+	}
+	R, err := liftX(x)
+	if err != nil {
+		return
+	}
+	if R.Y.Bit(0) != uint(v&1) {
+		R.Y.Sub(secp256k1.P, R.Y)
+	}
+	// Q = r^-1 (s*R - e*G)
+	rInv := new(big.Int).ModInverse(r, n)
+	e := HashToInt(h, curve)
+	minusE := new(big.Int).Sub(n, e.Mod(e, n))
+	sR := NewZero().Mul(curve, R, s)
+	eG := NewZero().BaseMul(curve, minusE)
+	q := NewZero().Mul(curve, NewZero().Add(curve, sR, eG), rInv)
+	if q.X.Sign() == 0 && q.Y.Sign() == 0 {
+		err = fmt.Errorf("recovered public key is the point at infinity")
+		return
+	}
+	pk = &ecdsa.PublicKey{
+		Curve: curve,
+		X:     q.X,
+		Y:     q.Y,
+	}
+	return
+}
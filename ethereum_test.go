@@ -0,0 +1,142 @@
+package tcecdsa_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"github.com/niclabs/tcecdsa"
+	"github.com/niclabs/tcpaillier"
+	"math/big"
+	"testing"
+)
+
+// Values taken from EIP-155 example.
+var eip155Tx = &tcecdsa.LegacyTx{
+	ChainID:  big.NewInt(1),
+	Nonce:    9,
+	GasPrice: big.NewInt(20000000000),
+	Gas:      21000,
+	To:       bytes.Repeat([]byte{0x35}, 20),
+	Value:    new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil),
+}
+
+const eip155SigningHash = "daf5a779ae972f972197303d7b574746c7ef83eadac0f2791ad23db92e4c8e53"
+const eip155R = "18515461264373351373200002665853028612451056578545711640558177340181847433846"
+const eip155S = "46948507304638947509940763649030358759909902576025900602547168820602576006531"
+const eip155Signed = "f86c098504a817c800825208943535353535353535353535353535353535353535880de0b6b3a76400008025a028ef61340bd939bc2195fe537567866003e1a15d3c71ff63e1590620aa636276a067cbe9d8997f761aecb703304b3800ccf555c9f3dc64214b297fb1966a3b6d83"
+const eip155Sender = "0x9d8A62f656a8d1615C1294fd71e9CFb3E4855A4F"
+
+func TestKeccak256(t *testing.T) {
+	expected := "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470"
+	if h := hex.EncodeToString(tcecdsa.Keccak256()); h != expected {
+		t.Errorf("hash of empty string should be %s, but it is %s", expected, h)
+	}
+}
+
+func TestLegacyTx_EncodeSigned(t *testing.T) {
+	h := eip155Tx.SigningHash()
+	if hex.EncodeToString(h) != eip155SigningHash {
+		t.Errorf("signing hash should be %s, but it is %x", eip155SigningHash, h)
+		return
+	}
+	r, _ := new(big.Int).SetString(eip155R, 10)
+	s, _ := new(big.Int).SetString(eip155S, 10)
+	if raw := hex.EncodeToString(eip155Tx.EncodeSigned(r, s, 0)); raw != eip155Signed {
+		t.Errorf("signed transaction should be %s, but it is %s", eip155Signed, raw)
+		return
+	}
+	pk, err := tcecdsa.RecoverPublicKey(h, r, s, 0)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if address := tcecdsa.EthereumAddressHex(tcecdsa.EthereumAddress(pk)); address != eip155Sender {
+		t.Errorf("sender should be %s, but it is %s", eip155Sender, address)
+		return
+	}
+	if _, err := tcecdsa.ParseEthereumAddress(eip155Sender); err != nil {
+		t.Error(err)
+		return
+	}
+}
+
+func TestEthereumTxSession(t *testing.T) {
+	params := &tcecdsa.NewKeyParams{
+		PaillierFixed: &tcpaillier.FixedParams{
+			P:  p256,
+			P1: p1256,
+			Q:  q256,
+			Q1: q1256,
+		},
+	}
+	shares, keyMeta, err := tcecdsa.NewKey(L, K, SchnorrCurve, params)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	pk, err := initKey(shares, keyMeta)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	tx := &tcecdsa.DynamicFeeTx{
+		ChainID:   big.NewInt(1),
+		Nonce:     1,
+		GasTipCap: big.NewInt(2000000000),
+		GasFeeCap: big.NewInt(30000000000),
+		Gas:       21000,
+		To:        bytes.Repeat([]byte{0x35}, 20),
+		Value:     big.NewInt(1000000000000000),
+	}
+	states := make([]*tcecdsa.EthereumTxSession, 0)
+	sessions := make([]*tcecdsa.SigSession, 0)
+	for _, share := range shares {
+		state, err := share.NewEthereumTxSession(keyMeta, tx)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		states = append(states, state)
+		sessions = append(sessions, state.SigSession)
+	}
+	round3Messages, err := runRounds(sessions)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	raw, err := states[0].GetSignedTransaction(round3Messages)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	r, s, v, err := states[1].GetRecoverableSignature(round3Messages)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !ecdsa.Verify(pk, tx.SigningHash(), r, s) {
+		t.Errorf("signature verification failed")
+		return
+	}
+	if R := states[1].SigSession.RandomPoint(); R == nil || R.X.Cmp(r) != 0 || byte(R.Y.Bit(0)) != v&1 {
+		t.Errorf("random point of the session does not match the signature")
+		return
+	}
+	s, flipped := tcecdsa.NormalizeLowS(keyMeta.Curve(), s)
+	if flipped {
+		v ^= 1
+	}
+	if !bytes.Equal(raw, tx.EncodeSigned(r, s, v)) {
+		t.Errorf("signed transaction is not encoded with a low s signature")
+		return
+	}
+	recovered, err := tcecdsa.RecoverPublicKey(tx.SigningHash(), r, s, v)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !bytes.Equal(tcecdsa.EthereumAddress(recovered), tcecdsa.EthereumAddress(pk)) {
+		t.Errorf("recovered address is different to signer address")
+		return
+	}
+}
@@ -0,0 +1,190 @@
+package tcecdsa
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// DynamicFeeTxType is the EIP-2718 type of EIP-1559 transactions.
+const DynamicFeeTxType = 0x02
+
+// EthereumTx represents an Ethereum transaction that could be signed by a threshold session.
+type EthereumTx interface {
+	// SigningHash returns the Keccak-256 hash that needs to be signed.
+	SigningHash() []byte
+	// EncodeSigned returns the raw signed transaction, using a signature (r, s) with a low s value
+	// and its recovery id v.
+	EncodeSigned(r, s *big.Int, v byte) []byte
+}
+
+// LegacyTx represents a legacy Ethereum transaction with EIP-155 replay protection.
+type LegacyTx struct {
+	ChainID  *big.Int
+	Nonce    uint64
+	GasPrice *big.Int
+	Gas      uint64
+	To       []byte // nil on contract creation
+	Value    *big.Int
+	Data     []byte
+}
+
+// AccessTuple represents an EIP-2930 access list entry.
+type AccessTuple struct {
+	Address     []byte
+	StorageKeys [][]byte
+}
+
+// DynamicFeeTx represents an EIP-1559 Ethereum transaction.
+type DynamicFeeTx struct {
+	ChainID    *big.Int
+	Nonce      uint64
+	GasTipCap  *big.Int // Max priority fee per gas
+	GasFeeCap  *big.Int // Max fee per gas
+	Gas        uint64
+	To         []byte // nil on contract creation
+	Value      *big.Int
+	Data       []byte
+	AccessList []AccessTuple
+}
+
+// EthereumTxSession represents a threshold signing session over an Ethereum transaction.
+// It is a SigSession, so its rounds are used in the same way, but it returns a raw signed transaction.
+type EthereumTxSession struct {
+	*SigSession
+	tx EthereumTx
+}
+
+// NewEthereumTxSession creates a new signing session related to an Ethereum transaction.
+// The key should have been created over secp256k1 curve.
+func (p *KeyShare) NewEthereumTxSession(meta *KeyMeta, tx EthereumTx) (state *EthereumTxSession, err error) {
+	if meta.Curve() != Secp256k1() {
+		err = fmt.Errorf("ethereum transactions require a secp256k1 key, but curve is %s", meta.CurveName)
+		return
+	}
+	session, err := p.NewSigSession(meta, tx.SigningHash())
+	if err != nil {
+		return
+	}
+	state = &EthereumTxSession{
+		SigSession: session,
+		tx:         tx,
+	}
+	return
+}
+
+// GetSignedTransaction joins the last values and returns the raw signed transaction.
+// Ethereum only accepts signatures with s in the lower half of the curve order (EIP-2), so s is
+// normalized before encoding the transaction.
+func (state *EthereumTxSession) GetSignedTransaction(msgs Round3MessageList) (raw []byte, err error) {
+	r, s, v, err := state.GetRecoverableSignature(msgs)
+	if err != nil {
+		return
+	}
+	s, flipped := NormalizeLowS(state.meta.Curve(), s)
+	if flipped {
+		v ^= 1
+	}
+	raw = state.tx.EncodeSigned(r, s, v)
+	return
+}
+
+// SigningHash returns the EIP-155 hash of the transaction.
+func (tx *LegacyTx) SigningHash() []byte {
+	return Keccak256(rlpList(append(tx.fields(), rlpBigInt(tx.ChainID), rlpUint(0), rlpUint(0))...))
+}
+
+// EncodeSigned returns the RLP encoded signed transaction, with v = recovery id + 2 * chain id + 35.
+func (tx *LegacyTx) EncodeSigned(r, s *big.Int, v byte) []byte {
+	eip155V := new(big.Int).Lsh(tx.ChainID, 1)
+	eip155V.Add(eip155V, big.NewInt(int64(v)+35))
+	return rlpList(append(tx.fields(), rlpBigInt(eip155V), rlpBigInt(r), rlpBigInt(s))...)
+}
+
+// fields returns the RLP encoded fields of the unsigned transaction.
+func (tx *LegacyTx) fields() [][]byte {
+	return [][]byte{
+		rlpUint(tx.Nonce),
+		rlpBigInt(tx.GasPrice),
+		rlpUint(tx.Gas),
+		rlpBytes(tx.To),
+		rlpBigInt(tx.Value),
+		rlpBytes(tx.Data),
+	}
+}
+
+// SigningHash returns the EIP-1559 hash of the transaction.
+func (tx *DynamicFeeTx) SigningHash() []byte {
+	return Keccak256([]byte{DynamicFeeTxType}, rlpList(tx.fields()...))
+}
+
+// EncodeSigned returns the EIP-2718 typed envelope of the signed transaction, with v used as y parity.
+func (tx *DynamicFeeTx) EncodeSigned(r, s *big.Int, v byte) []byte {
+	payload := rlpList(append(tx.fields(), rlpUint(uint64(v&1)), rlpBigInt(r), rlpBigInt(s))...)
+	return append([]byte{DynamicFeeTxType}, payload...)
+}
+
+// fields returns the RLP encoded fields of the unsigned transaction.
+func (tx *DynamicFeeTx) fields() [][]byte {
+	accessList := make([][]byte, 0, len(tx.AccessList))
+	for _, tuple := range tx.AccessList {
+		keys := make([][]byte, 0, len(tuple.StorageKeys))
+		for _, key := range tuple.StorageKeys {
+			keys = append(keys, rlpBytes(key))
+		}
+		accessList = append(accessList, rlpList(rlpBytes(tuple.Address), rlpList(keys...)))
+	}
+	return [][]byte{
+		rlpBigInt(tx.ChainID),
+		rlpUint(tx.Nonce),
+		rlpBigInt(tx.GasTipCap),
+		rlpBigInt(tx.GasFeeCap),
+		rlpUint(tx.Gas),
+		rlpBytes(tx.To),
+		rlpBigInt(tx.Value),
+		rlpBytes(tx.Data),
+		rlpList(accessList...),
+	}
+}
+
+// rlpBytes returns the RLP encoding of a byte string.
+func rlpBytes(b []byte) []byte {
+	if len(b) == 1 && b[0] < 0x80 {
+		return []byte{b[0]}
+	}
+	return append(rlpHeader(0x80, len(b)), b...)
+}
+
+// rlpList returns the RLP encoding of a list of already encoded items.
+func rlpList(items ...[]byte) []byte {
+	length := 0
+	for _, item := range items {
+		length += len(item)
+	}
+	encoded := rlpHeader(0xc0, length)
+	for _, item := range items {
+		encoded = append(encoded, item...)
+	}
+	return encoded
+}
+
+// rlpUint returns the RLP encoding of an unsigned integer, as a minimal big endian byte string.
+func rlpUint(i uint64) []byte {
+	return rlpBytes(new(big.Int).SetUint64(i).Bytes())
+}
+
+// rlpBigInt returns the RLP encoding of a non-negative big integer. nil is encoded as zero.
+func rlpBigInt(i *big.Int) []byte {
+	if i == nil {
+		return rlpBytes(nil)
+	}
+	return rlpBytes(i.Bytes())
+}
+
+// rlpHeader returns the RLP header for a string (offset 0x80) or list (offset 0xc0) of the given length.
+func rlpHeader(offset byte, length int) []byte {
+	if length < 56 {
+		return []byte{offset + byte(length)}
+	}
+	lenBytes := big.NewInt(int64(length)).Bytes()
+	return append([]byte{offset + 55 + byte(len(lenBytes))}, lenBytes...)
+}
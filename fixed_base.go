@@ -0,0 +1,173 @@
+package tcecdsa
+
+import (
+	"crypto/elliptic"
+	"math/big"
+)
+
+// fixedBaseWindow is the size in bits of the windows of the fixed-base tables.
+const fixedBaseWindow = 4
+
+// fixedBaseTables groups the precomputed tables of the bases used by the ZKProofs of a key.
+type fixedBaseTables struct {
+	g      *pointTable // Nil if the curve already uses precomputed tables for ScalarBaseMult
+	h1, h2 *expTable   // Nil if the key has no ZKProofMeta
+}
+
+// expTable represents the precomputed powers of a fixed base: table[i][d-1] is base^(d*2^(w*i)) mod
+// modulus, where w is fixedBaseWindow. An exponentiation with an exponent of up to maxBits bits needs
+// one modular multiplication for each non-zero window and no squarings.
+type expTable struct {
+	base, modulus *big.Int
+	maxBits       int
+	table         [][]*big.Int
+}
+
+// pointTable represents the precomputed multiples of the base point of a curve: table[i][d-1] is
+// d*2^(w*i)*G, where w is fixedBaseWindow.
+type pointTable struct {
+	curve jacobianCurve
+	table [][]*Point
+}
+
+// jacobianCurve is implemented by the curves of this package that expose their Jacobian arithmetic.
+type jacobianCurve interface {
+	elliptic.Curve
+	addJacobian(x1, y1, z1, x2, y2, z2 *big.Int) (*big.Int, *big.Int, *big.Int)
+	affineFromJacobian(x, y, z *big.Int) (*big.Int, *big.Int)
+}
+
+// fixedBases returns the fixed-base tables of the key, building them the first time it is called.
+// The tables of H1 and H2 cover the exponents used by the ZKProofs and by their batch verification; larger
+// exponents are computed with big.Int.Exp.
+func (meta *KeyMeta) fixedBases() *fixedBaseTables {
+	meta.tablesOnce.Do(func() {
+		tables := &fixedBaseTables{}
+		// The curves of crypto/elliptic already use precomputed tables.
+		if curve, ok := meta.Curve().(jacobianCurve); ok {
+			tables.g = newPointTable(curve)
+		}
+		if meta.ZKProofMeta != nil {
+			maxBits := 8*meta.Q().BitLen() + batchWeightBits + 16
+			tables.h1 = newExpTable(meta.H1, meta.NTilde, maxBits)
+			tables.h2 = newExpTable(meta.H2, meta.NTilde, meta.NTilde.BitLen()+maxBits)
+		}
+		meta.tables = tables
+	})
+	return meta.tables
+}
+
+// baseMul returns k*G, using the table of G if the curve has one.
+func (meta *KeyMeta) baseMul(k *big.Int) *Point {
+	if g := meta.fixedBases().g; g != nil {
+		return g.mul(k)
+	}
+	return NewZero().BaseMul(meta.Curve(), k)
+}
+
+// expH1H2 returns h1^a * h2^b mod NTilde.
+func (meta *KeyMeta) expH1H2(a, b *big.Int) *big.Int {
+	tables := meta.fixedBases()
+	r := tables.h1.exp(a)
+	r.Mul(r, tables.h2.exp(b)).Mod(r, meta.NTilde)
+	return r
+}
+
+// expGenerator returns (N+1)^m mod N^(s+1). It does not need a table: by the binomial theorem,
+// (N+1)^m = sum of C(m, k)*N^k for k from 0 to s, because the next terms are multiples of N^(s+1).
+func (meta *KeyMeta) expGenerator(m *big.Int) *big.Int {
+	cache := meta.Paillier.Cache()
+	if m.Sign() < 0 {
+		return new(big.Int).Exp(cache.NPlusOne, m, cache.NToSPlusOne)
+	}
+	r := big.NewInt(1)
+	binomial := big.NewInt(1)
+	nToK := big.NewInt(1)
+	for k := int64(1); k <= int64(meta.Paillier.S); k++ {
+		binomial.Mul(binomial, new(big.Int).Sub(m, big.NewInt(k-1)))
+		binomial.Quo(binomial, big.NewInt(k))
+		nToK.Mul(nToK, meta.Paillier.N)
+		r.Add(r, new(big.Int).Mul(binomial, nToK))
+	}
+	return r.Mod(r, cache.NToSPlusOne)
+}
+
+// newExpTable returns the table of base for exponents of up to maxBits bits.
+func newExpTable(base, modulus *big.Int, maxBits int) *expTable {
+	windows := (maxBits + fixedBaseWindow - 1) / fixedBaseWindow
+	t := &expTable{
+		base:    base,
+		modulus: modulus,
+		maxBits: windows * fixedBaseWindow,
+		table:   make([][]*big.Int, windows),
+	}
+	b := new(big.Int).Mod(base, modulus)
+	for i := range t.table {
+		row := make([]*big.Int, 1<<fixedBaseWindow-1)
+		row[0] = b
+		for d := 1; d < len(row); d++ {
+			row[d] = new(big.Int).Mul(row[d-1], b)
+			row[d].Mod(row[d], modulus)
+		}
+		t.table[i] = row
+		b = new(big.Int).Mul(row[len(row)-1], b)
+		b.Mod(b, modulus)
+	}
+	return t
+}
+
+// exp returns base^e mod modulus. Negative exponents and exponents larger than the table are computed
+// with big.Int.Exp.
+func (t *expTable) exp(e *big.Int) *big.Int {
+	if e.Sign() < 0 || e.BitLen() > t.maxBits {
+		return new(big.Int).Exp(t.base, e, t.modulus)
+	}
+	r := big.NewInt(1)
+	for i, row := range t.table {
+		if d := window(e, i); d != 0 {
+			r.Mul(r, row[d-1]).Mod(r, t.modulus)
+		}
+	}
+	return r.Mod(r, t.modulus)
+}
+
+// newPointTable returns the table of the base point of a curve, for scalars lower than its order.
+func newPointTable(curve jacobianCurve) *pointTable {
+	params := curve.Params()
+	windows := (params.N.BitLen() + fixedBaseWindow - 1) / fixedBaseWindow
+	t := &pointTable{
+		curve: curve,
+		table: make([][]*Point, windows),
+	}
+	b := NewPoint(params.Gx, params.Gy)
+	for i := range t.table {
+		row := make([]*Point, 1<<fixedBaseWindow-1)
+		row[0] = b
+		for d := 1; d < len(row); d++ {
+			row[d] = NewPoint(curve.Add(row[d-1].X, row[d-1].Y, b.X, b.Y))
+		}
+		t.table[i] = row
+		b = NewPoint(curve.Add(row[len(row)-1].X, row[len(row)-1].Y, b.X, b.Y))
+	}
+	return t
+}
+
+// mul returns k*G. The scalar is reduced modulo the order of the curve, so negative scalars are accepted.
+func (t *pointTable) mul(k *big.Int) *Point {
+	k = new(big.Int).Mod(k, t.curve.Params().N)
+	x, y, z := new(big.Int), new(big.Int), new(big.Int)
+	for i, row := range t.table {
+		if d := window(k, i); d != 0 {
+			x, y, z = t.curve.addJacobian(x, y, z, row[d-1].X, row[d-1].Y, one)
+		}
+	}
+	return NewPoint(t.curve.affineFromJacobian(x, y, z)).SetCurve(t.curve)
+}
+
+// window returns the i-th window of fixedBaseWindow bits of a non-negative integer.
+func window(e *big.Int, i int) (d int) {
+	for j := fixedBaseWindow - 1; j >= 0; j-- {
+		d = d<<1 | int(e.Bit(i*fixedBaseWindow+j))
+	}
+	return
+}
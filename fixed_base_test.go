@@ -0,0 +1,141 @@
+package tcecdsa
+
+import (
+	"crypto/rand"
+	"github.com/niclabs/tcecdsa/l2fhe"
+	"github.com/niclabs/tcpaillier"
+	"math/big"
+	"testing"
+)
+
+// fixedBaseModulus returns a random prime modulus and a random base lower than it.
+func fixedBaseModulus(t testing.TB) (base, modulus *big.Int) {
+	modulus, err := rand.Prime(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, err = RandomInRange(big.NewInt(2), modulus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestExpTable_Exp(t *testing.T) {
+	base, modulus := fixedBaseModulus(t)
+	table := newExpTable(base, modulus, 2304)
+	random, err := rand.Int(rand.Reader, new(big.Int).Lsh(one, 2304))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	large := new(big.Int).Lsh(random, 100)
+	negative := new(big.Int).Neg(random)
+	for _, e := range []*big.Int{zero, one, big.NewInt(16), random, large, negative} {
+		expected := new(big.Int).Exp(base, e, modulus)
+		if table.exp(e).Cmp(expected) != 0 {
+			t.Errorf("exponentiation with table differs from Exp with exponent of %d bits", e.BitLen())
+			return
+		}
+	}
+}
+
+func TestPointTable_Mul(t *testing.T) {
+	curve := Secp256k1()
+	table := newPointTable(curve.(jacobianCurve))
+	random, err := RandomFieldElement(curve)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	n := curve.Params().N
+	large := new(big.Int).Add(random, n)
+	negative := new(big.Int).Neg(random)
+	for _, k := range []*big.Int{zero, one, random, new(big.Int).Sub(n, one), n, large, negative} {
+		expected := NewZero().BaseMul(curve, new(big.Int).Mod(k, n))
+		if table.mul(k).Cmp(expected) != 0 {
+			t.Errorf("multiplication with table differs from BaseMul with scalar %s", k)
+			return
+		}
+	}
+}
+
+func TestKeyMeta_ExpGenerator(t *testing.T) {
+	_, n := fixedBaseModulus(t)
+	random, err := rand.Int(rand.Reader, new(big.Int).Lsh(one, 2304))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for _, s := range []uint8{1, 2, 3} {
+		meta := &KeyMeta{PubKey: &l2fhe.PubKey{Paillier: &tcpaillier.PubKey{N: n, S: s}}}
+		cache := meta.Paillier.Cache()
+		for _, m := range []*big.Int{zero, one, big.NewInt(2), random, new(big.Int).Neg(random)} {
+			expected := new(big.Int).Exp(cache.NPlusOne, m, cache.NToSPlusOne)
+			if meta.expGenerator(m).Cmp(expected) != 0 {
+				t.Errorf("generator exponentiation differs from Exp with s=%d", s)
+				return
+			}
+		}
+	}
+}
+
+func BenchmarkExpTable(b *testing.B) {
+	base, modulus := fixedBaseModulus(b)
+	e, err := rand.Int(rand.Reader, new(big.Int).Lsh(one, 2304))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Run("Exp", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			new(big.Int).Exp(base, e, modulus)
+		}
+	})
+	b.Run("Table", func(b *testing.B) {
+		table := newExpTable(base, modulus, 2304)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			table.exp(e)
+		}
+	})
+}
+
+func BenchmarkPointTable(b *testing.B) {
+	curve := Secp256k1()
+	k, err := RandomFieldElement(curve)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Run("ScalarBaseMult", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			NewZero().BaseMul(curve, k)
+		}
+	})
+	b.Run("Table", func(b *testing.B) {
+		table := newPointTable(curve.(jacobianCurve))
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			table.mul(k)
+		}
+	})
+}
+
+func BenchmarkKeyMeta_ExpGenerator(b *testing.B) {
+	_, n := fixedBaseModulus(b)
+	meta := &KeyMeta{PubKey: &l2fhe.PubKey{Paillier: &tcpaillier.PubKey{N: n, S: 1}}}
+	cache := meta.Paillier.Cache()
+	m, err := rand.Int(rand.Reader, new(big.Int).Lsh(one, 2304))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Run("Exp", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			new(big.Int).Exp(cache.NPlusOne, m, cache.NToSPlusOne)
+		}
+	})
+	b.Run("Binomial", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			meta.expGenerator(m)
+		}
+	})
+}
@@ -2,4 +2,7 @@ module github.com/niclabs/tcecdsa
 
 go 1.13
 
-require github.com/niclabs/tcpaillier v0.0.7
+require (
+	github.com/niclabs/tcpaillier v0.0.7
+	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9
+)
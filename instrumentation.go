@@ -0,0 +1,127 @@
+package tcecdsa
+
+import (
+	"github.com/niclabs/tcecdsa/l2fhe"
+	"github.com/niclabs/tcpaillier"
+	"math/big"
+	"sync"
+)
+
+// The following values are the names of the spans reported to the instrumentation.
+const (
+	SpanKeyInit                  = "key_init"                           // KeyShare.Init
+	SpanKeySet                   = "key_set"                            // KeyShare.SetKey
+	SpanRound1                   = "sign_round1"                        // SigSession.Round1
+	SpanRound2                   = "sign_round2"                        // SigSession.Round2
+	SpanRound3                   = "sign_round3"                        // SigSession.Round3
+	SpanGetSignature             = "sign_get_signature"                 // SigSession.GetSignature
+	SpanJoinKeyInit              = "join_key_init"                      // KeyInitMessageList.Join
+	SpanJoinRound1               = "join_round1"                        // Round1MessageList.Join
+	SpanJoinRound2               = "join_round2"                        // Round2MessageList.Join
+	SpanJoinRound3               = "join_round3"                        // Round3MessageList.Join
+	SpanEncrypt                  = "paillier_encrypt"                   // L2FHE encryption with a random value
+	SpanPartialDecryptL2         = "partial_decrypt_l2"                 // Partial decryption of a level 2 value, with its proof
+	SpanNewKeyGenProof           = "keygen_zkproof_new"                 // Generation of a KeyGenZKProof
+	SpanVerifyKeyGenProof        = "keygen_zkproof_verify"              // KeyGenZKProof.Verify
+	SpanNewSigProof              = "sig_zkproof_new"                    // NewSigZKProof
+	SpanVerifySigProof           = "sig_zkproof_verify"                 // SigZKProof.Verify
+	SpanVerifyDecryptProofs      = "decrypt_share_zkproof_verify"       // Round2Message.Verify and Round3Message.Verify
+	SpanBatchVerifySigProofs     = "sig_zkproof_batch_verify"           // Combined check of Round1MessageList.VerifyBatch
+	SpanBatchVerifyDecryptProofs = "decrypt_share_zkproof_batch_verify" // Combined check of Round2MessageList.VerifyBatch and Round3MessageList.VerifyBatch
+)
+
+// The following values are the names of the counters and histograms reported to the instrumentation.
+const (
+	CounterRejectedMessages   = "rejected_messages_total"      // Messages rejected because their proofs are not valid
+	CounterIncompleteMessages = "incomplete_messages_total"    // Messages skipped because they are nil or their fields are not set
+	HistogramValidMessages    = "valid_messages"               // Number of valid messages of each list verified by a Join
+	CounterBatchFallbacks     = "batch_verify_fallbacks_total" // Batch verifications that failed and were repeated for each proof
+)
+
+// Instrumentation receives the spans, counters and histograms of the protocol. It is called around each round
+// and each expensive primitive, so it can be used to collect metrics or traces. Its methods can be called
+// concurrently, and they should return quickly.
+type Instrumentation interface {
+	// StartSpan starts a span with the given name, which ends when End is called on the returned span.
+	StartSpan(name string) Span
+	// AddCounter adds delta to the counter with the given name.
+	AddCounter(name string, delta float64)
+	// ObserveHistogram adds an observation to the histogram with the given name.
+	ObserveHistogram(name string, value float64)
+}
+
+// Span represents a started operation.
+type Span interface {
+	// End ends the span. The error is the result of the operation, and it is nil if it succeeded.
+	End(err error)
+}
+
+// instrumentation is the Instrumentation set with SetInstrumentation, or nil.
+var instrumentation struct {
+	sync.RWMutex
+	Instrumentation
+}
+
+// SetInstrumentation sets the Instrumentation used by the package. If it is nil, nothing is reported.
+// The instrumentation is disabled by default.
+func SetInstrumentation(instr Instrumentation) {
+	instrumentation.Lock()
+	defer instrumentation.Unlock()
+	instrumentation.Instrumentation = instr
+}
+
+// getInstrumentation returns the Instrumentation used by the package, or nil.
+func getInstrumentation() Instrumentation {
+	instrumentation.RLock()
+	defer instrumentation.RUnlock()
+	return instrumentation.Instrumentation
+}
+
+// activeSpan is a span started by startSpan. Its span is nil if the instrumentation is disabled.
+type activeSpan struct {
+	span Span
+}
+
+// startSpan starts a span on the instrumentation, if it is enabled.
+// It is used as "defer startSpan(name).end(&err)" on functions with a named error result.
+func startSpan(name string) activeSpan {
+	if instr := getInstrumentation(); instr != nil {
+		return activeSpan{instr.StartSpan(name)}
+	}
+	return activeSpan{}
+}
+
+// end ends the span with the error err points to.
+func (s activeSpan) end(err *error) {
+	if s.span != nil {
+		s.span.End(*err)
+	}
+}
+
+// addCounter adds delta to a counter on the instrumentation, if it is enabled.
+func addCounter(name string, delta float64) {
+	if instr := getInstrumentation(); instr != nil {
+		instr.AddCounter(name, delta)
+	}
+}
+
+// observeHistogram adds an observation to a histogram on the instrumentation, if it is enabled.
+func observeHistogram(name string, value float64) {
+	if instr := getInstrumentation(); instr != nil {
+		instr.ObserveHistogram(name, value)
+	}
+}
+
+// Encrypt encrypts m using the L2FHE public key and returns the random value used.
+// It wraps l2fhe.PubKey.Encrypt to report it to the instrumentation.
+func (meta *KeyMeta) Encrypt(m *big.Int) (e *l2fhe.EncryptedL1, r *big.Int, err error) {
+	defer startSpan(SpanEncrypt).end(&err)
+	return meta.PubKey.Encrypt(m)
+}
+
+// PartialDecryptL2 returns a partial decryption of a level 2 value with its proof.
+// It wraps l2fhe.PubKey.PartialDecryptL2 to report it to the instrumentation.
+func (meta *KeyMeta) PartialDecryptL2(key *tcpaillier.KeyShare, c *l2fhe.EncryptedL2) (share *l2fhe.DecryptedShareL2, zk *l2fhe.DecryptedShareL2ZK, err error) {
+	defer startSpan(SpanPartialDecryptL2).end(&err)
+	return meta.PubKey.PartialDecryptL2(key, c)
+}
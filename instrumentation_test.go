@@ -0,0 +1,119 @@
+package tcecdsa_test
+
+import (
+	"github.com/niclabs/tcecdsa"
+	"github.com/niclabs/tcpaillier"
+	"sync"
+	"testing"
+)
+
+// recordingInstrumentation is an instrumentation that counts the spans, counters and histogram observations.
+type recordingInstrumentation struct {
+	mutex        sync.Mutex
+	spans        map[string]int
+	failed       map[string]int
+	counters     map[string]float64
+	observations map[string]int
+}
+
+// recordingSpan is a span started on a recordingInstrumentation.
+type recordingSpan struct {
+	instr *recordingInstrumentation
+	name  string
+}
+
+func newRecordingInstrumentation() *recordingInstrumentation {
+	return &recordingInstrumentation{
+		spans:        make(map[string]int),
+		failed:       make(map[string]int),
+		counters:     make(map[string]float64),
+		observations: make(map[string]int),
+	}
+}
+
+func (instr *recordingInstrumentation) StartSpan(name string) tcecdsa.Span {
+	return &recordingSpan{instr, name}
+}
+
+func (instr *recordingInstrumentation) AddCounter(name string, delta float64) {
+	instr.mutex.Lock()
+	defer instr.mutex.Unlock()
+	instr.counters[name] += delta
+}
+
+func (instr *recordingInstrumentation) ObserveHistogram(name string, _ float64) {
+	instr.mutex.Lock()
+	defer instr.mutex.Unlock()
+	instr.observations[name]++
+}
+
+func (span *recordingSpan) End(err error) {
+	span.instr.mutex.Lock()
+	defer span.instr.mutex.Unlock()
+	span.instr.spans[span.name]++
+	if err != nil {
+		span.instr.failed[span.name]++
+	}
+}
+
+func TestSetInstrumentation(t *testing.T) {
+	params := &tcecdsa.NewKeyParams{
+		PaillierFixed: &tcpaillier.FixedParams{
+			P:  p,
+			P1: p1,
+			Q:  q,
+			Q1: q1,
+		},
+	}
+	shares, keyMeta, err := tcecdsa.NewKey(L, K, Curve, params)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	instr := newRecordingInstrumentation()
+	tcecdsa.SetInstrumentation(instr)
+	defer tcecdsa.SetInstrumentation(nil)
+	if _, err := initKey(shares, keyMeta); err != nil {
+		t.Error(err)
+		return
+	}
+	expected := map[string]int{
+		tcecdsa.SpanKeyInit:           L,
+		tcecdsa.SpanEncrypt:           L,
+		tcecdsa.SpanNewKeyGenProof:    L,
+		tcecdsa.SpanKeySet:            L,
+		tcecdsa.SpanJoinKeyInit:       L + 1, // initKey also joins the messages to get the public key
+		tcecdsa.SpanVerifyKeyGenProof: (L + 1) * L,
+	}
+	for name, n := range expected {
+		if instr.spans[name] != n {
+			t.Errorf("span %s should end %d times, but it ended %d times", name, n, instr.spans[name])
+			return
+		}
+	}
+	if len(instr.failed) != 0 {
+		t.Errorf("no span should fail, but %v failed", instr.failed)
+		return
+	}
+
+	invalid := tcecdsa.Round2MessageList{}
+	for i := 0; i < K; i++ {
+		invalid = append(invalid, &tcecdsa.Round2Message{})
+	}
+	if _, err := invalid.Join(keyMeta, nil); err == nil {
+		t.Error("join of invalid messages should fail")
+		return
+	}
+	if instr.failed[tcecdsa.SpanJoinRound2] != 1 || instr.failed[tcecdsa.SpanVerifyDecryptProofs] != K {
+		t.Errorf("join and verification spans should fail, but failed spans are %v", instr.failed)
+		return
+	}
+	if instr.counters[tcecdsa.CounterIncompleteMessages] != K || instr.observations[tcecdsa.HistogramValidMessages] != 1 {
+		t.Errorf("%d messages should be incomplete, but %v were", K, instr.counters[tcecdsa.CounterIncompleteMessages])
+		return
+	}
+	if instr.counters[tcecdsa.CounterRejectedMessages] != 0 {
+		t.Errorf("incomplete messages should not be counted as rejected, but %v were", instr.counters[tcecdsa.CounterRejectedMessages])
+		return
+	}
+}
@@ -0,0 +1,44 @@
+// Package benchparams contains fixed Paillier parameters for the benchmarks and the tcecdsa-bench command,
+// so they do not need to generate safe primes before measuring the protocol. The factors of these
+// parameters are public, so they must never be used to protect real keys.
+package benchparams
+
+import (
+	"github.com/niclabs/tcpaillier"
+	"math/big"
+)
+
+// params contains the fixed parameters by the bit size of the curve they can be used with.
+// The Paillier modulus of a curve of n bits has 8*n bits.
+var params = map[int][4]string{
+	224: {
+		"481843155987347819240471233018818582440288384824667225054816554801181862153791832386130859645260354756309645278837491351820327738110587630919202231210878510487358927457873959614389626766288687576223670770229149716938428974940517592276865576702001967378548748115710361363",
+		"240921577993673909620235616509409291220144192412333612527408277400590931076895916193065429822630177378154822639418745675910163869055293815459601115605439255243679463728936979807194813383144343788111835385114574858469214487470258796138432788351000983689274374057855180681",
+		"412869555449418513088610723546515649758113679263497461143281964942533362549552387321171889833327035679850480855648596001543734177165640501544385552969893524814969820967880299485349586412916667152003711405804484319055891168516277276341589468276521027239786422345706510127",
+		"206434777724709256544305361773257824879056839631748730571640982471266681274776193660585944916663517839925240427824298000771867088582820250772192776484946762407484910483940149742674793206458333576001855702902242159527945584258138638170794734138260513619893211172853255063",
+	},
+	256: {
+		"137537413420762547650493449893260858917800237153163144023736310003651436274145088983133654061063082464994185960314187752168804446390815366271979811577969538698776031062862677455983392561329766291346225778385930191175906902426248344178361969094907274847853172862307239782873996608540554379871730524796395787247",
+		"68768706710381273825246724946630429458900118576581572011868155001825718137072544491566827030531541232497092980157093876084402223195407683135989905788984769349388015531431338727991696280664883145673112889192965095587953451213124172089180984547453637423926586431153619891436998304270277189935865262398197893623",
+		"155023296754007244089528221677469556667119528650199642609409577564109526126683690043302787241624003158964758573520637795429011770537460107983462221613420743461825559166142342076795339979845533939313389392767803764678383759133574074970235524519083017279806360059710695964336383022336657693956448087509984830363",
+		"77511648377003622044764110838734778333559764325099821304704788782054763063341845021651393620812001579482379286760318897714505885268730053991731110806710371730912779583071171038397669989922766969656694696383901882339191879566787037485117762259541508639903180029855347982168191511168328846978224043754992415181",
+	},
+}
+
+// Paillier returns fixed Paillier parameters for a curve of the given bit size, or nil if there are none.
+func Paillier(curveBitSize int) *tcpaillier.FixedParams {
+	values, ok := params[curveBitSize]
+	if !ok {
+		return nil
+	}
+	ints := make([]*big.Int, len(values))
+	for i, v := range values {
+		ints[i], _ = new(big.Int).SetString(v, 10)
+	}
+	return &tcpaillier.FixedParams{
+		P:  ints[0],
+		P1: ints[1],
+		Q:  ints[2],
+		Q1: ints[3],
+	}
+}
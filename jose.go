@@ -0,0 +1,166 @@
+package tcecdsa
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	_ "crypto/sha256" // SHA-256 is used by ES256
+	_ "crypto/sha512" // SHA-384 and SHA-512 are used by ES384 and ES512
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// jwsAlgorithm represents a JWS ECDSA algorithm and its hash function, as defined in RFC 7518.
+type jwsAlgorithm struct {
+	Name string
+	Hash crypto.Hash
+}
+
+// jwsAlgorithms maps the curve names to their JWS algorithms.
+var jwsAlgorithms = map[string]jwsAlgorithm{
+	"P-256": {"ES256", crypto.SHA256},
+	"P-384": {"ES384", crypto.SHA384},
+	"P-521": {"ES512", crypto.SHA512},
+}
+
+// JWSSession represents a threshold signing session over a JWS signing input.
+// It is a SigSession, so its rounds are used in the same way, but it returns a JWS in compact serialization.
+type JWSSession struct {
+	*SigSession
+	signingInput string
+}
+
+// JWSAlgorithm returns the JWS algorithm name and hash function related to a curve.
+func JWSAlgorithm(curveName string) (alg string, hash crypto.Hash, err error) {
+	jwsAlg, ok := jwsAlgorithms[curveName]
+	if !ok {
+		err = fmt.Errorf("curve %s has no related JWS algorithm", curveName)
+		return
+	}
+	return jwsAlg.Name, jwsAlg.Hash, nil
+}
+
+// JWSSigningInput returns the JWS signing input, defined as the base64url encoding of the protected
+// header and the payload, separated by a dot. The header alg value is set using the curve of the key.
+func JWSSigningInput(meta *KeyMeta, header map[string]interface{}, payload []byte) (input string, err error) {
+	alg, _, err := JWSAlgorithm(meta.CurveName)
+	if err != nil {
+		return
+	}
+	protected := make(map[string]interface{})
+	for k, v := range header {
+		protected[k] = v
+	}
+	protected["alg"] = alg
+	headerJSON, err := json.Marshal(protected)
+	if err != nil {
+		return
+	}
+	input = base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return
+}
+
+// NewJWSSession creates a new signing session over a JWS with the given protected header and payload.
+// The header alg value is set using the curve of the key, and the signing input is hashed with the hash
+// function of that algorithm.
+func (p *KeyShare) NewJWSSession(meta *KeyMeta, header map[string]interface{}, payload []byte) (state *JWSSession, err error) {
+	input, err := JWSSigningInput(meta, header, payload)
+	if err != nil {
+		return
+	}
+	_, hash, err := JWSAlgorithm(meta.CurveName)
+	if err != nil {
+		return
+	}
+	h := hash.New()
+	h.Write([]byte(input))
+	session, err := p.NewSigSession(meta, h.Sum(nil))
+	if err != nil {
+		return
+	}
+	state = &JWSSession{
+		SigSession:   session,
+		signingInput: input,
+	}
+	return
+}
+
+// NewJWTSession creates a new signing session over a JWT with the given claims, which are encoded as JSON.
+// The kid header is added only if it is not empty.
+func (p *KeyShare) NewJWTSession(meta *KeyMeta, claims interface{}, kid string) (state *JWSSession, err error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return
+	}
+	header := map[string]interface{}{"typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	return p.NewJWSSession(meta, header, payload)
+}
+
+// GetJWS joins the last values and returns the JWS in compact serialization.
+func (state *JWSSession) GetJWS(msgs Round3MessageList) (jws string, err error) {
+	r, s, err := state.GetSignature(msgs)
+	if err != nil {
+		return
+	}
+	sig := JWSSignature(state.meta.Curve(), r, s)
+	jws = state.signingInput + "." + base64.RawURLEncoding.EncodeToString(sig)
+	return
+}
+
+// JWSSignature returns the RFC 7518 encoding of an ECDSA signature, defined as the concatenation of r and s
+// as big endian byte arrays with the length of the curve order.
+func JWSSignature(curve elliptic.Curve, r, s *big.Int) []byte {
+	size := (curve.Params().BitSize + 7) / 8
+	return append(padBytes(r, size), padBytes(s, size)...)
+}
+
+// VerifyJWS verifies a JWS in compact serialization with an ECDSA public key, and returns its payload.
+// The alg header value must match the algorithm related to the curve of the key.
+func VerifyJWS(pk *ecdsa.PublicKey, jws string) (payload []byte, err error) {
+	parts := strings.Split(jws, ".")
+	if len(parts) != 3 {
+		err = fmt.Errorf("jws should have three parts, but it has %d", len(parts))
+		return
+	}
+	alg, hash, err := JWSAlgorithm(pk.Curve.Params().Name)
+	if err != nil {
+		return
+	}
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return
+	}
+	var header map[string]interface{}
+	if err = json.Unmarshal(headerJSON, &header); err != nil {
+		return
+	}
+	if header["alg"] != alg {
+		err = fmt.Errorf("jws alg should be %s, but it is %v", alg, header["alg"])
+		return
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return
+	}
+	size := (pk.Curve.Params().BitSize + 7) / 8
+	if len(sig) != 2*size {
+		err = fmt.Errorf("signature should be %d bytes long, but it is %d", 2*size, len(sig))
+		return
+	}
+	r := new(big.Int).SetBytes(sig[:size])
+	s := new(big.Int).SetBytes(sig[size:])
+	h := hash.New()
+	h.Write([]byte(parts[0] + "." + parts[1]))
+	if !ecdsa.Verify(pk, h.Sum(nil), r, s) {
+		err = fmt.Errorf("signature verification failed")
+		return
+	}
+	payload, err = base64.RawURLEncoding.DecodeString(parts[1])
+	return
+}
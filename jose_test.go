@@ -0,0 +1,113 @@
+package tcecdsa_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/niclabs/tcecdsa"
+	"github.com/niclabs/tcpaillier"
+	"strings"
+	"testing"
+)
+
+func TestJWSSession(t *testing.T) {
+	params := &tcecdsa.NewKeyParams{
+		PaillierFixed: &tcpaillier.FixedParams{
+			P:  p256,
+			P1: p1256,
+			Q:  q256,
+			Q1: q1256,
+		},
+	}
+	shares, keyMeta, err := tcecdsa.NewKey(L, K, "P-256", params)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	pk, err := initKey(shares, keyMeta)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	claims := map[string]interface{}{"sub": "tcecdsa", "admin": true}
+	states := make([]*tcecdsa.JWSSession, 0)
+	sessions := make([]*tcecdsa.SigSession, 0)
+	for _, share := range shares {
+		state, err := share.NewJWTSession(keyMeta, claims, "key-1")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		states = append(states, state)
+		sessions = append(sessions, state.SigSession)
+	}
+	round3Messages, err := runRounds(sessions)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	jws, err := states[0].GetJWS(round3Messages)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	payload, err := tcecdsa.VerifyJWS(pk, jws)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(payload, &decoded); err != nil {
+		t.Error(err)
+		return
+	}
+	if decoded["sub"] != "tcecdsa" {
+		t.Errorf("payload claims are different to signed claims")
+		return
+	}
+	sig, _ := base64.RawURLEncoding.DecodeString(jws[strings.LastIndex(jws, ".")+1:])
+	if len(sig) != 64 {
+		t.Errorf("ES256 signature should be 64 bytes long, but it is %d", len(sig))
+		return
+	}
+}
+
+func TestVerifyJWS(t *testing.T) {
+	meta := &tcecdsa.KeyMeta{CurveName: "P-521"}
+	sk, err := ecdsa.GenerateKey(meta.Curve(), rand.Reader)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	input, err := tcecdsa.JWSSigningInput(meta, nil, []byte("payload"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	h := sha512.Sum512([]byte(input))
+	r, s, err := ecdsa.Sign(rand.Reader, sk, h[:])
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	sig := tcecdsa.JWSSignature(meta.Curve(), r, s)
+	if len(sig) != 132 {
+		t.Errorf("ES512 signature should be 132 bytes long, but it is %d", len(sig))
+		return
+	}
+	jws := input + "." + base64.RawURLEncoding.EncodeToString(sig)
+	if _, err := tcecdsa.VerifyJWS(&sk.PublicKey, jws); err != nil {
+		t.Error(err)
+		return
+	}
+	modified := jws[:len(jws)-1] + "A"
+	if strings.HasSuffix(jws, "A") {
+		modified = jws[:len(jws)-1] + "B"
+	}
+	if _, err := tcecdsa.VerifyJWS(&sk.PublicKey, modified); err == nil {
+		t.Errorf("modified signature should have been rejected")
+		return
+	}
+}
@@ -0,0 +1,292 @@
+package tcecdsa
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"golang.org/x/crypto/ssh"
+	"math/big"
+)
+
+// pemPublicKeyType is the PEM block type of PKIX public keys.
+const pemPublicKeyType = "PUBLIC KEY"
+
+// JWK represents the JSON Web Key (RFC 7517) encoding of an elliptic curve public key.
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// PublicKey returns the public key of the key share as a *ecdsa.PublicKey.
+func (p *KeyShare) PublicKey(meta *KeyMeta) (pk *ecdsa.PublicKey, err error) {
+	if p.Y == nil {
+		err = fmt.Errorf("key share has not been set")
+		return
+	}
+	pk = &ecdsa.PublicKey{
+		Curve: meta.Curve(),
+		X:     new(big.Int).Set(p.Y.X),
+		Y:     new(big.Int).Set(p.Y.Y),
+	}
+	return
+}
+
+// CheckPublicKey returns an error if the public key of the key share is not equal to pk.
+// It can be used with the output of any of the Parse functions of this package.
+func (p *KeyShare) CheckPublicKey(meta *KeyMeta, pk *ecdsa.PublicKey) error {
+	if p.Y == nil {
+		return fmt.Errorf("key share has not been set")
+	}
+	if pk.Curve.Params().Name != meta.Curve().Params().Name {
+		return fmt.Errorf("public key curve is %s, but key share curve is %s", pk.Curve.Params().Name, meta.CurveName)
+	}
+	if p.Y.Cmp(NewPoint(pk.X, pk.Y)) != 0 {
+		return fmt.Errorf("public key is different to key share public key")
+	}
+	return nil
+}
+
+// MarshalPKIX returns the PKIX ASN.1 DER encoding of the public key of the key share.
+// secp256k1 keys are not supported, because Go x509 package does not recognize that curve.
+func (p *KeyShare) MarshalPKIX(meta *KeyMeta) ([]byte, error) {
+	pk, err := p.PublicKey(meta)
+	if err != nil {
+		return nil, err
+	}
+	return x509.MarshalPKIXPublicKey(pk)
+}
+
+// MarshalPEM returns the PEM encoding of the PKIX public key of the key share.
+func (p *KeyShare) MarshalPEM(meta *KeyMeta) ([]byte, error) {
+	der, err := p.MarshalPKIX(meta)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: pemPublicKeyType, Bytes: der}), nil
+}
+
+// MarshalJWK returns the JSON Web Key encoding of the public key of the key share. Its kid is set to
+// the RFC 7638 thumbprint of the key.
+func (p *KeyShare) MarshalJWK(meta *KeyMeta) ([]byte, error) {
+	pk, err := p.PublicKey(meta)
+	if err != nil {
+		return nil, err
+	}
+	jwk, err := NewJWK(pk)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jwk)
+}
+
+// MarshalAuthorizedKey returns the public key of the key share as an OpenSSH authorized_keys line,
+// with an optional comment. Only P-256, P-384 and P-521 curves are supported by SSH.
+func (p *KeyShare) MarshalAuthorizedKey(meta *KeyMeta, comment string) ([]byte, error) {
+	pk, err := p.PublicKey(meta)
+	if err != nil {
+		return nil, err
+	}
+	sshKey, err := ssh.NewPublicKey(pk)
+	if err != nil {
+		return nil, err
+	}
+	line := bytes.TrimRight(ssh.MarshalAuthorizedKey(sshKey), "\n")
+	if comment != "" {
+		line = append(append(line, ' '), comment...)
+	}
+	return append(line, '\n'), nil
+}
+
+// MarshalSEC1 returns the SEC1 encoding of the public key of the key share, in compressed or
+// uncompressed form.
+func (p *KeyShare) MarshalSEC1(meta *KeyMeta, compressed bool) ([]byte, error) {
+	if p.Y == nil {
+		return nil, fmt.Errorf("key share has not been set")
+	}
+	if compressed {
+		return p.Y.CompressedBytes(meta.Curve()), nil
+	}
+	return p.Y.Bytes(meta.Curve()), nil
+}
+
+// NewJWK returns the JSON Web Key representation of a public key, with its thumbprint as kid.
+func NewJWK(pk *ecdsa.PublicKey) (jwk *JWK, err error) {
+	crv := pk.Curve.Params().Name
+	if _, ok := jwkCurves[crv]; !ok {
+		err = fmt.Errorf("curve %s is not supported by JWK", crv)
+		return
+	}
+	size := (pk.Curve.Params().BitSize + 7) / 8
+	jwk = &JWK{
+		Kty: "EC",
+		Crv: crv,
+		X:   base64.RawURLEncoding.EncodeToString(padBytes(pk.X, size)),
+		Y:   base64.RawURLEncoding.EncodeToString(padBytes(pk.Y, size)),
+	}
+	jwk.Kid = jwk.Thumbprint()
+	return
+}
+
+// Thumbprint returns the base64url encoded RFC 7638 SHA-256 thumbprint of the key.
+func (jwk *JWK) Thumbprint() string {
+	// Required members in lexicographic order, without whitespace.
+	canonical := fmt.Sprintf(`{"crv":"%s","kty":"%s","x":"%s","y":"%s"}`, jwk.Crv, jwk.Kty, jwk.X, jwk.Y)
+	sum := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// PublicKey returns the *ecdsa.PublicKey represented by the JWK, checking that it lies on the curve.
+func (jwk *JWK) PublicKey() (pk *ecdsa.PublicKey, err error) {
+	if jwk.Kty != "EC" {
+		err = fmt.Errorf("key type should be EC, but it is %s", jwk.Kty)
+		return
+	}
+	curve, ok := jwkCurves[jwk.Crv]
+	if !ok {
+		err = fmt.Errorf("curve %s is not supported by JWK", jwk.Crv)
+		return
+	}
+	x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	if err != nil {
+		return
+	}
+	y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+	if err != nil {
+		return
+	}
+	size := (curve.Params().BitSize + 7) / 8
+	if len(x) != size || len(y) != size {
+		err = fmt.Errorf("coordinates should be %d bytes long", size)
+		return
+	}
+	pk = &ecdsa.PublicKey{
+		Curve: curve,
+		X:     new(big.Int).SetBytes(x),
+		Y:     new(big.Int).SetBytes(y),
+	}
+	if !curve.IsOnCurve(pk.X, pk.Y) {
+		err = fmt.Errorf("point is not on curve %s", jwk.Crv)
+		return
+	}
+	return
+}
+
+// ParsePKIXPublicKey parses a PKIX ASN.1 DER encoded elliptic curve public key.
+func ParsePKIXPublicKey(der []byte) (pk *ecdsa.PublicKey, err error) {
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return
+	}
+	pk, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		err = fmt.Errorf("public key is not an elliptic curve key")
+		return
+	}
+	return
+}
+
+// ParsePEMPublicKey parses a PEM encoded PKIX elliptic curve public key.
+func ParsePEMPublicKey(b []byte) (pk *ecdsa.PublicKey, err error) {
+	block, _ := pem.Decode(b)
+	if block == nil || block.Type != pemPublicKeyType {
+		err = fmt.Errorf("cannot find a %s PEM block", pemPublicKeyType)
+		return
+	}
+	return ParsePKIXPublicKey(block.Bytes)
+}
+
+// ParseJWK parses a JSON encoded JSON Web Key. If the key has a kid, it must be equal to its thumbprint.
+func ParseJWK(b []byte) (pk *ecdsa.PublicKey, err error) {
+	var jwk JWK
+	if err = json.Unmarshal(b, &jwk); err != nil {
+		return
+	}
+	if jwk.Kid != "" && jwk.Kid != jwk.Thumbprint() {
+		err = fmt.Errorf("kid is not the thumbprint of the key")
+		return
+	}
+	return jwk.PublicKey()
+}
+
+// ParseAuthorizedKey parses an OpenSSH authorized_keys line with an ECDSA public key.
+func ParseAuthorizedKey(line []byte) (pk *ecdsa.PublicKey, err error) {
+	sshKey, _, _, _, err := ssh.ParseAuthorizedKey(line)
+	if err != nil {
+		return
+	}
+	cryptoKey, ok := sshKey.(ssh.CryptoPublicKey)
+	if !ok {
+		err = fmt.Errorf("cannot obtain public key from ssh key")
+		return
+	}
+	pk, ok = cryptoKey.CryptoPublicKey().(*ecdsa.PublicKey)
+	if !ok {
+		err = fmt.Errorf("ssh key is not an ECDSA key")
+		return
+	}
+	return
+}
+
+// ParseSEC1PublicKey parses a compressed or uncompressed SEC1 encoded public key over the curve
+// with the given name, checking that it lies on the curve.
+func ParseSEC1PublicKey(curveName string, b []byte) (pk *ecdsa.PublicKey, err error) {
+	curve, ok := CurveNameToCurve[curveName]
+	if !ok {
+		err = fmt.Errorf("curve with name %s unsupported", curveName)
+		return
+	}
+	point, err := NewZero().SetBytes(curve, b)
+	if err != nil {
+		return
+	}
+	if point.IsIdentity() {
+		err = fmt.Errorf("public key is the point at infinity")
+		return
+	}
+	pk = &ecdsa.PublicKey{
+		Curve: curve,
+		X:     point.X,
+		Y:     point.Y,
+	}
+	return
+}
+
+// jwkCurves maps the JWK curve names to the curves supported by JWK.
+var jwkCurves = map[string]elliptic.Curve{
+	"P-256":     elliptic.P256(),
+	"P-384":     elliptic.P384(),
+	"P-521":     elliptic.P521(),
+	"secp256k1": Secp256k1(),
+}
+
+// padBytes returns the big endian representation of x, left padded with zeros to size bytes.
+func padBytes(x *big.Int, size int) []byte {
+	b := make([]byte, size)
+	xBytes := x.Bytes()
+	copy(b[size-len(xBytes):], xBytes)
+	return b
+}
+
+// curvePolynomial returns x³ + a*x + b mod P, with a = 0 for secp256k1 and a = -3 for NIST curves.
+func curvePolynomial(curve elliptic.Curve, x *big.Int) *big.Int {
+	if k1, ok := curve.(*secp256k1Curve); ok {
+		return k1.polynomial(x)
+	}
+	params := curve.Params()
+	x3 := new(big.Int).Mul(x, x)
+	x3.Mul(x3, x)
+	threeX := new(big.Int).Lsh(x, 1)
+	threeX.Add(threeX, x)
+	x3.Sub(x3, threeX)
+	x3.Add(x3, params.B)
+	return x3.Mod(x3, params.P)
+}
@@ -0,0 +1,129 @@
+package tcecdsa_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"github.com/niclabs/tcecdsa"
+	"testing"
+)
+
+// newExportShare returns a key share with only its public key set, which is enough to export it.
+func newExportShare(curveName string) (share *tcecdsa.KeyShare, meta *tcecdsa.KeyMeta, err error) {
+	meta = &tcecdsa.KeyMeta{CurveName: curveName}
+	sk, err := ecdsa.GenerateKey(meta.Curve(), rand.Reader)
+	if err != nil {
+		return
+	}
+	share = &tcecdsa.KeyShare{
+		Y: tcecdsa.NewPoint(sk.X, sk.Y),
+	}
+	return
+}
+
+func TestKeyShare_MarshalPEM(t *testing.T) {
+	share, meta, err := newExportShare("P-256")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	b, err := share.MarshalPEM(meta)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	pk, err := tcecdsa.ParsePEMPublicKey(b)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if err := share.CheckPublicKey(meta, pk); err != nil {
+		t.Error(err)
+		return
+	}
+}
+
+func TestKeyShare_MarshalJWK(t *testing.T) {
+	for _, curveName := range []string{"P-256", "P-384", "P-521", "secp256k1"} {
+		share, meta, err := newExportShare(curveName)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		b, err := share.MarshalJWK(meta)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		pk, err := tcecdsa.ParseJWK(b)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if err := share.CheckPublicKey(meta, pk); err != nil {
+			t.Errorf("%s: %s", curveName, err)
+			return
+		}
+	}
+}
+
+func TestJWK_Thumbprint(t *testing.T) {
+	// Public key taken from RFC 7517 Appendix A.1.
+	jwk := &tcecdsa.JWK{
+		Kty: "EC",
+		Crv: "P-256",
+		X:   "f83OJ3D2xF1Bg8vub9tLe1gHMzV76e8Tus9uPHvRVEU",
+		Y:   "x_FEzRu9m36HLN_tue659LNpXW6pCyStikYjKIWI5a0",
+	}
+	if jwk.Thumbprint() != "oKIywvGUpTVTyxMQ3bwIIeQUudfr_CkLMjCE19ECD-U" {
+		t.Errorf("unexpected thumbprint %s", jwk.Thumbprint())
+		return
+	}
+}
+
+func TestKeyShare_MarshalAuthorizedKey(t *testing.T) {
+	share, meta, err := newExportShare("P-384")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	line, err := share.MarshalAuthorizedKey(meta, "tcecdsa")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	pk, err := tcecdsa.ParseAuthorizedKey(line)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if err := share.CheckPublicKey(meta, pk); err != nil {
+		t.Error(err)
+		return
+	}
+}
+
+func TestKeyShare_MarshalSEC1(t *testing.T) {
+	for _, curveName := range []string{"P-224", "P-256", "P-384", "P-521", "secp256k1"} {
+		share, meta, err := newExportShare(curveName)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		for _, compressed := range []bool{true, false} {
+			b, err := share.MarshalSEC1(meta, compressed)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			pk, err := tcecdsa.ParseSEC1PublicKey(curveName, b)
+			if err != nil {
+				t.Errorf("%s: %s", curveName, err)
+				return
+			}
+			if err := share.CheckPublicKey(meta, pk); err != nil {
+				t.Errorf("%s: %s", curveName, err)
+				return
+			}
+		}
+	}
+}
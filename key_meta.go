@@ -6,17 +6,22 @@ import (
 	"fmt"
 	"github.com/niclabs/tcecdsa/l2fhe"
 	"math/big"
+	"runtime"
+	"sync"
 )
 
 // KeyMeta represents a set of parameters that are used by every key share.
 type KeyMeta struct {
-	*l2fhe.PubKey                 // L2FHE Public Key
-	*ZKProofMeta                   // Parameters used by ZK Proofs
+	*l2fhe.PubKey                // L2FHE Public Key
+	*ZKProofMeta                 // Parameters used by ZK Proofs
 	curve         elliptic.Curve // Elliptic curve used by the signing protocol
 	CurveName     string
+	VerifyWorkers int              `json:"-"` // Maximum number of proofs verified concurrently by Join methods. If it is 0, GOMAXPROCS is used
+	BatchVerify   bool             `json:"-"` // If it is true, the Join methods of Round1, Round2 and Round3 message lists verify the proofs with VerifyBatch
+	tables        *fixedBaseTables // Fixed-base tables, built lazily by fixedBases
+	tablesOnce    sync.Once
 }
 
-
 func (meta *KeyMeta) Curve() elliptic.Curve {
 	if meta.curve == nil {
 		curve, ok := CurveNameToCurve[meta.CurveName]
@@ -36,8 +41,9 @@ func (meta *KeyMeta) Q() *big.Int {
 // G returns the base point of the curve, as a *Point.
 func (meta *KeyMeta) G() *Point {
 	return &Point{
-		X: new(big.Int).Set(meta.Curve().Params().Gx),
-		Y: new(big.Int).Set(meta.Curve().Params().Gy),
+		X:     new(big.Int).Set(meta.Curve().Params().Gx),
+		Y:     new(big.Int).Set(meta.Curve().Params().Gy),
+		curve: meta.Curve(),
 	}
 }
 
@@ -54,3 +60,54 @@ func (meta *KeyMeta) GetPublicKey(msgs KeyInitMessageList) (pk *ecdsa.PublicKey,
 	}
 	return
 }
+
+// verifyAll calls verify with every index from 0 to n-1, using at most VerifyWorkers goroutines, and returns
+// the errors by index, so they can be reported in the same order as a sequential verification.
+func (meta *KeyMeta) verifyAll(n int, verify func(i int) error) []error {
+	errs := make([]error, n)
+	workers := meta.VerifyWorkers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > n {
+		workers = n
+	}
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			errs[i] = recoverVerify(verify, i)
+		}
+		return errs
+	}
+	// The curve and the Paillier cache are set lazily, so they are set before using them concurrently.
+	meta.Curve()
+	meta.Paillier.Cache()
+	meta.fixedBases()
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				errs[i] = recoverVerify(verify, i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return errs
+}
+
+// recoverVerify calls verify with the index, returning as an error any panic caused by a malformed message,
+// so it cannot crash a verification worker.
+func recoverVerify(verify func(i int) error, i int) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("malformed message: %v", r)
+		}
+	}()
+	return verify(i)
+}
@@ -18,12 +18,13 @@ type KeyShare struct {
 // Init generates the needed initial parameters and creates the KeyInitMessage that needs to be
 // broadcasted to other participants.
 func (p *KeyShare) Init(meta *KeyMeta) (msg *KeyInitMessage, err error) {
+	defer startSpan(SpanKeyInit).end(&err)
 	var r *big.Int
 	xi, err := RandomFieldElement(meta.Curve())
 	if err != nil {
 		return
 	}
-	yi := NewZero().BaseMul(meta.Curve(), xi)
+	yi := meta.baseMul(xi)
 	alphai, r, err := meta.Encrypt(xi)
 	if err != nil {
 		return
@@ -43,7 +44,8 @@ func (p *KeyShare) Init(meta *KeyMeta) (msg *KeyInitMessage, err error) {
 // SetKey sets the key to a keyshare based on the messages of other nodes.
 // It defines the Alpha (encrypted private key) and Y (public key) values.
 // It returns an error if it cannot join the signatures.
-func (p *KeyShare) SetKey(meta *KeyMeta, msgs KeyInitMessageList) error {
+func (p *KeyShare) SetKey(meta *KeyMeta, msgs KeyInitMessageList) (err error) {
+	defer startSpan(SpanKeySet).end(&err)
 	alpha, g, err := msgs.Join(meta)
 	if err != nil {
 		return err
@@ -0,0 +1,369 @@
+// Package keystore stores key shares and their key metadata on disk, encrypted with a key derived from a passphrase.
+// Every key is stored in its own file, named after the key ID, on the keystore directory. The files are written
+// atomically and the access to the directory is protected with a file lock, so several processes can use the same
+// keystore.
+package keystore
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"github.com/niclabs/tcecdsa"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The following consts are the default scrypt parameters used to derive the keys of new files.
+const (
+	DefaultScryptN = 1 << 15
+	DefaultScryptR = 8
+	DefaultScryptP = 1
+)
+
+// The following consts limit the scrypt parameters accepted from a key file, so a modified file cannot
+// make Load use too much memory or time.
+const (
+	maxScryptN = 1 << 22
+	maxScryptR = 32
+	maxScryptP = 16
+)
+
+// fileVersion is the version of the key file format. Version 2 added the public information of the key,
+// authenticated with the ciphertext.
+const fileVersion = 2
+
+// legacyFileVersion is the previous version of the key file format, without public information. Its files can
+// still be loaded, and are upgraded when they are stored again.
+const legacyFileVersion = 1
+
+// fileExt is the extension of the key files.
+const fileExt = ".key"
+
+// lockName is the name of the lock file of the keystore directory.
+const lockName = ".lock"
+
+// saltSize is the size in bytes of the scrypt salt.
+const saltSize = 32
+
+// validID matches the key IDs that can be used as file names.
+var validID = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9_.-]{0,127}$`)
+
+// Keystore represents a directory with encrypted key shares.
+type Keystore struct {
+	ScryptN, ScryptR, ScryptP int // scrypt parameters used to derive the keys of new files
+	dir                       string
+	passphrase                []byte
+}
+
+// keyFile is the JSON encoding of a key file.
+type keyFile struct {
+	Version    int        `json:"version"`
+	ID         string     `json:"id"`
+	KDF        string     `json:"kdf"`
+	N          int        `json:"n"`
+	R          int        `json:"r"`
+	P          int        `json:"p"`
+	Info       *ShareInfo `json:"info,omitempty"` // Public information of the key, authenticated with the ciphertext
+	Salt       []byte     `json:"salt"`
+	Nonce      []byte     `json:"nonce"`
+	Ciphertext []byte     `json:"ciphertext"`
+}
+
+// storedKey is the plaintext encrypted on a key file.
+type storedKey struct {
+	Share *tcecdsa.KeyShare
+	Meta  *tcecdsa.KeyMeta
+}
+
+// Open returns the keystore on the directory, creating the directory if it does not exist.
+// The passphrase is used to encrypt and decrypt every key of the keystore.
+func Open(dir string, passphrase []byte) (ks *Keystore, err error) {
+	if len(passphrase) == 0 {
+		err = fmt.Errorf("passphrase cannot be empty")
+		return
+	}
+	if err = os.MkdirAll(dir, 0700); err != nil {
+		return
+	}
+	ks = &Keystore{
+		ScryptN:    DefaultScryptN,
+		ScryptR:    DefaultScryptR,
+		ScryptP:    DefaultScryptP,
+		dir:        dir,
+		passphrase: append([]byte{}, passphrase...),
+	}
+	return
+}
+
+// Store encrypts and stores a key share and its key metadata with the given ID, replacing the key with the
+// same ID if it exists.
+func (ks *Keystore) Store(id string, share *tcecdsa.KeyShare, meta *tcecdsa.KeyMeta) (err error) {
+	if err = checkID(id); err != nil {
+		return
+	}
+	info, err := newShareInfo(id, share, meta, time.Now())
+	if err != nil {
+		return
+	}
+	plaintext, err := json.Marshal(&storedKey{Share: share, Meta: meta})
+	if err != nil {
+		return
+	}
+	file := &keyFile{
+		Version: fileVersion,
+		ID:      id,
+		KDF:     "scrypt",
+		N:       ks.ScryptN,
+		R:       ks.ScryptR,
+		P:       ks.ScryptP,
+		Info:    info,
+		Salt:    make([]byte, saltSize),
+		Nonce:   make([]byte, chacha20poly1305.NonceSize),
+	}
+	if _, err = io.ReadFull(rand.Reader, file.Salt); err != nil {
+		return
+	}
+	if _, err = io.ReadFull(rand.Reader, file.Nonce); err != nil {
+		return
+	}
+	aead, err := ks.aead(file)
+	if err != nil {
+		return
+	}
+	unlock, err := lock(filepath.Join(ks.dir, lockName), true)
+	if err != nil {
+		return
+	}
+	defer unlock()
+	// The creation time of a replaced key is preserved.
+	if old, err := ks.readFile(id); err == nil && old.Info != nil {
+		file.Info.Created = old.Info.Created
+	}
+	file.Ciphertext = aead.Seal(nil, file.Nonce, plaintext, file.additionalData())
+	b, err := json.Marshal(file)
+	if err != nil {
+		return
+	}
+	return writeFileAtomic(ks.path(id), b)
+}
+
+// Load decrypts and returns the key share and key metadata stored with the given ID.
+func (ks *Keystore) Load(id string) (share *tcecdsa.KeyShare, meta *tcecdsa.KeyMeta, err error) {
+	if err = checkID(id); err != nil {
+		return
+	}
+	unlock, err := lock(filepath.Join(ks.dir, lockName), false)
+	if err != nil {
+		return
+	}
+	file, err := ks.readFile(id)
+	unlock()
+	if err != nil {
+		return
+	}
+	return ks.open(file)
+}
+
+// open decrypts the key share and key metadata of a key file.
+func (ks *Keystore) open(file *keyFile) (share *tcecdsa.KeyShare, meta *tcecdsa.KeyMeta, err error) {
+	if file.N <= 1 || file.N > maxScryptN ||
+		file.R <= 0 || file.R > maxScryptR ||
+		file.P <= 0 || file.P > maxScryptP ||
+		len(file.Nonce) != chacha20poly1305.NonceSize {
+		err = fmt.Errorf("invalid key file parameters")
+		return
+	}
+	aead, err := ks.aead(file)
+	if err != nil {
+		return
+	}
+	plaintext, err := aead.Open(nil, file.Nonce, file.Ciphertext, file.additionalData())
+	if err != nil {
+		err = fmt.Errorf("wrong passphrase or corrupted key file")
+		return
+	}
+	var key storedKey
+	if err = json.Unmarshal(plaintext, &key); err != nil {
+		return
+	}
+	if key.Share == nil || key.Meta == nil {
+		err = fmt.Errorf("key file does not contain a key share and its metadata")
+		return
+	}
+	return key.Share, key.Meta, nil
+}
+
+// Info returns the public information of the key stored with the given ID. It is read without the
+// passphrase, so it is authenticated only when the key is loaded. Legacy files do not have public information,
+// so it is computed from their decrypted key, using the modification time of the file as creation time.
+func (ks *Keystore) Info(id string) (info *ShareInfo, err error) {
+	if err = checkID(id); err != nil {
+		return
+	}
+	unlock, err := lock(filepath.Join(ks.dir, lockName), false)
+	if err != nil {
+		return
+	}
+	defer unlock()
+	file, err := ks.readFile(id)
+	if err != nil {
+		return
+	}
+	if file.Version == legacyFileVersion {
+		return ks.legacyInfo(file)
+	}
+	return file.Info, nil
+}
+
+// legacyInfo returns the public information of a legacy key file.
+func (ks *Keystore) legacyInfo(file *keyFile) (info *ShareInfo, err error) {
+	stat, err := os.Stat(ks.path(file.ID))
+	if err != nil {
+		return
+	}
+	share, meta, err := ks.open(file)
+	if err != nil {
+		return
+	}
+	return newShareInfo(file.ID, share, meta, stat.ModTime())
+}
+
+// List returns the IDs of the stored keys, sorted alphabetically.
+func (ks *Keystore) List() (ids []string, err error) {
+	unlock, err := lock(filepath.Join(ks.dir, lockName), false)
+	if err != nil {
+		return
+	}
+	defer unlock()
+	infos, err := ioutil.ReadDir(ks.dir)
+	if err != nil {
+		return
+	}
+	ids = make([]string, 0)
+	for _, info := range infos {
+		name := info.Name()
+		if info.Mode().IsRegular() && strings.HasSuffix(name, fileExt) {
+			ids = append(ids, strings.TrimSuffix(name, fileExt))
+		}
+	}
+	sort.Strings(ids)
+	return
+}
+
+// Delete removes the key stored with the given ID.
+func (ks *Keystore) Delete(id string) (err error) {
+	if err = checkID(id); err != nil {
+		return
+	}
+	unlock, err := lock(filepath.Join(ks.dir, lockName), true)
+	if err != nil {
+		return
+	}
+	defer unlock()
+	err = os.Remove(ks.path(id))
+	if os.IsNotExist(err) {
+		err = fmt.Errorf("key %s not found", id)
+	}
+	return
+}
+
+// readFile reads and decodes the key file with the given ID, checking its version and ID.
+func (ks *Keystore) readFile(id string) (file *keyFile, err error) {
+	b, err := ioutil.ReadFile(ks.path(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = fmt.Errorf("key %s not found", id)
+		}
+		return
+	}
+	file = &keyFile{}
+	if err = json.Unmarshal(b, file); err != nil {
+		return
+	}
+	if (file.Version != fileVersion && file.Version != legacyFileVersion) || file.KDF != "scrypt" {
+		err = fmt.Errorf("unsupported key file version %d with kdf %s", file.Version, file.KDF)
+		return
+	}
+	if file.ID != id {
+		err = fmt.Errorf("key file of %s has id %s", id, file.ID)
+		return
+	}
+	if file.Version == legacyFileVersion {
+		return
+	}
+	if file.Info == nil || file.Info.ID != id {
+		err = fmt.Errorf("key file of %s has invalid info", id)
+		return
+	}
+	return
+}
+
+// path returns the path of the key file with the given ID.
+func (ks *Keystore) path(id string) string {
+	return filepath.Join(ks.dir, id+fileExt)
+}
+
+// aead returns the AEAD cipher of a key file, using the key derived from the passphrase and the file parameters.
+func (ks *Keystore) aead(file *keyFile) (aead cipher.AEAD, err error) {
+	key, err := scrypt.Key(ks.passphrase, file.Salt, file.N, file.R, file.P, chacha20poly1305.KeySize)
+	if err != nil {
+		return
+	}
+	return chacha20poly1305.New(key)
+}
+
+// additionalData returns the data of the key file authenticated with its ciphertext.
+func (file *keyFile) additionalData() []byte {
+	if file.Version == legacyFileVersion {
+		return []byte(fmt.Sprintf("tcecdsa keystore v%d|%s|%s|%d|%d|%d", file.Version, file.ID, file.KDF, file.N, file.R, file.P))
+	}
+	return []byte(fmt.Sprintf("tcecdsa keystore v%d|%s|%s|%d|%d|%d|%s|%d|%d|%d|%s",
+		file.Version, file.ID, file.KDF, file.N, file.R, file.P,
+		file.Info.Curve, file.Info.L, file.Info.K, file.Info.Index, file.Info.Created.UTC().Format(time.RFC3339Nano)))
+}
+
+// checkID returns an error if the key ID cannot be used as a file name.
+func checkID(id string) error {
+	if !validID.MatchString(id) {
+		return fmt.Errorf("invalid key id %q", id)
+	}
+	return nil
+}
+
+// writeFileAtomic writes the data to a temporary file on the same directory and then renames it to the path,
+// so readers never see a partially written file.
+func writeFileAtomic(path string, data []byte) (err error) {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return
+	}
+	if err = tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return
+	}
+	if err = tmp.Close(); err != nil {
+		return
+	}
+	if err = os.Chmod(tmp.Name(), 0600); err != nil {
+		return
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,244 @@
+package keystore_test
+
+import (
+	"encoding/json"
+	"github.com/niclabs/tcecdsa"
+	"github.com/niclabs/tcecdsa/keystore"
+	"github.com/niclabs/tcpaillier"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Fixed Paillier safe primes, used to create P-256 keys quickly.
+var p, _ = new(big.Int).SetString("162030756613868056036796139443129843350161050018433499392928747997121062437582359318413363541522945906190207466120420228562417550301608423503357625771890911679824491415226779457890365803241891222854531746857783300396174418015617143548507580468476733354470400390478102905037674080534856277049485137103579989863", 10)
+var p1, _ = new(big.Int).SetString("81015378306934028018398069721564921675080525009216749696464373998560531218791179659206681770761472953095103733060210114281208775150804211751678812885945455839912245707613389728945182901620945611427265873428891650198087209007808571774253790234238366677235200195239051452518837040267428138524742568551789994931", 10)
+var q, _ = new(big.Int).SetString("159975921947566124275751141960173316363328143247656508475307658287517469877224261792980903103070840619508184653128931497290385293427952440650499163739617129593096277904296127366192478731140870002479392523096204767026217812680694360672967022941744954338916462708021769441227741014190260209604543853752760232687", 10)
+var q1, _ = new(big.Int).SetString("79987960973783062137875570980086658181664071623828254237653829143758734938612130896490451551535420309754092326564465748645192646713976220325249581869808564796548138952148063683096239365570435001239696261548102383513108906340347180336483511470872477169458231354010884720613870507095130104802271926876380116343", 10)
+
+// newKeystore returns a keystore on a temporary directory, using fast scrypt parameters.
+func newKeystore(t *testing.T, passphrase string) (ks *keystore.Keystore, dir string) {
+	dir, err := ioutil.TempDir("", "tcecdsa-keystore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks, err = keystore.Open(dir, []byte(passphrase))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks.ScryptN = 1 << 10
+	return
+}
+
+// newShares returns the key shares of a new P-256 key.
+func newShares(t *testing.T) ([]*tcecdsa.KeyShare, *tcecdsa.KeyMeta) {
+	shares, meta, err := tcecdsa.NewKey(3, 2, "P-256", &tcecdsa.NewKeyParams{
+		PaillierFixed: &tcpaillier.FixedParams{P: p, P1: p1, Q: q, Q1: q1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return shares, meta
+}
+
+func TestKeystore(t *testing.T) {
+	ks, dir := newKeystore(t, "correct horse battery staple")
+	defer os.RemoveAll(dir)
+	shares, meta := newShares(t)
+
+	t.Run("StoreAndLoad", func(t *testing.T) {
+		if err := ks.Store("key-1", shares[1], meta); err != nil {
+			t.Error(err)
+			return
+		}
+		share, loadedMeta, err := ks.Load("key-1")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if share.Index != shares[1].Index ||
+			share.PaillierShare.Si.Cmp(shares[1].PaillierShare.Si) != 0 ||
+			share.PaillierShare.N.Cmp(shares[1].PaillierShare.N) != 0 {
+			t.Error("loaded key share is different to stored one")
+			return
+		}
+		if loadedMeta.CurveName != meta.CurveName ||
+			loadedMeta.NTilde.Cmp(meta.NTilde) != 0 ||
+			loadedMeta.Paillier.N.Cmp(meta.Paillier.N) != 0 {
+			t.Error("loaded key meta is different to stored one")
+			return
+		}
+		b, err := ioutil.ReadFile(filepath.Join(dir, "key-1.key"))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if strings.Contains(string(b), shares[1].PaillierShare.Si.String()) {
+			t.Error("key file contains the plaintext key share")
+			return
+		}
+	})
+
+	t.Run("WrongPassphrase", func(t *testing.T) {
+		other, err := keystore.Open(dir, []byte("wrong"))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if _, _, err := other.Load("key-1"); err == nil {
+			t.Error("key should not be loaded with a wrong passphrase")
+			return
+		}
+	})
+
+	t.Run("RenamedFile", func(t *testing.T) {
+		if err := os.Link(filepath.Join(dir, "key-1.key"), filepath.Join(dir, "renamed.key")); err != nil {
+			t.Error(err)
+			return
+		}
+		defer os.Remove(filepath.Join(dir, "renamed.key"))
+		if _, _, err := ks.Load("renamed"); err == nil {
+			t.Error("key file should not be loaded with another id")
+			return
+		}
+	})
+
+	t.Run("LegacyFile", func(t *testing.T) {
+		plaintext, err := json.Marshal(struct {
+			Share *tcecdsa.KeyShare
+			Meta  *tcecdsa.KeyMeta
+		}{shares[2], meta})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		salt, nonce := make([]byte, 32), make([]byte, chacha20poly1305.NonceSize)
+		key, err := scrypt.Key([]byte("correct horse battery staple"), salt, 1<<10, 8, 1, chacha20poly1305.KeySize)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		aead, err := chacha20poly1305.New(key)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		b, err := json.Marshal(map[string]interface{}{
+			"version":    1,
+			"id":         "legacy",
+			"kdf":        "scrypt",
+			"n":          1 << 10,
+			"r":          8,
+			"p":          1,
+			"salt":       salt,
+			"nonce":      nonce,
+			"ciphertext": aead.Seal(nil, nonce, plaintext, []byte("tcecdsa keystore v1|legacy|scrypt|1024|8|1")),
+		})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, "legacy.key"), b, 0600); err != nil {
+			t.Error(err)
+			return
+		}
+		defer ks.Delete("legacy")
+		share, _, err := ks.Load("legacy")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if share.Index != shares[2].Index {
+			t.Error("loaded key share is different to stored one")
+			return
+		}
+		info, err := ks.Info("legacy")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if info.ID != "legacy" || info.Index != shares[2].Index {
+			t.Errorf("unexpected info %+v", info)
+			return
+		}
+		if err := ks.Store("legacy", share, meta); err != nil {
+			t.Error(err)
+			return
+		}
+		if _, _, err := ks.Load("legacy"); err != nil {
+			t.Error(err)
+			return
+		}
+	})
+
+	t.Run("InvalidID", func(t *testing.T) {
+		for _, id := range []string{"", "../key-1", ".lock", "a/b"} {
+			if err := ks.Store(id, shares[0], meta); err == nil {
+				t.Errorf("key should not be stored with id %q", id)
+				return
+			}
+		}
+	})
+
+	t.Run("ConcurrentStore", func(t *testing.T) {
+		var wg sync.WaitGroup
+		errs := make([]error, len(shares))
+		for i, share := range shares {
+			wg.Add(1)
+			go func(i int, share *tcecdsa.KeyShare) {
+				defer wg.Done()
+				errs[i] = ks.Store("concurrent", share, meta)
+			}(i, share)
+		}
+		wg.Wait()
+		for _, err := range errs {
+			if err != nil {
+				t.Error(err)
+				return
+			}
+		}
+		if _, _, err := ks.Load("concurrent"); err != nil {
+			t.Error(err)
+			return
+		}
+	})
+
+	t.Run("ListAndDelete", func(t *testing.T) {
+		ids, err := ks.List()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if strings.Join(ids, ",") != "concurrent,key-1" {
+			t.Errorf("unexpected key list %v", ids)
+			return
+		}
+		if err := ks.Delete("key-1"); err != nil {
+			t.Error(err)
+			return
+		}
+		if _, _, err := ks.Load("key-1"); err == nil {
+			t.Error("deleted key should not be loaded")
+			return
+		}
+		if err := ks.Delete("key-1"); err == nil {
+			t.Error("deleting a missing key should fail")
+			return
+		}
+		ids, err = ks.List()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if strings.Join(ids, ",") != "concurrent" {
+			t.Errorf("unexpected key list %v", ids)
+			return
+		}
+	})
+}
@@ -0,0 +1,31 @@
+//go:build !windows
+// +build !windows
+
+package keystore
+
+import (
+	"os"
+	"syscall"
+)
+
+// lock locks the file on the path, creating it if it does not exist, and returns a function that unlocks it.
+// The lock is exclusive or shared, and it waits until the lock can be obtained.
+func lock(path string, exclusive bool) (unlock func(), err error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return
+	}
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	if err = syscall.Flock(int(f.Fd()), how); err != nil {
+		_ = f.Close()
+		return
+	}
+	unlock = func() {
+		_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		_ = f.Close()
+	}
+	return
+}
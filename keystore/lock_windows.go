@@ -0,0 +1,34 @@
+//go:build windows
+// +build windows
+
+package keystore
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// lockTimeout is the time lock waits for a lock file to be removed.
+const lockTimeout = 30 * time.Second
+
+// lock creates the file on the path exclusively, waiting until it does not exist, and returns a function
+// that removes it. Shared locks are not supported, so every lock is exclusive.
+func lock(path string, _ bool) (unlock func(), err error) {
+	path += ".excl"
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0600)
+		if err == nil {
+			_ = f.Close()
+			return func() { _ = os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("cannot lock keystore: %s exists", path)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,174 @@
+package keystore
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/niclabs/tcecdsa"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ShareInfo represents the public information of a stored key share.
+type ShareInfo struct {
+	ID      string    `json:"id"`
+	Curve   string    `json:"curve"`
+	L       uint8     `json:"l"`       // Number of participants
+	K       uint8     `json:"k"`       // Threshold
+	Index   uint8     `json:"index"`   // Participant Index of the key share
+	Created time.Time `json:"created"` // Time the key was stored for the first time
+}
+
+// ShareStore represents a storage backend for key shares and their key metadata, indexed by key ID.
+type ShareStore interface {
+	// Put stores a key share and its key metadata with the given ID, replacing the key with the same ID if
+	// it exists. The creation time of a replaced key is preserved.
+	Put(id string, share *tcecdsa.KeyShare, meta *tcecdsa.KeyMeta) error
+	// Get returns the key share and key metadata stored with the given ID.
+	Get(id string) (*tcecdsa.KeyShare, *tcecdsa.KeyMeta, error)
+	// List returns the information of the stored keys, sorted by ID.
+	List() ([]*ShareInfo, error)
+	// Delete removes the key stored with the given ID.
+	Delete(id string) error
+}
+
+// newShareInfo returns the information of a key share.
+func newShareInfo(id string, share *tcecdsa.KeyShare, meta *tcecdsa.KeyMeta, created time.Time) (info *ShareInfo, err error) {
+	if share == nil || meta == nil || meta.PubKey == nil || meta.Paillier == nil {
+		err = fmt.Errorf("key share and its key metadata must be set")
+		return
+	}
+	info = &ShareInfo{
+		ID:      id,
+		Curve:   meta.CurveName,
+		L:       meta.Paillier.L,
+		K:       meta.Paillier.K,
+		Index:   share.Index,
+		Created: created.UTC(),
+	}
+	return
+}
+
+// MemoryStore is a ShareStore that keeps the keys in memory. It is intended for tests.
+// The keys are stored JSON encoded, so the values stored and returned are not shared with the caller.
+type MemoryStore struct {
+	mutex sync.Mutex
+	keys  map[string]*memoryKey
+}
+
+// memoryKey is a key stored in a MemoryStore.
+type memoryKey struct {
+	info *ShareInfo
+	key  []byte
+}
+
+// NewMemoryStore returns a new empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		keys: make(map[string]*memoryKey),
+	}
+}
+
+// Put stores a key share and its key metadata with the given ID.
+func (ms *MemoryStore) Put(id string, share *tcecdsa.KeyShare, meta *tcecdsa.KeyMeta) error {
+	if err := checkID(id); err != nil {
+		return err
+	}
+	info, err := newShareInfo(id, share, meta, time.Now())
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(&storedKey{Share: share, Meta: meta})
+	if err != nil {
+		return err
+	}
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+	if old, ok := ms.keys[id]; ok {
+		info.Created = old.info.Created
+	}
+	ms.keys[id] = &memoryKey{info: info, key: b}
+	return nil
+}
+
+// Get returns the key share and key metadata stored with the given ID.
+func (ms *MemoryStore) Get(id string) (*tcecdsa.KeyShare, *tcecdsa.KeyMeta, error) {
+	ms.mutex.Lock()
+	stored, ok := ms.keys[id]
+	ms.mutex.Unlock()
+	if !ok {
+		return nil, nil, fmt.Errorf("key %s not found", id)
+	}
+	var key storedKey
+	if err := json.Unmarshal(stored.key, &key); err != nil {
+		return nil, nil, err
+	}
+	return key.Share, key.Meta, nil
+}
+
+// List returns the information of the stored keys, sorted by ID.
+func (ms *MemoryStore) List() ([]*ShareInfo, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+	infos := make([]*ShareInfo, 0, len(ms.keys))
+	for _, stored := range ms.keys {
+		info := *stored.info
+		infos = append(infos, &info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ID < infos[j].ID
+	})
+	return infos, nil
+}
+
+// Delete removes the key stored with the given ID.
+func (ms *MemoryStore) Delete(id string) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+	if _, ok := ms.keys[id]; !ok {
+		return fmt.Errorf("key %s not found", id)
+	}
+	delete(ms.keys, id)
+	return nil
+}
+
+// FileStore is a ShareStore that keeps the keys encrypted on a Keystore directory.
+type FileStore struct {
+	*Keystore
+}
+
+// NewFileStore returns a new FileStore on the directory, creating it if it does not exist.
+func NewFileStore(dir string, passphrase []byte) (*FileStore, error) {
+	ks, err := Open(dir, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return &FileStore{ks}, nil
+}
+
+// Put stores a key share and its key metadata with the given ID.
+func (fs *FileStore) Put(id string, share *tcecdsa.KeyShare, meta *tcecdsa.KeyMeta) error {
+	return fs.Store(id, share, meta)
+}
+
+// Get returns the key share and key metadata stored with the given ID.
+func (fs *FileStore) Get(id string) (*tcecdsa.KeyShare, *tcecdsa.KeyMeta, error) {
+	return fs.Load(id)
+}
+
+// List returns the information of the stored keys, sorted by ID.
+func (fs *FileStore) List() ([]*ShareInfo, error) {
+	ids, err := fs.Keystore.List()
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]*ShareInfo, 0, len(ids))
+	for _, id := range ids {
+		info, err := fs.Info(id)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
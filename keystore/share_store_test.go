@@ -0,0 +1,91 @@
+package keystore_test
+
+import (
+	"github.com/niclabs/tcecdsa/keystore"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestShareStore(t *testing.T) {
+	ks, dir := newKeystore(t, "passphrase")
+	defer os.RemoveAll(dir)
+	stores := map[string]keystore.ShareStore{
+		"Memory": keystore.NewMemoryStore(),
+		"File":   &keystore.FileStore{Keystore: ks},
+	}
+	shares, meta := newShares(t)
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			before := time.Now().Add(-time.Second)
+			for _, id := range []string{"b", "a"} {
+				if err := store.Put(id, shares[2], meta); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+			infos, err := store.List()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if len(infos) != 2 || infos[0].ID != "a" || infos[1].ID != "b" {
+				t.Errorf("unexpected key list %v", infos)
+				return
+			}
+			info := infos[0]
+			if info.Curve != "P-256" || info.L != 3 || info.K != 2 || info.Index != 2 {
+				t.Errorf("unexpected key info %+v", info)
+				return
+			}
+			if info.Created.Before(before) || info.Created.After(time.Now()) {
+				t.Errorf("unexpected creation time %s", info.Created)
+				return
+			}
+
+			// Replacing a key preserves its creation time.
+			if err := store.Put("a", shares[1], meta); err != nil {
+				t.Error(err)
+				return
+			}
+			infos, err = store.List()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if infos[0].Index != 1 || !infos[0].Created.Equal(info.Created) {
+				t.Errorf("unexpected replaced key info %+v", infos[0])
+				return
+			}
+			share, loadedMeta, err := store.Get("a")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if share.Index != 1 || share.PaillierShare.Si.Cmp(shares[1].PaillierShare.Si) != 0 || loadedMeta.CurveName != "P-256" {
+				t.Error("loaded key share is different to stored one")
+				return
+			}
+			share.Index = 4
+			if share, _, _ := store.Get("a"); share.Index != 1 {
+				t.Error("stored key share should not change when a loaded one is modified")
+				return
+			}
+
+			for _, id := range []string{"a", "b"} {
+				if err := store.Delete(id); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+			if _, _, err := store.Get("a"); err == nil {
+				t.Error("deleted key should not be loaded")
+				return
+			}
+			if infos, err := store.List(); err != nil || len(infos) != 0 {
+				t.Errorf("store should be empty, but it has %v (%v)", infos, err)
+				return
+			}
+		})
+	}
+}
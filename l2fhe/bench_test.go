@@ -0,0 +1,222 @@
+package l2fhe_test
+
+import (
+	"flag"
+	"fmt"
+	"github.com/niclabs/tcecdsa/internal/benchparams"
+	"github.com/niclabs/tcecdsa/l2fhe"
+	"github.com/niclabs/tcpaillier"
+	"math/big"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var benchBits = flag.String("bench.bits", "224,256", "comma separated message bit sizes (curve bit sizes) used by the benchmarks. Sizes without fixed Paillier parameters generate them, which can take a long time")
+var benchSizes = flag.String("bench.sizes", "3/2,5/3,7/4", "comma separated L/K configurations used by the benchmarks")
+
+// benchConfig represents a message bit size and a (L, K) configuration of the benchmarks.
+type benchConfig struct {
+	bits int
+	l, k uint8
+}
+
+// benchFixture represents a key and a set of encrypted values, shared by the benchmarks of a configuration.
+type benchFixture struct {
+	pk         *l2fhe.PubKey
+	shares     []*tcpaillier.KeyShare
+	c1, c2     *l2fhe.EncryptedL1
+	l2         *l2fhe.EncryptedL2
+	sharesL1   []*l2fhe.DecryptedShareL1
+	sharesL2   []*l2fhe.DecryptedShareL2
+	zkL2       *l2fhe.DecryptedShareL2ZK
+	multiplier *big.Int
+}
+
+var benchFixtures = struct {
+	sync.Mutex
+	m map[benchConfig]*benchFixture
+}{m: make(map[benchConfig]*benchFixture)}
+
+// String returns the name of the sub-benchmark of the configuration.
+func (cfg benchConfig) String() string {
+	return fmt.Sprintf("bits=%d/L=%d/K=%d", cfg.bits, cfg.l, cfg.k)
+}
+
+// runConfigs runs a sub-benchmark with the fixture of each configuration set with the -bench.bits and
+// -bench.sizes flags.
+func runConfigs(b *testing.B, bench func(b *testing.B, fx *benchFixture)) {
+	for _, bits := range strings.Split(*benchBits, ",") {
+		for _, size := range strings.Split(*benchSizes, ",") {
+			var cfg benchConfig
+			if _, err := fmt.Sscanf(bits+"/"+size, "%d/%d/%d", &cfg.bits, &cfg.l, &cfg.k); err != nil {
+				b.Fatalf("invalid configuration %s/%s: %s", bits, size, err)
+			}
+			b.Run(cfg.String(), func(b *testing.B) {
+				fx := getFixture(b, cfg)
+				b.ResetTimer()
+				bench(b, fx)
+			})
+		}
+	}
+}
+
+// getFixture returns the fixture of a configuration, creating it the first time.
+func getFixture(b *testing.B, cfg benchConfig) *benchFixture {
+	benchFixtures.Lock()
+	defer benchFixtures.Unlock()
+	if fx, ok := benchFixtures.m[cfg]; ok {
+		return fx
+	}
+	var pk *l2fhe.PubKey
+	var shares []*tcpaillier.KeyShare
+	var err error
+	if params := benchparams.Paillier(cfg.bits); params != nil {
+		pk, shares, err = l2fhe.NewFixedKey(cfg.bits, cfg.l, cfg.k, params)
+	} else {
+		pk, shares, err = l2fhe.NewKey(cfg.bits, cfg.l, cfg.k)
+	}
+	if err != nil {
+		b.Fatal(err)
+	}
+	fx := &benchFixture{
+		pk:         pk,
+		shares:     shares,
+		multiplier: big.NewInt(70),
+	}
+	if fx.c1, _, err = pk.Encrypt(fifty); err != nil {
+		b.Fatal(err)
+	}
+	if fx.c2, _, err = pk.Encrypt(seventy); err != nil {
+		b.Fatal(err)
+	}
+	if fx.l2, err = pk.Mul(fx.c1, fx.c2); err != nil {
+		b.Fatal(err)
+	}
+	for _, share := range shares {
+		ds1, _, err := pk.PartialDecryptL1(share, fx.c1)
+		if err != nil {
+			b.Fatal(err)
+		}
+		fx.sharesL1 = append(fx.sharesL1, ds1)
+		ds2, zk2, err := pk.PartialDecryptL2(share, fx.l2)
+		if err != nil {
+			b.Fatal(err)
+		}
+		fx.sharesL2 = append(fx.sharesL2, ds2)
+		fx.zkL2 = zk2
+	}
+	benchFixtures.m[cfg] = fx
+	return fx
+}
+
+func BenchmarkPubKey_Encrypt(b *testing.B) {
+	runConfigs(b, func(b *testing.B, fx *benchFixture) {
+		for i := 0; i < b.N; i++ {
+			if _, _, err := fx.pk.Encrypt(fifty); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkPubKey_AddL1(b *testing.B) {
+	runConfigs(b, func(b *testing.B, fx *benchFixture) {
+		for i := 0; i < b.N; i++ {
+			if _, err := fx.pk.AddL1(fx.c1, fx.c2); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkPubKey_MulConstL1(b *testing.B) {
+	runConfigs(b, func(b *testing.B, fx *benchFixture) {
+		for i := 0; i < b.N; i++ {
+			if _, err := fx.pk.MulConstL1(fx.c1, fx.multiplier); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkPubKey_Mul(b *testing.B) {
+	runConfigs(b, func(b *testing.B, fx *benchFixture) {
+		for i := 0; i < b.N; i++ {
+			if _, err := fx.pk.Mul(fx.c1, fx.c2); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkPubKey_AddL2(b *testing.B) {
+	runConfigs(b, func(b *testing.B, fx *benchFixture) {
+		for i := 0; i < b.N; i++ {
+			if _, err := fx.pk.AddL2(fx.l2, fx.l2); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkPubKey_MulConstL2(b *testing.B) {
+	runConfigs(b, func(b *testing.B, fx *benchFixture) {
+		for i := 0; i < b.N; i++ {
+			if _, err := fx.pk.MulConstL2(fx.l2, fx.multiplier); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkPubKey_PartialDecryptL1(b *testing.B) {
+	runConfigs(b, func(b *testing.B, fx *benchFixture) {
+		for i := 0; i < b.N; i++ {
+			if _, _, err := fx.pk.PartialDecryptL1(fx.shares[0], fx.c1); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkPubKey_CombineSharesL1(b *testing.B) {
+	runConfigs(b, func(b *testing.B, fx *benchFixture) {
+		for i := 0; i < b.N; i++ {
+			if _, err := fx.pk.CombineSharesL1(fx.sharesL1...); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkPubKey_PartialDecryptL2(b *testing.B) {
+	runConfigs(b, func(b *testing.B, fx *benchFixture) {
+		for i := 0; i < b.N; i++ {
+			if _, _, err := fx.pk.PartialDecryptL2(fx.shares[0], fx.l2); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkPubKey_CombineSharesL2(b *testing.B) {
+	runConfigs(b, func(b *testing.B, fx *benchFixture) {
+		for i := 0; i < b.N; i++ {
+			if _, err := fx.pk.CombineSharesL2(fx.sharesL2...); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkDecryptedShareL2ZK_Verify(b *testing.B) {
+	runConfigs(b, func(b *testing.B, fx *benchFixture) {
+		last := fx.sharesL2[len(fx.sharesL2)-1]
+		for i := 0; i < b.N; i++ {
+			if err := fx.zkL2.Verify(fx.pk.Paillier, fx.l2, last); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,51 @@
+package l2fhe
+
+import (
+	"crypto/sha256"
+	"github.com/niclabs/tcpaillier"
+	"math/big"
+)
+
+// PartialDecryptWithProof decrypts partially c using a key share, and returns the decryption share with its
+// proof and the commitments a = (c^4)^r and b = v^r of the proof.
+// The proof is the one of tcpaillier.KeyShare.PartialDecryptProof, so it is verified by
+// tcpaillier.DecryptShareZK.Verify, but r has (s+2)k bits plus the challenge length, where k is the bit length of
+// N, as in Damgard-Jurik. tcpaillier uses the threshold as k, so its responses z = r + e*delta*si reveal si.
+func PartialDecryptWithProof(key *tcpaillier.KeyShare, c *big.Int) (ds *tcpaillier.DecryptionShare, zk *tcpaillier.DecryptShareZK, a, b *big.Int, err error) {
+	ds, err = key.PartialDecrypt(c)
+	if err != nil {
+		return
+	}
+	nToSPlusOne := key.Cache().NToSPlusOne
+	numBits := (int(key.S)+2)*key.N.BitLen() + sha256.Size*8
+	r, err := tcpaillier.RandomInt(numBits)
+	if err != nil {
+		return
+	}
+	cTo4 := new(big.Int).Exp(c, big.NewInt(4), nToSPlusOne)
+	a = new(big.Int).Exp(cTo4, r, nToSPlusOne)
+	b = new(big.Int).Exp(key.V, r, nToSPlusOne)
+	ciTo2 := new(big.Int).Exp(ds.Ci, big.NewInt(2), nToSPlusOne)
+	e := DecryptShareChallenge(a, b, cTo4, ciTo2)
+
+	z := new(big.Int).Mul(e, key.Si)
+	z.Mul(z, key.Delta).Add(z, r)
+	zk = &tcpaillier.DecryptShareZK{
+		V:  key.V,
+		Vi: key.Vi[key.Index-1],
+		Z:  z,
+		E:  e,
+	}
+	return
+}
+
+// DecryptShareChallenge returns the challenge of a DecryptShareZK with commitments a and b, where cTo4 is the
+// decrypted value raised to 4 and ciTo2 is the decryption share raised to 2, as computed by tcpaillier.
+func DecryptShareChallenge(a, b, cTo4, ciTo2 *big.Int) *big.Int {
+	hash := sha256.New()
+	hash.Write(a.Bytes())
+	hash.Write(b.Bytes())
+	hash.Write(cTo4.Bytes())
+	hash.Write(ciTo2.Bytes())
+	return new(big.Int).SetBytes(hash.Sum(nil))
+}
@@ -472,3 +472,41 @@ func TestL2TCPaillier_MulConstL2(t *testing.T) {
 		return
 	}
 }
+
+func TestPartialDecryptWithProof(t *testing.T) {
+	pk, keyShares, err := l2fhe.NewKey(bitSize, l, k)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	encVal, _, err := pk.Encrypt(fifty)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	nToSPlusOne := pk.Paillier.Cache().NToSPlusOne
+	for _, share := range keyShares {
+		ds, zkp, a, b, err := l2fhe.PartialDecryptWithProof(share, encVal.Beta)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if err := zkp.Verify(pk.Paillier, encVal.Beta, ds); err != nil {
+			t.Error(err)
+			return
+		}
+		cTo4 := new(big.Int).Exp(encVal.Beta, big.NewInt(4), nToSPlusOne)
+		ciTo2 := new(big.Int).Exp(ds.Ci, big.NewInt(2), nToSPlusOne)
+		if l2fhe.DecryptShareChallenge(a, b, cTo4, ciTo2).Cmp(zkp.E) != 0 {
+			t.Error("commitments are not the ones of the proof")
+			return
+		}
+		// r = z - e*delta*si must be long enough to hide the key share.
+		r := new(big.Int).Mul(zkp.E, share.Delta)
+		r.Mul(r, share.Si).Sub(zkp.Z, r)
+		if minBits := (int(share.S)+2)*share.N.BitLen() + 256 - 32; r.BitLen() < minBits {
+			t.Errorf("random value of the proof has %d bits, but it should have about %d", r.BitLen(), minBits+32)
+			return
+		}
+	}
+}
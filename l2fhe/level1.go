@@ -89,9 +89,9 @@ func (l *PubKey) MulConstL1(c *EncryptedL1, cons *big.Int) (mul *EncryptedL1, er
 }
 
 // PartialDecryptL1 decrypts partially an encrypted Level-1 value using a given key share. It returns the decrypted
-// share and a ZKProof over the encrypted value.
+// share and a ZKProof over the encrypted value, built by PartialDecryptWithProof.
 func (l *PubKey) PartialDecryptL1(key *tcpaillier.KeyShare, c *EncryptedL1) (share *DecryptedShareL1, zk *DecryptedShareL1ZK, err error) {
-	partialDecryptBeta, zkp, err := key.PartialDecryptWithProof(c.Beta)
+	partialDecryptBeta, zkp, _, _, err := PartialDecryptWithProof(key, c.Beta)
 	if err != nil {
 		return
 	}
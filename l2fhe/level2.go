@@ -102,9 +102,9 @@ func (l *PubKey) MulConstL2(c *EncryptedL2, cons *big.Int) (mul *EncryptedL2, er
 }
 
 // PartialDecryptL2 decrypts partially an encrypted Level-2 value using a given key share. It returns the decrypted
-// share and a ZKProof over the encrypted value.
+// share and a ZKProof over the encrypted value, built by PartialDecryptWithProof for each Paillier value.
 func (l *PubKey) PartialDecryptL2(key *tcpaillier.KeyShare, c *EncryptedL2) (share *DecryptedShareL2, zk *DecryptedShareL2ZK, err error) {
-	decAlpha, zkpAlpha, err := key.PartialDecryptWithProof(c.Alpha)
+	decAlpha, zkpAlpha, _, _, err := PartialDecryptWithProof(key, c.Alpha)
 	if err != nil {
 		return
 	}
@@ -117,12 +117,12 @@ func (l *PubKey) PartialDecryptL2(key *tcpaillier.KeyShare, c *EncryptedL2) (sha
 		Betas: make([]*DecryptedShareBetas, 0),
 	}
 	for _, beta := range c.Betas {
-		dsBeta1, zkpBeta1, err2 := key.PartialDecryptWithProof(beta.Beta1)
+		dsBeta1, zkpBeta1, _, _, err2 := PartialDecryptWithProof(key, beta.Beta1)
 		if err2 != nil {
 			err = err2
 			return
 		}
-		dsBeta2, zkpBeta2, err2 := key.PartialDecryptWithProof(beta.Beta2)
+		dsBeta2, zkpBeta2, _, _, err2 := PartialDecryptWithProof(key, beta.Beta2)
 		if err2 != nil {
 			err = err2
 			return
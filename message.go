@@ -13,6 +13,9 @@ type KeyInitMessage struct {
 	Proof  *KeyGenZKProof     // ZKProof that the value in AlphaI is a valid private key share
 }
 
+// errIncompleteMessage is returned when verifying a message that is nil or has fields that are not set.
+var errIncompleteMessage = fmt.Errorf("message fields must be set")
+
 // KeyInitMessageList represents a list of KeyInitMessage
 type KeyInitMessageList []*KeyInitMessage
 
@@ -28,8 +31,9 @@ type Round1MessageList []*Round1Message
 
 // Round2Message defines a message sent on Round 2
 type Round2Message struct {
-	PDZ   *l2fhe.DecryptedShareL2 // Z Decrypt share.
-	Proof *l2fhe.DecryptedShareL2ZK      // Proof that PDZ is a partial decryption of Z
+	PDZ         *l2fhe.DecryptedShareL2 // Z Decrypt share.
+	Proof       *l2fhe.DecryptedShareL2ZK      // Proof that PDZ is a partial decryption of Z
+	Commitments []*DecryptShareCommitment // Optional commitments of the proofs, used by VerifyBatch
 }
 
 // Round2MessageList represents a list of Round2Message
@@ -37,35 +41,50 @@ type Round2MessageList []*Round2Message
 
 // Round3Message defines a message sent on Round 3
 type Round3Message struct {
-	PDSigma *l2fhe.DecryptedShareL2 // sigma Decrypt share.
-	Proof   *l2fhe.DecryptedShareL2ZK      // Proof that PDSigma is a partial decryption of sigma
+	PDSigma     *l2fhe.DecryptedShareL2 // sigma Decrypt share.
+	Proof       *l2fhe.DecryptedShareL2ZK      // Proof that PDSigma is a partial decryption of sigma
+	Commitments []*DecryptShareCommitment // Optional commitments of the proofs, used by VerifyBatch
 }
 
 // Round3MessageList represents a list of Round3Message
 type Round3MessageList []*Round3Message
 
+// SchnorrCommitMessage defines the message sent by a participant of a Schnorr signing session to commit to
+// its Round1Message before any Round1Message is revealed
+type SchnorrCommitMessage struct {
+	Commitment []byte // SHA-256 hash of the compact encoding of the Round1Message
+}
+
+// SchnorrCommitMessageList represents a list of SchnorrCommitMessage
+type SchnorrCommitMessageList []*SchnorrCommitMessage
+
+// SchnorrRound2Message defines a message sent on Round 2 of a Schnorr signing session
+type SchnorrRound2Message struct {
+	PDS   *l2fhe.DecryptedShareL1   // s Decrypt share.
+	Proof *l2fhe.DecryptedShareL1ZK // Proof that PDS is a partial decryption of s
+}
+
+// SchnorrRound2MessageList represents a list of SchnorrRound2Message
+type SchnorrRound2MessageList []*SchnorrRound2Message
+
 // Join joins a list of KeyInitMessages and returns the encrypted public key and private keys.
 func (msgs KeyInitMessageList) Join(meta *KeyMeta) (alpha *l2fhe.EncryptedL1, y *Point, err error) {
+	defer startSpan(SpanJoinKeyInit).end(&err)
 	if len(msgs) != int(meta.Paillier.L) {
 		err = fmt.Errorf("number of messages must be equal to participants number L (%d)", meta.Paillier.L)
 		return
 	}
 	alphaIList := make([]*l2fhe.EncryptedL1, 0)
 	yiList := make([]*Point, 0)
+	errs := meta.verifyAll(len(msgs), func(i int) error {
+		return msgs[i].Verify(meta)
+	})
 	for i, msg := range msgs {
-		if err = msg.Proof.Verify(meta, msg.Yi, msg.AlphaI); err != nil {
-			err = fmt.Errorf("error with zkproof %d: %s", i, err)
-			return
-		}
-		if msg.AlphaI == nil {
-			err = fmt.Errorf("alphaI from message %d is nil", i)
+		if err = errs[i]; err != nil {
+			err = fmt.Errorf("error with message %d: %s", i, err)
 			return
 		}
 		alphaIList = append(alphaIList, msg.AlphaI)
-		if msg.Yi == nil {
-			err = fmt.Errorf("yi from message %d is nil", i)
-			return
-		}
 		yiList = append(yiList, msg.Yi)
 	}
 	alpha, err = meta.AddL1(alphaIList...)
@@ -73,11 +92,38 @@ func (msgs KeyInitMessageList) Join(meta *KeyMeta) (alpha *l2fhe.EncryptedL1, y
 		return
 	}
 	y = NewZero().Add(meta.Curve(), yiList...)
+	if y.IsIdentity() {
+		err = fmt.Errorf("public key is the point at infinity")
+	}
 	return
 }
 
+// Verify returns an error if the message fields are not set or if its ZKProof is not valid.
+func (msg *KeyInitMessage) Verify(meta *KeyMeta) error {
+	if msg == nil || msg.Proof == nil || msg.Yi == nil || msg.AlphaI == nil {
+		return errIncompleteMessage
+	}
+	return msg.Proof.Verify(meta, msg.Yi, msg.AlphaI)
+}
+
+// Verify returns an error if the message fields are not set or if its ZKProof is not valid.
+func (msg *Round1Message) Verify(meta *KeyMeta) error {
+	if msg == nil || msg.Proof == nil || msg.Ri == nil || msg.Ui == nil || msg.Vi == nil || msg.Wi == nil {
+		return errIncompleteMessage
+	}
+	return msg.Proof.Verify(meta, msg.Ri, msg.Ui, msg.Vi, msg.Wi)
+}
+
 // Join joins a list of Round1Messages and returns the values R, u, v and w.
 func (msgs Round1MessageList) Join(meta *KeyMeta) (R *Point, u, v, w *l2fhe.EncryptedL1, err error) {
+	R, u, v, w, _, err = msgs.verifyAndJoin(meta)
+	return
+}
+
+// verifyAndJoin verifies a list of Round1Messages and joins the first K valid ones, returning also their
+// positions on the list.
+func (msgs Round1MessageList) verifyAndJoin(meta *KeyMeta) (R *Point, u, v, w *l2fhe.EncryptedL1, positions []int, err error) {
+	defer startSpan(SpanJoinRound1).end(&err)
 
 	k := int(meta.Paillier.K)
 	if len(msgs) < k {
@@ -85,42 +131,81 @@ func (msgs Round1MessageList) Join(meta *KeyMeta) (R *Point, u, v, w *l2fhe.Encr
 		return
 	}
 
-	rs := make([]*Point, 0)
-	us := make([]*l2fhe.EncryptedL1, 0)
-	vs := make([]*l2fhe.EncryptedL1, 0)
-	ws := make([]*l2fhe.EncryptedL1, 0)
+	valid, positions, err := msgs.verified(meta)
+	if err != nil {
+		return
+	}
+	if R, u, v, w, err = valid.join(meta); err != nil {
+		return
+	}
+	positions = positions[:k]
+	return
+}
 
-	for _, msg := range msgs {
-		if msg.Proof != nil &&
+// verified returns the complete messages of the list with their positions on it.
+// It returns an error if one of the complete messages is not valid.
+func (msgs Round1MessageList) verified(meta *KeyMeta) (valid Round1MessageList, positions []int, err error) {
+	valid = make(Round1MessageList, 0)
+	complete := make([]bool, len(msgs))
+	for i, msg := range msgs {
+		complete[i] = msg != nil &&
+			msg.Proof != nil &&
 			msg.Ri != nil &&
 			msg.Ui != nil &&
 			msg.Vi != nil &&
-			msg.Wi != nil {
-			if err = msg.Proof.Verify(meta, msg.Ri, msg.Ui, msg.Vi, msg.Wi); err == nil {
-				rs = append(rs, msg.Ri)
-				vs = append(vs, msg.Vi)
-				us = append(us, msg.Ui)
-				ws = append(ws, msg.Wi)
-			} else {
-				return
+			msg.Wi != nil
+	}
+	var errs []error
+	if meta.BatchVerify {
+		errs = msgs.VerifyBatch(meta)
+	} else {
+		errs = meta.verifyAll(len(msgs), func(i int) error {
+			if !complete[i] {
+				return nil
 			}
+			return msgs[i].Verify(meta)
+		})
+	}
+	for i, msg := range msgs {
+		if !complete[i] {
+			addCounter(CounterIncompleteMessages, 1)
+			continue
 		}
+		if err = errs[i]; err != nil {
+			addCounter(CounterRejectedMessages, 1)
+			return
+		}
+		valid = append(valid, msg)
+		positions = append(positions, i)
 	}
+	observeHistogram(HistogramValidMessages, float64(len(valid)))
+	return
+}
 
-	if len(rs) < k ||
-		len(us) < k ||
-		len(vs) < k ||
-		len(ws) < k {
+// join joins the first K messages of a list of already verified Round1Messages.
+func (msgs Round1MessageList) join(meta *KeyMeta) (R *Point, u, v, w *l2fhe.EncryptedL1, err error) {
+	if len(msgs) < int(meta.Paillier.K) {
 		err = fmt.Errorf("cannot get minimum number of values needed for protocol")
 		return
 	}
 
-	rs = rs[:meta.Paillier.K]
-	us = us[:meta.Paillier.K]
-	vs = vs[:meta.Paillier.K]
-	ws = ws[:meta.Paillier.K]
+	rs := make([]*Point, 0)
+	us := make([]*l2fhe.EncryptedL1, 0)
+	vs := make([]*l2fhe.EncryptedL1, 0)
+	ws := make([]*l2fhe.EncryptedL1, 0)
+
+	for _, msg := range msgs[:meta.Paillier.K] {
+		rs = append(rs, msg.Ri)
+		vs = append(vs, msg.Vi)
+		us = append(us, msg.Ui)
+		ws = append(ws, msg.Wi)
+	}
 
 	R = NewZero().Add(meta.Curve(), rs...)
+	if R.IsIdentity() {
+		err = fmt.Errorf("R is the point at infinity")
+		return
+	}
 	u, err = meta.AddL1(us...)
 	if err != nil {
 		return
@@ -136,30 +221,85 @@ func (msgs Round1MessageList) Join(meta *KeyMeta) (R *Point, u, v, w *l2fhe.Encr
 	return
 }
 
+// Verify returns an error if the message fields are not set or if its ZKProof is not valid.
+// If the message has commitments, it also returns an error if they are not the ones of the ZKProof.
+// The Z value required is to check the ZKProof.
+func (msg *Round2Message) Verify(meta *KeyMeta, z *l2fhe.EncryptedL2) (err error) {
+	defer startSpan(SpanVerifyDecryptProofs).end(&err)
+	if msg == nil || msg.Proof == nil || msg.PDZ == nil {
+		return errIncompleteMessage
+	}
+	if err = msg.Proof.Verify(meta.Paillier, z, msg.PDZ); err != nil || len(msg.Commitments) == 0 {
+		return
+	}
+	return verifyDecryptShareCommitments(meta, z, &decryptShareMessage{msg.PDZ, msg.Proof, msg.Commitments})
+}
+
 // Join joins a list of Round2Messages and returns the value nu.
 // The Z value required is to check the ZKProofs.
 func (msgs Round2MessageList) Join(meta *KeyMeta, z *l2fhe.EncryptedL2) (nu *big.Int, err error) {
+	nu, _, err = msgs.verifyAndJoin(meta, z)
+	return
+}
+
+// verifyAndJoin verifies a list of Round2Messages and joins the first K valid ones, returning also their
+// positions on the list.
+func (msgs Round2MessageList) verifyAndJoin(meta *KeyMeta, z *l2fhe.EncryptedL2) (nu *big.Int, positions []int, err error) {
+	defer startSpan(SpanJoinRound2).end(&err)
 	k := int(meta.Paillier.K)
 	if len(msgs) < k {
 		err = fmt.Errorf("length of messages should be at least K")
 		return
 	}
-	pdZList := make([]*l2fhe.DecryptedShareL2, 0)
-	for _, msg := range msgs {
-		if msg.Proof != nil &&
-			msg.PDZ != nil {
-			if err = msg.Proof.Verify(meta.Paillier, z, msg.PDZ); err == nil {
-				pdZList = append(pdZList, msg.PDZ)
+	valid, positions := msgs.verified(meta, z)
+	if nu, err = valid.join(meta); err != nil {
+		return
+	}
+	positions = positions[:k]
+	return
+}
+
+// verified returns the valid messages of the list with their positions on it.
+// The Z value required is to check the ZKProofs.
+func (msgs Round2MessageList) verified(meta *KeyMeta, z *l2fhe.EncryptedL2) (valid Round2MessageList, positions []int) {
+	valid = make(Round2MessageList, 0)
+	var errs []error
+	if meta.BatchVerify {
+		errs = msgs.VerifyBatch(meta, z)
+	} else {
+		errs = meta.verifyAll(len(msgs), func(i int) error {
+			if msgs[i] == nil {
+				return errIncompleteMessage
 			}
+			return msgs[i].Verify(meta, z)
+		})
+	}
+	for i, msg := range msgs {
+		switch errs[i] {
+		case nil:
+			valid = append(valid, msg)
+			positions = append(positions, i)
+		case errIncompleteMessage:
+			addCounter(CounterIncompleteMessages, 1)
+		default:
+			addCounter(CounterRejectedMessages, 1)
 		}
 	}
+	observeHistogram(HistogramValidMessages, float64(len(valid)))
+	return
+}
 
-	if len(pdZList) < k {
+// join joins the first K messages of a list of already verified Round2Messages.
+func (msgs Round2MessageList) join(meta *KeyMeta) (nu *big.Int, err error) {
+	k := int(meta.Paillier.K)
+	if len(msgs) < k {
 		err = fmt.Errorf("cannot get minimum number of values needed for protocol")
 		return
 	}
-
-	pdZList = pdZList[:k]
+	pdZList := make([]*l2fhe.DecryptedShareL2, 0)
+	for _, msg := range msgs[:k] {
+		pdZList = append(pdZList, msg.PDZ)
+	}
 	nu, err = meta.CombineSharesL2(pdZList...)
 	if err != nil {
 		return
@@ -168,29 +308,118 @@ func (msgs Round2MessageList) Join(meta *KeyMeta, z *l2fhe.EncryptedL2) (nu *big
 	return
 }
 
+// Verify returns an error if the message fields are not set or if its ZKProof is not valid.
+// If the message has commitments, it also returns an error if they are not the ones of the ZKProof.
+// The sigma value required is to check the ZKProof.
+func (msg *Round3Message) Verify(meta *KeyMeta, sigma *l2fhe.EncryptedL2) (err error) {
+	defer startSpan(SpanVerifyDecryptProofs).end(&err)
+	if msg == nil || msg.Proof == nil || msg.PDSigma == nil {
+		return errIncompleteMessage
+	}
+	if err = msg.Proof.Verify(meta.Paillier, sigma, msg.PDSigma); err != nil || len(msg.Commitments) == 0 {
+		return
+	}
+	return verifyDecryptShareCommitments(meta, sigma, &decryptShareMessage{msg.PDSigma, msg.Proof, msg.Commitments})
+}
+
 // Join joins a list of Round3Messages and returns the value S.
 // the sigma value required is to check the ZKProofs.
 func (msgs Round3MessageList) Join(meta *KeyMeta, sigma *l2fhe.EncryptedL2) (s *big.Int, err error) {
+	s, _, err = msgs.verifyAndJoin(meta, sigma)
+	return
+}
+
+// verifyAndJoin verifies a list of Round3Messages and joins the first K valid ones, returning also their
+// positions on the list.
+func (msgs Round3MessageList) verifyAndJoin(meta *KeyMeta, sigma *l2fhe.EncryptedL2) (s *big.Int, positions []int, err error) {
+	defer startSpan(SpanJoinRound3).end(&err)
 	k := int(meta.Paillier.K)
 	if len(msgs) < k {
 		err = fmt.Errorf("length of messages should be at least K")
 		return
 	}
+	valid, positions := msgs.verified(meta, sigma)
+	if s, err = valid.join(meta); err != nil {
+		return
+	}
+	positions = positions[:k]
+	return
+}
+
+// verified returns the valid messages of the list with their positions on it.
+// The sigma value required is to check the ZKProofs.
+func (msgs Round3MessageList) verified(meta *KeyMeta, sigma *l2fhe.EncryptedL2) (valid Round3MessageList, positions []int) {
+	valid = make(Round3MessageList, 0)
+	var errs []error
+	if meta.BatchVerify {
+		errs = msgs.VerifyBatch(meta, sigma)
+	} else {
+		errs = meta.verifyAll(len(msgs), func(i int) error {
+			if msgs[i] == nil {
+				return errIncompleteMessage
+			}
+			return msgs[i].Verify(meta, sigma)
+		})
+	}
+	for i, msg := range msgs {
+		switch errs[i] {
+		case nil:
+			valid = append(valid, msg)
+			positions = append(positions, i)
+		case errIncompleteMessage:
+			addCounter(CounterIncompleteMessages, 1)
+		default:
+			addCounter(CounterRejectedMessages, 1)
+		}
+	}
+	observeHistogram(HistogramValidMessages, float64(len(valid)))
+	return
+}
+
+// join joins the first K messages of a list of already verified Round3Messages.
+func (msgs Round3MessageList) join(meta *KeyMeta) (s *big.Int, err error) {
+	k := int(meta.Paillier.K)
+	if len(msgs) < k {
+		err = fmt.Errorf("cannot get minimum number of values needed for protocol")
+		return
+	}
 	pdSigmaList := make([]*l2fhe.DecryptedShareL2, 0)
+	for _, msg := range msgs[:k] {
+		pdSigmaList = append(pdSigmaList, msg.PDSigma)
+	}
+	s, err = meta.CombineSharesL2(pdSigmaList...)
+	if err != nil {
+		return
+	}
+	s.Mod(s, meta.Q())
+	return
+}
+
+// Join joins a list of SchnorrRound2Messages and returns the value S.
+// the encrypted s value required is to check the ZKProofs.
+func (msgs SchnorrRound2MessageList) Join(meta *KeyMeta, encS *l2fhe.EncryptedL1) (s *big.Int, err error) {
+	k := int(meta.Paillier.K)
+	if len(msgs) < k {
+		err = fmt.Errorf("length of messages should be at least K")
+		return
+	}
+	pdSList := make([]*l2fhe.DecryptedShareL1, 0)
 	for _, msg := range msgs {
 		if msg.Proof != nil &&
-			msg.PDSigma != nil {
-			if err = msg.Proof.Verify(meta.Paillier, sigma, msg.PDSigma); err == nil {
-				pdSigmaList = append(pdSigmaList, msg.PDSigma)
+			msg.PDS != nil &&
+			msg.PDS.Alpha != nil &&
+			msg.PDS.Alpha.Cmp(encS.Alpha) == 0 {
+			if err = msg.Proof.Verify(meta.Paillier, encS, msg.PDS); err == nil {
+				pdSList = append(pdSList, msg.PDS)
 			}
 		}
 	}
-	if len(pdSigmaList) < k {
+	if len(pdSList) < k {
 		err = fmt.Errorf("cannot get minimum number of values needed for protocol")
 		return
 	}
-	pdSigmaList = pdSigmaList[:k]
-	s, err = meta.CombineSharesL2(pdSigmaList...)
+	pdSList = pdSList[:k]
+	s, err = meta.CombineSharesL1(pdSList...)
 	if err != nil {
 		return
 	}
@@ -0,0 +1,217 @@
+package tcecdsa_test
+
+import (
+	"crypto/sha256"
+	"github.com/niclabs/tcecdsa"
+	"github.com/niclabs/tcecdsa/l2fhe"
+	"github.com/niclabs/tcpaillier"
+	"math/big"
+	"testing"
+)
+
+func TestJoin_VerifyWorkers(t *testing.T) {
+	params := &tcecdsa.NewKeyParams{
+		PaillierFixed: &tcpaillier.FixedParams{
+			P:  p,
+			P1: p1,
+			Q:  q,
+			Q1: q1,
+		},
+	}
+	shares, keyMeta, err := tcecdsa.NewKey(L, K, Curve, params)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	keyInitMessages := make(tcecdsa.KeyInitMessageList, 0)
+	for _, share := range shares {
+		msg, err := share.Init(keyMeta)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		keyInitMessages = append(keyInitMessages, msg)
+	}
+	for _, share := range shares {
+		if err := share.SetKey(keyMeta, keyInitMessages); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	h := sha256.Sum256(exampleText)
+	round1Messages := make(tcecdsa.Round1MessageList, 0)
+	for _, share := range shares {
+		state, err := share.NewSigSession(keyMeta, h[:])
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		msg, err := state.Round1()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		round1Messages = append(round1Messages, msg)
+	}
+
+	t.Run("KeyInitSameError", func(t *testing.T) {
+		invalid := make(tcecdsa.KeyInitMessageList, len(keyInitMessages))
+		copy(invalid, keyInitMessages)
+		// Messages 1 and 3 have encrypted values out of the ciphertext space.
+		for _, i := range []int{1, 3} {
+			alphaI := &l2fhe.EncryptedL1{Alpha: invalid[i].AlphaI.Alpha, Beta: big.NewInt(-1)}
+			invalid[i] = &tcecdsa.KeyInitMessage{AlphaI: alphaI, Yi: invalid[i].Yi, Proof: invalid[i].Proof}
+		}
+		keyMeta.VerifyWorkers = 1
+		_, _, expected := invalid.Join(keyMeta)
+		if expected == nil {
+			t.Error("join with invalid messages should fail")
+			return
+		}
+		for _, workers := range []int{0, 2, L} {
+			keyMeta.VerifyWorkers = workers
+			_, _, err := invalid.Join(keyMeta)
+			if err == nil || err.Error() != expected.Error() {
+				t.Errorf("join with %d workers returned %v instead of %v", workers, err, expected)
+				return
+			}
+		}
+	})
+
+	t.Run("KeyInitIncomplete", func(t *testing.T) {
+		incomplete := []*tcecdsa.KeyInitMessage{
+			nil,
+			{AlphaI: keyInitMessages[1].AlphaI, Yi: keyInitMessages[1].Yi},
+			{AlphaI: keyInitMessages[1].AlphaI, Proof: keyInitMessages[1].Proof},
+			{Yi: keyInitMessages[1].Yi, Proof: keyInitMessages[1].Proof},
+			{AlphaI: keyInitMessages[1].AlphaI, Yi: keyInitMessages[1].Yi, Proof: &tcecdsa.KeyGenZKProof{}},
+		}
+		for _, msg := range incomplete {
+			invalid := make(tcecdsa.KeyInitMessageList, len(keyInitMessages))
+			copy(invalid, keyInitMessages)
+			invalid[1] = msg
+			for _, workers := range []int{1, 4} {
+				keyMeta.VerifyWorkers = workers
+				if _, _, err := invalid.Join(keyMeta); err == nil {
+					t.Errorf("join with an incomplete message and %d workers should fail", workers)
+					return
+				}
+			}
+		}
+	})
+
+	t.Run("Round1SelectionOrder", func(t *testing.T) {
+		// The first message is incomplete, so messages 1 to K are joined.
+		msgs := append(tcecdsa.Round1MessageList{{}}, round1Messages...)
+		keyMeta.VerifyWorkers = 1
+		expected, _, _, _, err := msgs.Join(keyMeta)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		for _, workers := range []int{0, 2, L} {
+			keyMeta.VerifyWorkers = workers
+			R, _, _, _, err := msgs.Join(keyMeta)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if R.Cmp(expected) != 0 {
+				t.Errorf("join with %d workers selected different messages", workers)
+				return
+			}
+		}
+	})
+	keyMeta.VerifyWorkers = 0
+}
+
+func TestJoin_InvalidPoints(t *testing.T) {
+	params := &tcecdsa.NewKeyParams{
+		PaillierFixed: &tcpaillier.FixedParams{
+			P:  p,
+			P1: p1,
+			Q:  q,
+			Q1: q1,
+		},
+	}
+	shares, keyMeta, err := tcecdsa.NewKey(L, K, Curve, params)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	keyInitMessages := make(tcecdsa.KeyInitMessageList, 0)
+	for _, share := range shares {
+		msg, err := share.Init(keyMeta)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		keyInitMessages = append(keyInitMessages, msg)
+	}
+	for _, share := range shares {
+		if err := share.SetKey(keyMeta, keyInitMessages); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	h := sha256.Sum256(exampleText)
+	round1Messages := make(tcecdsa.Round1MessageList, 0)
+	for _, share := range shares {
+		state, err := share.NewSigSession(keyMeta, h[:])
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		msg, err := state.Round1()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		round1Messages = append(round1Messages, msg)
+	}
+	invalidPoints := map[string]*tcecdsa.Point{
+		"Identity": tcecdsa.NewZero(),
+		"OffCurve": tcecdsa.NewPoint(big.NewInt(1), big.NewInt(1)),
+		"Negative": tcecdsa.NewPoint(keyInitMessages[0].Yi.X, new(big.Int).Neg(keyInitMessages[0].Yi.Y)),
+	}
+
+	for name, point := range invalidPoints {
+		t.Run("KeyInitYi"+name, func(t *testing.T) {
+			msgs := make(tcecdsa.KeyInitMessageList, len(keyInitMessages))
+			copy(msgs, keyInitMessages)
+			msgs[1] = &tcecdsa.KeyInitMessage{AlphaI: msgs[1].AlphaI, Yi: point, Proof: msgs[1].Proof}
+			if _, _, err := msgs.Join(keyMeta); err == nil {
+				t.Error("join should reject an invalid public key share")
+				return
+			}
+		})
+		t.Run("Round1Ri"+name, func(t *testing.T) {
+			msgs := make(tcecdsa.Round1MessageList, len(round1Messages))
+			copy(msgs, round1Messages)
+			msg := *msgs[0]
+			msg.Ri = point
+			msgs[0] = &msg
+			if _, _, _, _, err := msgs.Join(keyMeta); err == nil {
+				t.Error("join should reject an invalid Ri")
+				return
+			}
+			if err := msgs.VerifyBatch(keyMeta)[0]; err == nil {
+				t.Error("batch verification should reject an invalid Ri")
+				return
+			}
+		})
+		t.Run("Round1ProofU1"+name, func(t *testing.T) {
+			msgs := make(tcecdsa.Round1MessageList, len(round1Messages))
+			copy(msgs, round1Messages)
+			proof := *msgs[0].Proof
+			proof.U1 = point
+			msg := *msgs[0]
+			msg.Proof = &proof
+			msgs[0] = &msg
+			if _, _, _, _, err := msgs.Join(keyMeta); err == nil {
+				t.Error("join should reject a proof with an invalid U1")
+				return
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+// Package metrics implements a tcecdsa.Instrumentation that aggregates the spans, counters and histograms of
+// the protocol in memory and exports them in the Prometheus text format, so they can be scraped without
+// any external service.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/niclabs/tcecdsa"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Prefix is the prefix of the names of all the exported metrics.
+const Prefix = "tcecdsa_"
+
+// The following values are the names of the metrics generated from the spans.
+const (
+	spanDuration = "span_duration_seconds"
+	spanErrors   = "span_errors_total"
+)
+
+// DefaultDurationBuckets are the upper bounds of the buckets of the span duration histograms, in seconds.
+var DefaultDurationBuckets = []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// DefaultBuckets are the upper bounds of the buckets of the histograms reported with ObserveHistogram.
+var DefaultBuckets = []float64{1, 2, 3, 5, 10, 20, 50, 100, 200}
+
+// Exporter is a tcecdsa.Instrumentation that keeps the metrics in memory and writes them in the Prometheus
+// text format. It also implements http.Handler, so it can be served on a /metrics endpoint.
+type Exporter struct {
+	DurationBuckets []float64 // Buckets of the span duration histograms
+	Buckets         []float64 // Buckets of the other histograms
+	mutex           sync.Mutex
+	counters        map[metricKey]float64
+	histograms      map[metricKey]*histogram
+}
+
+// metricKey identifies a metric by its name and the value of its span label, which is empty on metrics
+// that are not generated from spans.
+type metricKey struct {
+	name string
+	span string
+}
+
+// histogram represents a Prometheus histogram. counts[i] is the number of observations lower or equal to
+// buckets[i], and the last count is the number of all the observations.
+type histogram struct {
+	buckets []float64
+	counts  []uint64
+	sum     float64
+}
+
+// span is a span started on an Exporter.
+type span struct {
+	exporter *Exporter
+	name     string
+	start    time.Time
+}
+
+// NewExporter returns a new Exporter without metrics, using the default buckets.
+func NewExporter() *Exporter {
+	return &Exporter{
+		DurationBuckets: DefaultDurationBuckets,
+		Buckets:         DefaultBuckets,
+		counters:        make(map[metricKey]float64),
+		histograms:      make(map[metricKey]*histogram),
+	}
+}
+
+// StartSpan starts a span. When it ends, its duration is observed on the span duration histogram, and its
+// error, if it is not nil, is counted on the span error counter.
+func (e *Exporter) StartSpan(name string) tcecdsa.Span {
+	return &span{
+		exporter: e,
+		name:     name,
+		start:    time.Now(),
+	}
+}
+
+// End ends the span.
+func (s *span) End(err error) {
+	e := s.exporter
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.observe(metricKey{spanDuration, s.name}, e.DurationBuckets, time.Since(s.start).Seconds())
+	if err != nil {
+		e.counters[metricKey{spanErrors, s.name}]++
+	}
+}
+
+// AddCounter adds delta to the counter with the given name.
+func (e *Exporter) AddCounter(name string, delta float64) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.counters[metricKey{name: name}] += delta
+}
+
+// ObserveHistogram adds an observation to the histogram with the given name.
+func (e *Exporter) ObserveHistogram(name string, value float64) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.observe(metricKey{name: name}, e.Buckets, value)
+}
+
+// observe adds an observation to a histogram, creating it with the buckets if it does not exist.
+func (e *Exporter) observe(key metricKey, buckets []float64, value float64) {
+	h, ok := e.histograms[key]
+	if !ok {
+		h = &histogram{
+			buckets: buckets,
+			counts:  make([]uint64, len(buckets)+1),
+		}
+		e.histograms[key] = h
+	}
+	for i, bound := range h.buckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.counts[len(h.buckets)]++
+	h.sum += value
+}
+
+// WriteTo writes the metrics in the Prometheus text format, sorted by name and span.
+func (e *Exporter) WriteTo(w io.Writer) (n int64, err error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	counterKeys := make([]metricKey, 0, len(e.counters))
+	for key := range e.counters {
+		counterKeys = append(counterKeys, key)
+	}
+	sortKeys(counterKeys)
+	for i, key := range counterKeys {
+		if i == 0 || key.name != counterKeys[i-1].name {
+			fmt.Fprintf(cw, "# TYPE %s%s counter\n", Prefix, key.name)
+		}
+		fmt.Fprintf(cw, "%s%s%s %s\n", Prefix, key.name, labels(key.span, ""), formatFloat(e.counters[key]))
+	}
+	histogramKeys := make([]metricKey, 0, len(e.histograms))
+	for key := range e.histograms {
+		histogramKeys = append(histogramKeys, key)
+	}
+	sortKeys(histogramKeys)
+	for i, key := range histogramKeys {
+		if i == 0 || key.name != histogramKeys[i-1].name {
+			fmt.Fprintf(cw, "# TYPE %s%s histogram\n", Prefix, key.name)
+		}
+		h := e.histograms[key]
+		for i, bound := range h.buckets {
+			fmt.Fprintf(cw, "%s%s_bucket%s %d\n", Prefix, key.name, labels(key.span, formatFloat(bound)), h.counts[i])
+		}
+		fmt.Fprintf(cw, "%s%s_bucket%s %d\n", Prefix, key.name, labels(key.span, "+Inf"), h.counts[len(h.buckets)])
+		fmt.Fprintf(cw, "%s%s_sum%s %s\n", Prefix, key.name, labels(key.span, ""), formatFloat(h.sum))
+		fmt.Fprintf(cw, "%s%s_count%s %d\n", Prefix, key.name, labels(key.span, ""), h.counts[len(h.buckets)])
+	}
+	if cw.err == nil {
+		cw.err = cw.w.Flush()
+	}
+	return cw.n, cw.err
+}
+
+// ServeHTTP writes the metrics as the response, in the Prometheus text format.
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = e.WriteTo(w)
+}
+
+// countingWriter is a writer that counts the written bytes and keeps the first error.
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+// Write writes p if no error happened before.
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	cw.err = err
+	return n, err
+}
+
+// sortKeys sorts metric keys by name and span.
+func sortKeys(keys []metricKey) {
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].name != keys[j].name {
+			return keys[i].name < keys[j].name
+		}
+		return keys[i].span < keys[j].span
+	})
+}
+
+// labels returns the label set of a metric with the given span and bucket upper bound, which can be empty.
+func labels(span, le string) string {
+	var pairs []string
+	if span != "" {
+		pairs = append(pairs, "span="+strconv.Quote(span))
+	}
+	if le != "" {
+		pairs = append(pairs, "le="+strconv.Quote(le))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// formatFloat formats a value as Prometheus expects it.
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
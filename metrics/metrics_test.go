@@ -0,0 +1,71 @@
+package metrics_test
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/niclabs/tcecdsa/metrics"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExporter(t *testing.T) {
+	exporter := metrics.NewExporter()
+	exporter.DurationBuckets = []float64{60}
+	exporter.StartSpan("sign_round1").End(nil)
+	exporter.StartSpan("sign_round1").End(fmt.Errorf("failed"))
+	exporter.StartSpan("join_round1").End(nil)
+	exporter.AddCounter("rejected_messages_total", 2)
+	exporter.ObserveHistogram("valid_messages", 3)
+	exporter.ObserveHistogram("valid_messages", 300)
+
+	var buf bytes.Buffer
+	if _, err := exporter.WriteTo(&buf); err != nil {
+		t.Error(err)
+		return
+	}
+	expected := []string{
+		`# TYPE tcecdsa_rejected_messages_total counter`,
+		`tcecdsa_rejected_messages_total 2`,
+		`# TYPE tcecdsa_span_errors_total counter`,
+		`tcecdsa_span_errors_total{span="sign_round1"} 1`,
+		`# TYPE tcecdsa_span_duration_seconds histogram`,
+		`tcecdsa_span_duration_seconds_bucket{span="join_round1",le="60"} 1`,
+		`tcecdsa_span_duration_seconds_bucket{span="join_round1",le="+Inf"} 1`,
+		`tcecdsa_span_duration_seconds_count{span="join_round1"} 1`,
+		`tcecdsa_span_duration_seconds_bucket{span="sign_round1",le="60"} 2`,
+		`tcecdsa_span_duration_seconds_count{span="sign_round1"} 2`,
+		`# TYPE tcecdsa_valid_messages histogram`,
+		`tcecdsa_valid_messages_bucket{le="2"} 0`,
+		`tcecdsa_valid_messages_bucket{le="3"} 1`,
+		`tcecdsa_valid_messages_bucket{le="+Inf"} 2`,
+		`tcecdsa_valid_messages_sum 303`,
+		`tcecdsa_valid_messages_count 2`,
+	}
+	lines := strings.Split(buf.String(), "\n")
+	last := -1
+	for _, line := range expected {
+		found := false
+		for i := last + 1; i < len(lines); i++ {
+			if lines[i] == line {
+				last, found = i, true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("line %q not found in order on output:\n%s", line, buf.String())
+			return
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	exporter.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	if !strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Errorf("unexpected content type %s", recorder.Header().Get("Content-Type"))
+		return
+	}
+	if recorder.Body.String() != buf.String() {
+		t.Error("served metrics are different to the written ones")
+		return
+	}
+}
@@ -1,22 +1,48 @@
 package tcecdsa
 
 import (
+	"bytes"
 	"crypto/elliptic"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"math/big"
+	"strings"
 )
 
-// Point represents a point in a discrete elliptic curve.
-type Point struct{ X, Y *big.Int }
+// Point represents a point in a discrete elliptic curve. The point at infinity is represented as (0,0),
+// following elliptic package conventions.
+// The operations that receive a curve remember it, so the point can be encoded with its curve name.
+type Point struct {
+	X, Y  *big.Int
+	curve elliptic.Curve
+}
 
-// NewZero returns a new point centered in (0,0)
+// NewZero returns a new point centered in (0,0), which is the point at infinity.
 func NewZero() *Point {
 	return NewPoint(new(big.Int), new(big.Int))
 }
 
+// NewInfinity returns the point at infinity of a curve, which is the identity element of its group.
+func NewInfinity(curve elliptic.Curve) *Point {
+	return NewZero().SetCurve(curve)
+}
+
 // NewPoint returns a new point centered in (x, y)
 func NewPoint(x, y *big.Int) *Point {
-	return &Point{x, y}
+	return &Point{X: x, Y: y}
+}
+
+// Curve returns the curve of the point, or nil if it is not known.
+func (p *Point) Curve() elliptic.Curve {
+	return p.curve
+}
+
+// SetCurve sets the curve of the point, used to tag its text and JSON encodings. It does not check
+// that the point lies on the curve.
+func (p *Point) SetCurve(curve elliptic.Curve) *Point {
+	p.curve = curve
+	return p
 }
 
 // Clone copies the x and y coordinates of a point.
@@ -24,60 +50,230 @@ func (p *Point) Clone() *Point {
 	np := NewZero()
 	np.X = new(big.Int).Set(p.X)
 	np.Y = new(big.Int).Set(p.Y)
+	np.curve = p.curve
 	return np
 }
 
-// Neg negates a point, inverting its y coordinate.
+// Neg negates a point, inverting its y coordinate. If the curve of the point is known, y is reduced modulo
+// the field size, so the result lies on the curve. Otherwise, y is only negated, and the result is reduced
+// by the operations that receive a curve.
 func (p *Point) Neg(p2 *Point) *Point {
-	p.X = new(big.Int).Set(p2.X)
-	p.Y.Neg(p2.Y)
+	x, y := new(big.Int).Set(p2.X), new(big.Int).Neg(p2.Y)
+	if p2.curve != nil {
+		y.Mod(y, p2.curve.Params().P)
+	}
+	p.X, p.Y, p.curve = x, y, p2.curve
 	return p
 }
 
-// Mul multiplies a point by a scalar, using a given elliptic curve.
+// Mul multiplies a point by a scalar, using a given elliptic curve. The scalar is reduced modulo the order
+// of the curve, so negative scalars are accepted.
 func (p *Point) Mul(curve elliptic.Curve, p1 *Point, k *big.Int) *Point {
-	x, y := curve.ScalarMult(p1.X, p1.Y, k.Bytes())
-	if k.Cmp(zero) < 0 {
-		y.Neg(y) // k.Bytes() only encodes positive numbers
-	}
-	p.X, p.Y = x, y
+	x1, y1 := reduceCoords(curve, p1)
+	x, y := curve.ScalarMult(x1, y1, new(big.Int).Mod(k, curve.Params().N).Bytes())
+	p.X, p.Y, p.curve = x, y, curve
 	return p
 }
 
-// BaseMul multiplies the curve base point by a scalar, using a given elliptic curve.
+// BaseMul multiplies the curve base point by a scalar, using a given elliptic curve. The scalar is reduced
+// modulo the order of the curve, so negative scalars are accepted.
 func (p *Point) BaseMul(curve elliptic.Curve, k *big.Int) *Point {
-	x, y := curve.ScalarBaseMult(k.Bytes())
-	p.X, p.Y = x, y
-	if k.Cmp(zero) < 0 {
-		p.Neg(p) // k.Bytes() only encodes positive numbers
-	}
+	x, y := curve.ScalarBaseMult(new(big.Int).Mod(k, curve.Params().N).Bytes())
+	p.X, p.Y, p.curve = x, y, curve
 	return p
 }
 
-// Bytes transforms the point in a unique byte representation, based in Gob.Encode.
+// Bytes returns the SEC1 uncompressed encoding of the point. The point at infinity is encoded as a
+// single zero byte.
 func (p *Point) Bytes(curve elliptic.Curve) []byte {
+	if p.IsIdentity() {
+		return []byte{0}
+	}
 	return elliptic.Marshal(curve, p.X, p.Y)
 }
 
-// SetBytes transforms a byte array into a point, using elliptic.UnmarshalSignature method.
+// CompressedBytes returns the SEC1 compressed encoding of the point: 0x02 or 0x03, depending on the parity
+// of y, followed by x. The point at infinity is encoded as a single zero byte.
+func (p *Point) CompressedBytes(curve elliptic.Curve) []byte {
+	if p.IsIdentity() {
+		return []byte{0}
+	}
+	params := curve.Params()
+	y := new(big.Int).Mod(p.Y, params.P)
+	return append([]byte{byte(2 + y.Bit(0))}, padBytes(new(big.Int).Mod(p.X, params.P), (params.BitSize+7)/8)...)
+}
+
+// SetBytes sets the point to the value of its SEC1 encoding, in compressed or uncompressed form, checking
+// that it lies on the curve. A single zero byte is decoded as the point at infinity.
 func (p *Point) SetBytes(curve elliptic.Curve, b []byte) (p2 *Point, err error) {
+	if len(b) > 0 && b[0] != 4 {
+		return p.SetCompressedBytes(curve, b)
+	}
 	x, y := elliptic.Unmarshal(curve, b)
 	if x == nil {
 		err = fmt.Errorf("unmarshaling failed")
 		return
 	}
-	p.X, p.Y = x, y
+	p.X, p.Y, p.curve = x, y, curve
 	p2 = p
 	return
 }
 
+// SetCompressedBytes sets the point to the value of its SEC1 compressed encoding, checking that it lies on
+// the curve. A single zero byte is decoded as the point at infinity.
+func (p *Point) SetCompressedBytes(curve elliptic.Curve, b []byte) (p2 *Point, err error) {
+	if len(b) == 1 && b[0] == 0 {
+		p.X, p.Y, p.curve = new(big.Int), new(big.Int), curve
+		p2 = p
+		return
+	}
+	params := curve.Params()
+	byteLen := (params.BitSize + 7) / 8
+	if len(b) != 1+byteLen || (b[0] != 2 && b[0] != 3) {
+		err = fmt.Errorf("invalid compressed point encoding")
+		return
+	}
+	x := new(big.Int).SetBytes(b[1:])
+	if x.Cmp(params.P) >= 0 {
+		err = fmt.Errorf("x is not lower than field size")
+		return
+	}
+	y := new(big.Int).ModSqrt(curvePolynomial(curve, x), params.P)
+	if y == nil {
+		err = fmt.Errorf("x is not the coordinate of a point in the curve")
+		return
+	}
+	if y.Bit(0) != uint(b[0]&1) {
+		y.Sub(params.P, y)
+	}
+	if !curve.IsOnCurve(x, y) {
+		err = fmt.Errorf("point is not on curve")
+		return
+	}
+	p.X, p.Y, p.curve = x, y, curve
+	p2 = p
+	return
+}
+
+// MarshalText returns the name of the curve of the point and its SEC1 compressed encoding in hexadecimal,
+// separated by a colon (for example, "P-256:02ab..."). The point at infinity is encoded as "P-256:00".
+// It returns an error if the curve of the point is not known.
+func (p *Point) MarshalText() ([]byte, error) {
+	if p.curve == nil {
+		return nil, fmt.Errorf("point has no curve")
+	}
+	name := p.curve.Params().Name
+	if _, ok := CurveNameToCurve[name]; !ok {
+		return nil, fmt.Errorf("curve with name %s unsupported", name)
+	}
+	return []byte(name + ":" + hex.EncodeToString(p.CompressedBytes(p.curve))), nil
+}
+
+// UnmarshalText sets the point to the value of its text encoding, checking that it lies on the tagged curve.
+// Both compressed and uncompressed SEC1 encodings are accepted.
+func (p *Point) UnmarshalText(text []byte) error {
+	parts := strings.SplitN(string(text), ":", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("point encoding must have the form curve:hex")
+	}
+	curve, ok := CurveNameToCurve[parts[0]]
+	if !ok {
+		return fmt.Errorf("curve with name %s unsupported", parts[0])
+	}
+	b, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return err
+	}
+	_, err = p.SetBytes(curve, b)
+	return err
+}
+
+// MarshalJSON returns the text encoding of the point as a JSON string. Points without a curve are encoded
+// as an object with their X and Y coordinates, as in previous versions.
+func (p *Point) MarshalJSON() ([]byte, error) {
+	if p.curve == nil {
+		return json.Marshal(&struct{ X, Y *big.Int }{p.X, p.Y})
+	}
+	text, err := p.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(string(text))
+}
+
+// UnmarshalJSON sets the point to the value of a JSON string with its text encoding, or of an object with its
+// X and Y coordinates. Points decoded from objects have no curve and are not checked to lie on a curve.
+func (p *Point) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		var text string
+		if err := json.Unmarshal(b, &text); err != nil {
+			return err
+		}
+		return p.UnmarshalText([]byte(text))
+	}
+	if bytes.Equal(b, []byte("null")) {
+		return nil
+	}
+	var coords struct{ X, Y *big.Int }
+	if err := json.Unmarshal(b, &coords); err != nil {
+		return err
+	}
+	if coords.X == nil || coords.Y == nil {
+		return fmt.Errorf("point coordinates must be set")
+	}
+	p.X, p.Y, p.curve = coords.X, coords.Y, nil
+	return nil
+}
+
+// IsIdentity returns true if the point is the identity element, the point at infinity, represented as (0,0).
+func (p *Point) IsIdentity() bool {
+	return p.X.Sign() == 0 && p.Y.Sign() == 0
+}
+
+// IsOnCurve returns true if the coordinates of the point are set, lower than the field size of the curve
+// and satisfy its equation. The point at infinity is not on the curve.
+func (p *Point) IsOnCurve(curve elliptic.Curve) bool {
+	return p.X != nil && p.Y != nil && !p.IsIdentity() && curve.IsOnCurve(p.X, p.Y)
+}
+
+// Validate returns an error if the point is not set, if it is the point at infinity or if it does not lie on
+// the curve. Points received from other participants must be validated before being used, to avoid
+// invalid curve attacks.
+func (p *Point) Validate(curve elliptic.Curve) error {
+	if p == nil || p.X == nil || p.Y == nil {
+		return fmt.Errorf("point must be set")
+	}
+	if p.IsIdentity() {
+		return fmt.Errorf("point is the point at infinity")
+	}
+	if !curve.IsOnCurve(p.X, p.Y) {
+		return fmt.Errorf("point is not on curve")
+	}
+	return nil
+}
+
+// reduceCoords returns the coordinates of a point reduced modulo the field size of the curve, as points
+// negated without a curve can have a negative y coordinate.
+func reduceCoords(curve elliptic.Curve, p *Point) (x, y *big.Int) {
+	x, y = p.X, p.Y
+	fieldSize := curve.Params().P
+	if x.Sign() < 0 || x.Cmp(fieldSize) >= 0 {
+		x = new(big.Int).Mod(x, fieldSize)
+	}
+	if y.Sign() < 0 || y.Cmp(fieldSize) >= 0 {
+		y = new(big.Int).Mod(y, fieldSize)
+	}
+	return
+}
+
 // Add adds a point with another, using a given elliptic curve.
 func (p *Point) Add(curve elliptic.Curve, pList ...*Point) *Point {
 	x, y := new(big.Int), new(big.Int)
 	for _, pi := range pList {
-		x, y = curve.Add(x, y, pi.X, pi.Y)
+		xi, yi := reduceCoords(curve, pi)
+		x, y = curve.Add(x, y, xi, yi)
 	}
-	p.X, p.Y = x, y
+	p.X, p.Y, p.curve = x, y, curve
 	return p
 }
 
@@ -2,8 +2,11 @@ package tcecdsa
 
 import (
 	"crypto/elliptic"
+	"encoding/hex"
+	"encoding/json"
 	"github.com/niclabs/tcpaillier"
 	"math/big"
+	"strings"
 	"testing"
 )
 
@@ -166,3 +169,169 @@ func TestPoint_Neg(t *testing.T) {
 		return
 	}
 }
+
+func TestPoint_CompressedBytes(t *testing.T) {
+	for name, c := range CurveNameToCurve {
+		k, err := RandomFieldElement(c)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		for _, p := range []*Point{NewZero().BaseMul(c, k), NewZero().BaseMul(c, one), NewZero()} {
+			b := p.CompressedBytes(c)
+			decoded, err := NewZero().SetCompressedBytes(c, b)
+			if err != nil {
+				t.Errorf("%s: %s", name, err)
+				return
+			}
+			if decoded.Cmp(p) != 0 {
+				t.Errorf("%s: decoded point %s differs from %s", name, decoded, p)
+				return
+			}
+			if decoded, err = NewZero().SetBytes(c, b); err != nil || decoded.Cmp(p) != 0 {
+				t.Errorf("%s: SetBytes should decode compressed points", name)
+				return
+			}
+		}
+		// The parity bit selects the other root, which is the negative of the point.
+		p := NewZero().BaseMul(c, k)
+		b := p.CompressedBytes(c)
+		b[0] ^= 1
+		negative, err := NewZero().SetCompressedBytes(c, b)
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+			return
+		}
+		if negative.X.Cmp(p.X) != 0 || new(big.Int).Add(negative.Y, p.Y).Cmp(c.Params().P) != 0 {
+			t.Errorf("%s: changing the parity should return the negative point", name)
+			return
+		}
+	}
+}
+
+func TestPoint_SetCompressedBytes_Invalid(t *testing.T) {
+	size := (curve.Params().BitSize + 7) / 8
+	offCurve := append([]byte{2}, padBytes(big.NewInt(0), size)...)
+	for x := int64(1); ; x++ {
+		if new(big.Int).ModSqrt(curvePolynomial(curve, big.NewInt(x)), curve.Params().P) == nil {
+			offCurve = append([]byte{2}, padBytes(big.NewInt(x), size)...)
+			break
+		}
+	}
+	tooLarge := append([]byte{2}, curve.Params().P.Bytes()...)
+	for name, b := range map[string][]byte{
+		"Empty":     {},
+		"BadPrefix": append([]byte{5}, p1.CompressedBytes(curve)[1:]...),
+		"Short":     p1.CompressedBytes(curve)[:size],
+		"OffCurve":  offCurve,
+		"TooLarge":  tooLarge,
+	} {
+		if _, err := NewZero().SetCompressedBytes(curve, b); err == nil {
+			t.Errorf("%s encoding should not be decoded", name)
+			return
+		}
+	}
+}
+
+func TestPoint_MarshalJSON(t *testing.T) {
+	c := Secp256k1()
+	p := NewZero().BaseMul(c, r1)
+	for _, point := range []*Point{p, NewZero().SetCurve(c)} {
+		b, err := json.Marshal(point)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if !strings.HasPrefix(string(b), `"secp256k1:`) {
+			t.Errorf("encoding %s is not tagged with the curve", b)
+			return
+		}
+		var decoded Point
+		if err := json.Unmarshal(b, &decoded); err != nil {
+			t.Error(err)
+			return
+		}
+		if decoded.Cmp(point) != 0 || decoded.Curve() != c {
+			t.Errorf("decoded point %s differs from %s", &decoded, point)
+			return
+		}
+	}
+	t.Run("Legacy", func(t *testing.T) {
+		b, err := json.Marshal(NewPoint(p.X, p.Y))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		var decoded Point
+		if err := json.Unmarshal(b, &decoded); err != nil {
+			t.Error(err)
+			return
+		}
+		if decoded.Cmp(p) != 0 || decoded.Curve() != nil {
+			t.Error("points without a curve should be encoded with their coordinates")
+			return
+		}
+	})
+	t.Run("Invalid", func(t *testing.T) {
+		valid := hex.EncodeToString(p.CompressedBytes(c))
+		for _, text := range []string{
+			`"secp256k1"`,
+			`"P-192:` + valid + `"`,
+			`"secp256k1:zz"`,
+			`{"X":1}`,
+		} {
+			var decoded Point
+			if err := json.Unmarshal([]byte(text), &decoded); err == nil {
+				t.Errorf("%s should not be decoded", text)
+				return
+			}
+		}
+	})
+}
+
+func TestPoint_Validate(t *testing.T) {
+	if err := p1.Validate(curve); err != nil {
+		t.Error(err)
+		return
+	}
+	if !p1.IsOnCurve(curve) || p1.IsIdentity() {
+		t.Error("p1 should be on the curve and not be the identity")
+		return
+	}
+	infinity := NewInfinity(curve)
+	if !infinity.IsIdentity() || infinity.IsOnCurve(curve) {
+		t.Error("point at infinity should be the identity and not be on the curve")
+		return
+	}
+	if NewZero().Add(curve, p1, infinity).Cmp(p1) != 0 {
+		t.Error("adding the identity should not change a point")
+		return
+	}
+	if !NewZero().Add(curve, p1, NewZero().Neg(p1)).IsIdentity() {
+		t.Error("adding the negative of a point should return the identity")
+		return
+	}
+	if !NewZero().Mul(curve, p1, curve.Params().N).IsIdentity() {
+		t.Error("multiplying by the order of the curve should return the identity")
+		return
+	}
+	negativeY := NewPoint(p1.X, new(big.Int).Neg(p1.Y))
+	for name, p := range map[string]*Point{
+		"Nil":        nil,
+		"Unset":      {},
+		"Identity":   infinity,
+		"OffCurve":   NewPoint(big.NewInt(1), big.NewInt(1)),
+		"NegativeY":  negativeY,
+		"OtherCurve": NewZero().BaseMul(Secp256k1(), r1),
+	} {
+		if err := p.Validate(curve); err == nil {
+			t.Errorf("%s point should not be valid", name)
+			return
+		}
+	}
+	// Operations reduce the coordinates, so a point negated without a curve can still be used.
+	if NewZero().Add(curve, negativeY).Cmp(NewZero().Neg(p1)) != 0 {
+		t.Error("addition should reduce the coordinates of its points")
+		return
+	}
+}
@@ -0,0 +1,299 @@
+package tcecdsa
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// SignRequest represents the information a participant uses to decide if it signs a hash.
+type SignRequest struct {
+	KeyID     string     // ID of the key used to sign
+	Digest    []byte     // Hash to sign
+	Payload   EthereumTx // Optional transaction the hash was computed from, a *LegacyTx or *DynamicFeeTx
+	Requester string     // Identity of the party that requested the signature
+	Time      time.Time  // Time of the request. If it is zero, the evaluation time is used
+}
+
+// Policy decides if a signature request is approved. Evaluate returns nil if the request is approved,
+// or an error (usually a *PolicyError) with the reason it was denied.
+type Policy interface {
+	Evaluate(req *SignRequest) error
+}
+
+// StatefulPolicy is a Policy that keeps track of the approved requests, like a rate limit.
+// Record is called by PolicySet after every policy of the set approves a request.
+type StatefulPolicy interface {
+	Policy
+	Record(req *SignRequest)
+}
+
+// PolicyFunc is a function that implements Policy.
+type PolicyFunc func(req *SignRequest) error
+
+// PolicyError is returned by a policy that denies a request.
+type PolicyError struct {
+	Rule   string // Name of the rule that denied the request
+	Reason string // Reason of the denial
+}
+
+// Error returns the rule and reason of the denial.
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("policy %s denied the request: %s", e.Rule, e.Reason)
+}
+
+// Evaluate calls the function.
+func (f PolicyFunc) Evaluate(req *SignRequest) error {
+	return f(req)
+}
+
+// PolicySet is a Policy that approves a request only if all its policies approve it.
+// When a request is approved, it is recorded on the stateful policies of the set.
+type PolicySet struct {
+	mutex    sync.Mutex
+	policies []Policy
+}
+
+// AllOf returns a PolicySet with the given policies, which are evaluated in order.
+func AllOf(policies ...Policy) *PolicySet {
+	return &PolicySet{policies: policies}
+}
+
+// Evaluate evaluates every policy of the set, and records the request on the stateful ones if all of them approve it.
+func (set *PolicySet) Evaluate(req *SignRequest) error {
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+	for _, policy := range set.policies {
+		if err := policy.Evaluate(req); err != nil {
+			return err
+		}
+	}
+	for _, policy := range set.policies {
+		if stateful, ok := policy.(StatefulPolicy); ok {
+			stateful.Record(req)
+		}
+	}
+	return nil
+}
+
+// AllowRequesters returns a policy that approves only the requests of the given requesters.
+func AllowRequesters(requesters ...string) Policy {
+	allowed := make(map[string]bool)
+	for _, requester := range requesters {
+		allowed[requester] = true
+	}
+	return PolicyFunc(func(req *SignRequest) error {
+		if !allowed[req.Requester] {
+			return &PolicyError{"requester allow-list", fmt.Sprintf("requester %q is not allowed", req.Requester)}
+		}
+		return nil
+	})
+}
+
+// AllowRecipients returns a policy that approves only Ethereum transactions sent to the given addresses.
+// Contract creations and requests without a transaction payload are denied.
+func AllowRecipients(addresses ...[]byte) Policy {
+	return PolicyFunc(func(req *SignRequest) error {
+		if !isTransaction(req.Payload) {
+			return &PolicyError{"recipient allow-list", "request payload is not an Ethereum transaction"}
+		}
+		var to []byte
+		switch tx := req.Payload.(type) {
+		case *LegacyTx:
+			to = tx.To
+		case *DynamicFeeTx:
+			to = tx.To
+		}
+		for _, address := range addresses {
+			if len(to) > 0 && bytes.Equal(to, address) {
+				return nil
+			}
+		}
+		return &PolicyError{"recipient allow-list", fmt.Sprintf("recipient %x is not allowed", to)}
+	})
+}
+
+// DailyValueLimit is a policy that limits the sum of the values of the transactions signed with each key
+// on a UTC day. Requests without a transaction payload, or with a negative value, are denied, and so is every
+// request if Limit is nil.
+type DailyValueLimit struct {
+	Limit *big.Int
+	mutex sync.Mutex
+	spent map[string]*dailyValue
+}
+
+// dailyValue is the sum of the values of the transactions signed with a key on a day.
+type dailyValue struct {
+	day   string
+	value *big.Int
+}
+
+// NewDailyValueLimit returns a new DailyValueLimit with the given limit.
+func NewDailyValueLimit(limit *big.Int) *DailyValueLimit {
+	return &DailyValueLimit{
+		Limit: limit,
+		spent: make(map[string]*dailyValue),
+	}
+}
+
+// Evaluate denies the request if its value exceeds the remaining daily value of the key.
+func (l *DailyValueLimit) Evaluate(req *SignRequest) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.Limit == nil {
+		return &PolicyError{"daily value limit", "limit is not set"}
+	}
+	if !isTransaction(req.Payload) {
+		return &PolicyError{"daily value limit", "request payload is not an Ethereum transaction"}
+	}
+	value := payloadValue(req.Payload)
+	if value.Sign() < 0 {
+		return &PolicyError{"daily value limit", fmt.Sprintf("value %s is negative", value)}
+	}
+	total := new(big.Int).Add(l.spentOn(req.KeyID, req.day()), value)
+	if total.Cmp(l.Limit) > 0 {
+		return &PolicyError{"daily value limit", fmt.Sprintf("value %s exceeds the daily limit %s of key %s", total, l.Limit, req.KeyID)}
+	}
+	return nil
+}
+
+// Record adds the value of the request to the daily value of the key.
+func (l *DailyValueLimit) Record(req *SignRequest) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	day := req.day()
+	l.spent[req.KeyID] = &dailyValue{
+		day:   day,
+		value: new(big.Int).Add(l.spentOn(req.KeyID, day), payloadValue(req.Payload)),
+	}
+}
+
+// spentOn returns the value signed with the key on the day.
+func (l *DailyValueLimit) spentOn(keyID, day string) *big.Int {
+	if spent, ok := l.spent[keyID]; ok && spent.day == day {
+		return spent.value
+	}
+	return new(big.Int)
+}
+
+// RateLimit is a policy that limits the number of requests approved for each key on a sliding time window.
+type RateLimit struct {
+	Max      int
+	Window   time.Duration
+	mutex    sync.Mutex
+	requests map[string][]time.Time
+}
+
+// NewRateLimit returns a new RateLimit that approves at most max requests per key on each window.
+func NewRateLimit(max int, window time.Duration) *RateLimit {
+	return &RateLimit{
+		Max:      max,
+		Window:   window,
+		requests: make(map[string][]time.Time),
+	}
+}
+
+// Evaluate denies the request if the key has reached the maximum number of requests on the window.
+func (l *RateLimit) Evaluate(req *SignRequest) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if len(l.recent(req.KeyID, req.time())) >= l.Max {
+		return &PolicyError{"rate limit", fmt.Sprintf("key %s reached %d requests per %s", req.KeyID, l.Max, l.Window)}
+	}
+	return nil
+}
+
+// Record adds the request to the window of the key.
+func (l *RateLimit) Record(req *SignRequest) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := req.time()
+	l.requests[req.KeyID] = append(l.recent(req.KeyID, now), now)
+}
+
+// recent returns the times of the requests of the key on the window that ends at now.
+func (l *RateLimit) recent(keyID string, now time.Time) []time.Time {
+	recent := make([]time.Time, 0)
+	for _, t := range l.requests[keyID] {
+		if now.Sub(t) < l.Window {
+			recent = append(recent, t)
+		}
+	}
+	return recent
+}
+
+// SetPolicy sets the policy that must approve the request before Round1 generates any value. If the policy
+// denies it, Round1 returns its error and the session can not continue.
+// The digest of the request must be empty or equal to the hash of the session. If it is empty, it is set.
+// If the request has a payload, its signing hash must be equal to the hash of the session, so the policy
+// evaluates the transaction that is actually signed.
+func (state *SigSession) SetPolicy(policy Policy, req *SignRequest) (err error) {
+	if state.status != NotInited {
+		err = fmt.Errorf("status should be \"Not Inited\" to use this method")
+		return
+	}
+	if policy == nil || req == nil {
+		err = fmt.Errorf("policy and request must be set")
+		return
+	}
+	if len(req.Digest) == 0 {
+		req.Digest = state.m
+	} else if !bytes.Equal(req.Digest, state.m) {
+		err = fmt.Errorf("request digest does not match the session hash")
+		return
+	}
+	if req.Payload != nil {
+		if !isTransaction(req.Payload) {
+			err = fmt.Errorf("unsupported request payload %T", req.Payload)
+			return
+		}
+		if !bytes.Equal(req.Payload.SigningHash(), state.m) {
+			err = fmt.Errorf("request payload signing hash does not match the session hash")
+			return
+		}
+	}
+	state.policy = policy
+	state.request = req
+	return
+}
+
+// time returns the time of the request, or the current time if it is not set.
+func (req *SignRequest) time() time.Time {
+	if req.Time.IsZero() {
+		return time.Now()
+	}
+	return req.Time
+}
+
+// day returns the UTC day of the request.
+func (req *SignRequest) day() string {
+	return req.time().UTC().Format("2006-01-02")
+}
+
+// isTransaction returns true if the payload is a supported Ethereum transaction.
+func isTransaction(payload EthereumTx) bool {
+	switch tx := payload.(type) {
+	case *LegacyTx:
+		return tx != nil
+	case *DynamicFeeTx:
+		return tx != nil
+	}
+	return false
+}
+
+// payloadValue returns the value of a transaction payload, or zero if it is not set.
+func payloadValue(payload EthereumTx) *big.Int {
+	var value *big.Int
+	switch tx := payload.(type) {
+	case *LegacyTx:
+		value = tx.Value
+	case *DynamicFeeTx:
+		value = tx.Value
+	}
+	if value == nil {
+		return new(big.Int)
+	}
+	return value
+}
@@ -0,0 +1,143 @@
+package tcecdsa_test
+
+import (
+	"bytes"
+	"github.com/niclabs/tcecdsa"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestPolicy(t *testing.T) {
+	alice := bytes.Repeat([]byte{0xaa}, 20)
+	bob := bytes.Repeat([]byte{0xbb}, 20)
+	day := time.Date(2020, 7, 1, 12, 0, 0, 0, time.UTC)
+	transfer := func(to []byte, value int64, at time.Time) *tcecdsa.SignRequest {
+		return &tcecdsa.SignRequest{
+			KeyID:     "wallet",
+			Digest:    []byte{1, 2, 3},
+			Payload:   &tcecdsa.LegacyTx{ChainID: big.NewInt(1), To: to, Value: big.NewInt(value)},
+			Requester: "alice",
+			Time:      at,
+		}
+	}
+
+	t.Run("AllowRequesters", func(t *testing.T) {
+		policy := tcecdsa.AllowRequesters("alice", "bob")
+		if err := policy.Evaluate(&tcecdsa.SignRequest{Requester: "bob"}); err != nil {
+			t.Error(err)
+			return
+		}
+		if err := policy.Evaluate(&tcecdsa.SignRequest{Requester: "mallory"}); err == nil {
+			t.Error("request of mallory should be denied")
+			return
+		}
+	})
+
+	t.Run("AllowRecipients", func(t *testing.T) {
+		policy := tcecdsa.AllowRecipients(alice)
+		if err := policy.Evaluate(transfer(alice, 1, day)); err != nil {
+			t.Error(err)
+			return
+		}
+		dynamic := &tcecdsa.SignRequest{Payload: &tcecdsa.DynamicFeeTx{To: alice, Value: big.NewInt(1)}}
+		if err := policy.Evaluate(dynamic); err != nil {
+			t.Error(err)
+			return
+		}
+		if err := policy.Evaluate(transfer(bob, 1, day)); err == nil {
+			t.Error("transaction to bob should be denied")
+			return
+		}
+		if err := policy.Evaluate(transfer(nil, 1, day)); err == nil {
+			t.Error("contract creation should be denied")
+			return
+		}
+		if err := policy.Evaluate(&tcecdsa.SignRequest{}); err == nil {
+			t.Error("request without a transaction should be denied")
+			return
+		}
+	})
+
+	t.Run("DailyValueLimit", func(t *testing.T) {
+		policy := tcecdsa.AllOf(tcecdsa.NewDailyValueLimit(big.NewInt(100)))
+		if err := policy.Evaluate(transfer(alice, 60, day)); err != nil {
+			t.Error(err)
+			return
+		}
+		if err := policy.Evaluate(transfer(alice, 50, day.Add(time.Hour))); err == nil {
+			t.Error("transaction over the daily limit should be denied")
+			return
+		}
+		if err := policy.Evaluate(transfer(alice, 40, day.Add(time.Hour))); err != nil {
+			t.Error(err)
+			return
+		}
+		if err := policy.Evaluate(transfer(alice, 100, day.Add(24*time.Hour))); err != nil {
+			t.Errorf("limit should be reset on the next day: %s", err)
+			return
+		}
+		other := transfer(alice, 100, day.Add(24*time.Hour))
+		other.KeyID = "other"
+		if err := policy.Evaluate(other); err != nil {
+			t.Errorf("limit should be independent for each key: %s", err)
+			return
+		}
+		if err := policy.Evaluate(&tcecdsa.SignRequest{KeyID: "wallet"}); err == nil {
+			t.Error("request without a transaction should be denied")
+			return
+		}
+		if err := policy.Evaluate(transfer(alice, -50, day.Add(48*time.Hour))); err == nil {
+			t.Error("transaction with a negative value should be denied")
+			return
+		}
+		if err := (&tcecdsa.DailyValueLimit{}).Evaluate(transfer(alice, 0, day)); err == nil {
+			t.Error("every request should be denied without a limit")
+			return
+		}
+	})
+
+	t.Run("RateLimit", func(t *testing.T) {
+		policy := tcecdsa.AllOf(tcecdsa.NewRateLimit(2, time.Minute))
+		for i := 0; i < 2; i++ {
+			if err := policy.Evaluate(transfer(alice, 1, day.Add(time.Duration(i)*time.Second))); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+		if err := policy.Evaluate(transfer(alice, 1, day.Add(2*time.Second))); err == nil {
+			t.Error("request over the rate limit should be denied")
+			return
+		}
+		if err := policy.Evaluate(transfer(alice, 1, day.Add(time.Minute))); err != nil {
+			t.Errorf("first request should leave the window: %s", err)
+			return
+		}
+	})
+
+	t.Run("DeniedRequestsAreNotRecorded", func(t *testing.T) {
+		limit := tcecdsa.NewRateLimit(1, time.Minute)
+		policy := tcecdsa.AllOf(limit, tcecdsa.AllowRecipients(alice))
+		if err := policy.Evaluate(transfer(bob, 1, day)); err == nil {
+			t.Error("transaction to bob should be denied")
+			return
+		}
+		if err := policy.Evaluate(transfer(alice, 1, day)); err != nil {
+			t.Errorf("denied request should not count on the rate limit: %s", err)
+			return
+		}
+	})
+
+	t.Run("PolicyError", func(t *testing.T) {
+		err := tcecdsa.AllOf(tcecdsa.AllowRequesters()).Evaluate(transfer(alice, 1, day))
+		denial, ok := err.(*tcecdsa.PolicyError)
+		if !ok {
+			t.Errorf("error should be a policy error, but it is %v", err)
+			return
+		}
+		if denial.Rule != "requester allow-list" || denial.Reason == "" {
+			t.Errorf("unexpected policy error: %s", denial)
+			return
+		}
+	})
+}
@@ -0,0 +1,108 @@
+package tcecdsa
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+)
+
+// SchnorrSignatureSize is the size in bytes of a BIP-340 signature.
+const SchnorrSignatureSize = 64
+
+// schnorrFieldSize is the size in bytes of a BIP-340 x-only public key or R coordinate.
+const schnorrFieldSize = 32
+
+// TaggedHash returns the BIP-340 tagged hash of the concatenation of msgs, defined as
+// SHA256(SHA256(tag) || SHA256(tag) || msgs...).
+func TaggedHash(tag string, msgs ...[]byte) []byte {
+	tagHash := sha256.Sum256([]byte(tag))
+	h := sha256.New()
+	h.Write(tagHash[:])
+	h.Write(tagHash[:])
+	for _, msg := range msgs {
+		h.Write(msg)
+	}
+	return h.Sum(nil)
+}
+
+// SchnorrPublicKey returns the 32 bytes x-only public key defined in BIP-340 for a secp256k1 public key.
+func SchnorrPublicKey(pk *ecdsa.PublicKey) []byte {
+	return fieldBytes(pk.X)
+}
+
+// VerifySchnorr verifies a BIP-340 signature over a message m, using a 32 bytes x-only public key.
+// It returns nil if the signature is valid, or an error describing why it is not.
+func VerifySchnorr(pubKey, m, sig []byte) error {
+	curve := Secp256k1()
+	params := curve.Params()
+	if len(pubKey) != schnorrFieldSize {
+		return fmt.Errorf("public key should be %d bytes long, but it is %d", schnorrFieldSize, len(pubKey))
+	}
+	if len(sig) != SchnorrSignatureSize {
+		return fmt.Errorf("signature should be %d bytes long, but it is %d", SchnorrSignatureSize, len(sig))
+	}
+	y, err := liftX(new(big.Int).SetBytes(pubKey))
+	if err != nil {
+		return err
+	}
+	r := new(big.Int).SetBytes(sig[:schnorrFieldSize])
+	if r.Cmp(params.P) >= 0 {
+		return fmt.Errorf("r is not lower than field size")
+	}
+	s := new(big.Int).SetBytes(sig[schnorrFieldSize:])
+	if s.Cmp(params.N) >= 0 {
+		return fmt.Errorf("s is not lower than curve order")
+	}
+	e := schnorrChallenge(sig[:schnorrFieldSize], pubKey, m)
+	// R = s*G - e*P = s*G + (n-e)*P
+	minusE := new(big.Int).Sub(params.N, e)
+	sG := NewZero().BaseMul(curve, s)
+	eP := NewZero().Mul(curve, y, minusE)
+	R := NewZero().Add(curve, sG, eP)
+	if R.X.Sign() == 0 && R.Y.Sign() == 0 {
+		return fmt.Errorf("R is the point at infinity")
+	}
+	if R.Y.Bit(0) != 0 {
+		return fmt.Errorf("R has an odd y coordinate")
+	}
+	if R.X.Cmp(r) != 0 {
+		return fmt.Errorf("signature verification failed")
+	}
+	return nil
+}
+
+// schnorrChallenge returns the BIP-340 challenge e = int(hash_BIP0340/challenge(r || P || m)) mod n.
+func schnorrChallenge(r, pubKey, m []byte) *big.Int {
+	eHash := TaggedHash("BIP0340/challenge", r, pubKey, m)
+	e := new(big.Int).SetBytes(eHash)
+	return e.Mod(e, Secp256k1().Params().N)
+}
+
+// liftX returns the secp256k1 point with x coordinate x and an even y coordinate.
+func liftX(x *big.Int) (p *Point, err error) {
+	curve := secp256k1
+	if x.Cmp(curve.P) >= 0 {
+		err = fmt.Errorf("x is not lower than field size")
+		return
+	}
+	c := curve.polynomial(x)
+	// P = 3 mod 4, so a square root of c is c^((P+1)/4)
+	exp := new(big.Int).Add(curve.P, one)
+	exp.Rsh(exp, 2)
+	y := new(big.Int).Exp(c, exp, curve.P)
+	if new(big.Int).Exp(y, big.NewInt(2), curve.P).Cmp(c) != 0 {
+		err = fmt.Errorf("x is not the coordinate of a point in the curve")
+		return
+	}
+	if y.Bit(0) != 0 {
+		y.Sub(curve.P, y)
+	}
+	p = NewPoint(new(big.Int).Set(x), y).SetCurve(curve)
+	return
+}
+
+// fieldBytes returns the 32 bytes big endian representation of a field element.
+func fieldBytes(x *big.Int) []byte {
+	return padBytes(x, schnorrFieldSize)
+}
@@ -0,0 +1,205 @@
+package tcecdsa
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"github.com/niclabs/tcecdsa/l2fhe"
+	"math/big"
+)
+
+// SchnorrSession represents a set of values saved and used by the participants
+// to generate a specific BIP-340 Schnorr Signature over secp256k1.
+// It uses the same key shares and Round1 messages than SigSession, but it requires only
+// one round of partial decryptions, because s = k + e*x is linear on the encrypted values.
+// The participants commit to their Round1 messages before revealing them, so no participant can choose its
+// nonce point after seeing the ones of the others, which would allow forgeries with concurrent sessions.
+// It is an ephimeral structure and it lives only while the Signature is being created.
+type SchnorrSession struct {
+	status  Status                   // Session status
+	r, s    *big.Int                 // Final Signature
+	share   *KeyShare                // KeyShare related to the current signing process
+	meta    *KeyMeta                 // KeyMeta related to the current signing process
+	m       []byte                   // Message to sign
+	encS    *l2fhe.EncryptedL1       // Encrypted s value, needed to check ZKProofs
+	msg1    *Round1Message           // Round1 message of the participant, revealed after the commitments
+	commits SchnorrCommitMessageList // Commitments of the Round1 messages of the participants
+}
+
+// NewSchnorrSession creates a new Schnorr signing session, related to a specific non-empty message.
+// The key should have been created over secp256k1 curve and its public key must be already set.
+// As defined in BIP-340, the message is signed as is, so it should be usually a 32 bytes hash.
+func (p *KeyShare) NewSchnorrSession(meta *KeyMeta, m []byte) (state *SchnorrSession, err error) {
+	if len(m) == 0 {
+		err = fmt.Errorf("empty message")
+		return
+	}
+	if meta.Curve() != Secp256k1() {
+		err = fmt.Errorf("schnorr signatures require a secp256k1 key, but curve is %s", meta.CurveName)
+		return
+	}
+	if p.Alpha == nil || p.Y == nil {
+		err = fmt.Errorf("key share has not been set")
+		return
+	}
+	state = &SchnorrSession{
+		share:  p,
+		meta:   meta,
+		status: NotInited,
+		m:      m,
+	}
+	return
+}
+
+// Commit starts the signing process generating a random nonce share, its encryption and the ZKProof of them,
+// and returns a commitment to them. The values are revealed by Round1, after every participant has committed.
+func (state *SchnorrSession) Commit() (msg *SchnorrCommitMessage, err error) {
+	if state.status != NotInited || state.msg1 != nil {
+		err = fmt.Errorf("status should be \"Not Inited\" and the session should not be committed to use this method")
+		return
+	}
+	msg1, err := newRound1Message(state.meta)
+	if err != nil {
+		return
+	}
+	commitment, err := schnorrCommitment(state.meta, msg1)
+	if err != nil {
+		return
+	}
+	state.msg1 = msg1
+	msg = &SchnorrCommitMessage{Commitment: commitment}
+	return
+}
+
+// Round1 receives the commitments of the participants and reveals the values committed by Commit.
+// The message is the same one used on SigSession Round1. The Round1 messages given to Round2 must be in
+// the same order as the commitments.
+func (state *SchnorrSession) Round1(commits SchnorrCommitMessageList) (msg *Round1Message, err error) {
+	if state.status != NotInited || state.msg1 == nil {
+		err = fmt.Errorf("status should be \"Not Inited\" and the session should be committed to use this method")
+		return
+	}
+	if len(commits) < int(state.meta.Paillier.K) {
+		err = fmt.Errorf("length of commitments should be at least K")
+		return
+	}
+	state.commits = commits
+	state.status = Round1
+	msg = state.msg1
+	return
+}
+
+// Round2 joins the Round1 messages to generate the nonce point R, computes the encrypted value
+// s = k + e*x + q*c and generates a partial decryption of it.
+// Following BIP-340, k is negated if R has an odd y coordinate and x is negated if the public key
+// has an odd y coordinate. The random c values hide the sum as an integer, so only s mod q is revealed.
+// Each message must match the commitment on the same position of the list received on Round1, and the
+// messages without a commitment are skipped.
+func (state *SchnorrSession) Round2(msgs Round1MessageList) (msg *SchnorrRound2Message, err error) {
+	if state.status != Round1 {
+		err = fmt.Errorf("status should be \"Round1\" to use this method")
+		return
+	}
+	revealed, err := state.revealed(msgs)
+	if err != nil {
+		return
+	}
+	R, _, v, w, err := revealed.Join(state.meta)
+	if err != nil {
+		return
+	}
+	q := state.meta.Q()
+	minusOne := new(big.Int).Sub(q, one)
+
+	kFactor := one
+	if R.Y.Bit(0) != 0 {
+		kFactor = minusOne
+	}
+	rBytes := fieldBytes(R.X)
+	e := schnorrChallenge(rBytes, fieldBytes(state.share.Y.X), state.m)
+	if state.share.Y.Y.Bit(0) != 0 {
+		e.Mul(e, minusOne).Mod(e, q)
+	}
+
+	kv, err := state.meta.MulConstL1(v, kFactor)
+	if err != nil {
+		return
+	}
+	eAlpha, err := state.meta.MulConstL1(state.share.Alpha, e)
+	if err != nil {
+		return
+	}
+	qw, err := state.meta.MulConstL1(w, q)
+	if err != nil {
+		return
+	}
+	encS, err := state.meta.AddL1(kv, eAlpha, qw)
+	if err != nil {
+		return
+	}
+	pdS, zkp, err := state.meta.PartialDecryptL1(state.share.PaillierShare, encS)
+	if err != nil {
+		return
+	}
+	msg = &SchnorrRound2Message{
+		PDS:   pdS,
+		Proof: zkp,
+	}
+	state.encS = encS
+	state.r = R.X
+	state.status = Round2
+	return
+}
+
+// GetSignature joins the partial decryptions of s and returns the 64 bytes BIP-340 signature.
+func (state *SchnorrSession) GetSignature(msgs SchnorrRound2MessageList) (sig []byte, err error) {
+	if state.status == Finished {
+		// r and s already calculated, return them.
+		sig = append(fieldBytes(state.r), fieldBytes(state.s)...)
+		return
+	}
+	if state.status != Round2 {
+		err = fmt.Errorf("status should be \"Round2\" to use this method")
+		return
+	}
+	s, err := msgs.Join(state.meta, state.encS)
+	if err != nil {
+		return
+	}
+	state.s = s
+	state.status = Finished
+	sig = append(fieldBytes(state.r), fieldBytes(state.s)...)
+	return
+}
+
+// revealed returns the Round1 messages with a commitment, returning an error if one of them does not match
+// its commitment. The messages without a commitment are set to nil, so Join skips them.
+func (state *SchnorrSession) revealed(msgs Round1MessageList) (revealed Round1MessageList, err error) {
+	if len(msgs) != len(state.commits) {
+		err = fmt.Errorf("number of messages (%d) differs from the number of commitments (%d)", len(msgs), len(state.commits))
+		return
+	}
+	revealed = make(Round1MessageList, len(msgs))
+	for i, msg := range msgs {
+		if msg == nil || state.commits[i] == nil {
+			continue
+		}
+		commitment, err := schnorrCommitment(state.meta, msg)
+		if err != nil || subtle.ConstantTimeCompare(commitment, state.commits[i].Commitment) != 1 {
+			return nil, fmt.Errorf("message %d does not match its commitment", i)
+		}
+		revealed[i] = msg
+	}
+	return
+}
+
+// schnorrCommitment returns the commitment to a Round1Message, the SHA-256 hash of its compact encoding.
+// The message contains the random nonce point and encryptions, so its hash also hides it.
+func schnorrCommitment(meta *KeyMeta, msg *Round1Message) ([]byte, error) {
+	encoded, err := msg.MarshalCompact(meta)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(encoded)
+	return hash[:], nil
+}
@@ -0,0 +1,178 @@
+package tcecdsa_test
+
+import (
+	"encoding/hex"
+	"github.com/niclabs/tcecdsa"
+	"math/big"
+	"testing"
+)
+
+const SchnorrCurve = "secp256k1"
+
+// Test vectors taken from BIP-340 reference implementation.
+var schnorrVectors = []struct {
+	pubKey, msg, sig string
+	valid            bool
+}{
+	{
+		pubKey: "F9308A019258C31049344F85F89D5229B531C845836F99B08601F113BCE036F9",
+		msg:    "0000000000000000000000000000000000000000000000000000000000000000",
+		sig:    "E907831F80848D1069A5371B402410364BDF1C5F8307B0084C55F1CE2DCA821525F66A4A85EA8B71E482A74F382D2CE5EBEEE8FDB2172F477DF4900D310536C0",
+		valid:  true,
+	},
+	{
+		pubKey: "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659",
+		msg:    "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+		sig:    "6896BD60EEAE296DB48A229FF71DFE071BDE413E6D43F917DC8DCF8C78DE33418906D11AC976ABCCB20B091292BFF4EA897EFCB639EA871CFA95F6DE339E4B0A",
+		valid:  true,
+	},
+	{
+		pubKey: "DD308AFEC5777E13121FA72B9CC1B7CC0139715309B086C960E18FD969774EB8",
+		msg:    "7E2D58D8B3BCDF1ABADEC7829054F90DDA9805AAB56C77333024B9D0A508B75C",
+		sig:    "5831AAEED7B44BB74E5EAB94BA9D4294C49BCF2A60728D8B4C200F50DD313C1BAB745879A5AD954A72C45A91C3A51D3C7ADEA98D82F8481E0E1E03674A6F3FB7",
+		valid:  true,
+	},
+	{
+		pubKey: "25D1DFF95105F5253C4022F628A996AD3A0D95FBF21D468A1B33F8C160D8F517",
+		msg:    "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF",
+		sig:    "7EB0509757E246F19449885651611CB965ECC1A187DD51B64FDA1EDC9637D5EC97582B9CB13DB3933705B32BA982AF5AF25FD78881EBB32771FC5922EFC66EA3",
+		valid:  true,
+	},
+	{
+		// public key not on the curve
+		pubKey: "EEFDEA4CDB677750A420FEE807EACF21EB9898AE79B9768766E4FAA04A2D4A34",
+		msg:    "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+		sig:    "6CFF5C3BA86C69EA4B7376F31A9BCB4F74C1976089B2D9963DA2E5543E17776969E89B4C5564D00349106B8497785DD7D1D713A8AE82B32FA79D5F7FC407D39B",
+		valid:  false,
+	},
+	{
+		// R has an odd y coordinate
+		pubKey: "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659",
+		msg:    "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+		sig:    "FFF97BD5755EEEA420453A14355235D382F6472F8568A18B2F057A14602975563CC27944640AC607CD107AE10923D9EF7A73C643E166BE5EBEAFA34B1AC553E2",
+		valid:  false,
+	},
+}
+
+func TestVerifySchnorr(t *testing.T) {
+	for i, vector := range schnorrVectors {
+		pubKey, _ := hex.DecodeString(vector.pubKey)
+		msg, _ := hex.DecodeString(vector.msg)
+		sig, _ := hex.DecodeString(vector.sig)
+		err := tcecdsa.VerifySchnorr(pubKey, msg, sig)
+		if vector.valid && err != nil {
+			t.Errorf("vector %d should be valid, but verification failed: %s", i, err)
+		}
+		if !vector.valid && err == nil {
+			t.Errorf("vector %d should be invalid, but verification succeeded", i)
+		}
+	}
+}
+
+func TestSchnorrSession(t *testing.T) {
+	shares, keyMeta, pk, err := newKey(SchnorrCurve)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	states := make([]*tcecdsa.SchnorrSession, 0)
+	commitMessages := make(tcecdsa.SchnorrCommitMessageList, 0)
+	round1Messages := make(tcecdsa.Round1MessageList, 0)
+	round2Messages := make(tcecdsa.SchnorrRound2MessageList, 0)
+	sigs := make([][]byte, 0)
+
+	Hash.Reset()
+	Hash.Write(exampleText)
+	h := Hash.Sum(nil)
+
+	t.Run("NewSchnorrSession", func(t *testing.T) {
+		for _, share := range shares {
+			state, err := share.NewSchnorrSession(keyMeta, h)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			states = append(states, state)
+		}
+	})
+
+	t.Run("Commit", func(t *testing.T) {
+		for _, state := range states {
+			msg, err := state.Commit()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			commitMessages = append(commitMessages, msg)
+		}
+	})
+
+	t.Run("Round1", func(t *testing.T) {
+		for _, state := range states {
+			msg, err := state.Round1(commitMessages)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			round1Messages = append(round1Messages, msg)
+		}
+	})
+
+	t.Run("Round2", func(t *testing.T) {
+		// A participant can not replace its nonce after the commitments.
+		replaced := make(tcecdsa.Round1MessageList, len(round1Messages))
+		copy(replaced, round1Messages)
+		replaced[0], replaced[1] = round1Messages[1], round1Messages[0]
+		if _, err := states[0].Round2(replaced); err == nil {
+			t.Error("messages that do not match their commitments should be rejected")
+			return
+		}
+		for _, state := range states {
+			msg, err := state.Round2(round1Messages)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			round2Messages = append(round2Messages, msg)
+		}
+	})
+
+	t.Run("DecryptionProofs", func(t *testing.T) {
+		// z = r + e*delta*si reveals the key share si unless r is much longer than e*delta*si.
+		for i, msg := range round2Messages {
+			key := shares[i].PaillierShare
+			r := new(big.Int).Mul(msg.Proof.Beta.E, key.Delta)
+			r.Mul(r, key.Si).Sub(msg.Proof.Beta.Z, r)
+			if minBits := (int(key.S)+2)*key.N.BitLen() + 256 - 32; r.BitLen() < minBits {
+				t.Errorf("random value of proof %d has %d bits, but it should have about %d", i, r.BitLen(), minBits+32)
+				return
+			}
+		}
+	})
+
+	t.Run("GetSignature", func(t *testing.T) {
+		for _, state := range states {
+			sig, err := state.GetSignature(round2Messages)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			sigs = append(sigs, sig)
+		}
+	})
+
+	t.Run("Verify", func(t *testing.T) {
+		xOnly := tcecdsa.SchnorrPublicKey(pk)
+		for i, sig := range sigs {
+			if err := tcecdsa.VerifySchnorr(xOnly, h, sig); err != nil {
+				t.Errorf("verification of signature %d failed: %s", i, err)
+				return
+			}
+		}
+		sig := append([]byte{}, sigs[0]...)
+		sig[len(sig)-1] ^= 1
+		if err := tcecdsa.VerifySchnorr(xOnly, h, sig); err == nil {
+			t.Errorf("verification of modified signature should fail")
+		}
+	})
+}
@@ -25,44 +25,72 @@ const (
 type SigSession struct {
 	status   Status             // Session status
 	r, s     *big.Int           // Final Signature
+	bigR     *Point             // Random point, used to get the recovery id of the signature
 	share    *KeyShare          // KeyShare related to the current signing process
 	meta     *KeyMeta           // KeyMeta related to the current signing process
 	sigma, z *l2fhe.EncryptedL2 // Values needed to check ZKProofs
 	m        []byte             // Hashed message
 	encM     *l2fhe.EncryptedL1 // Encrypted hashed message
 	u        *l2fhe.EncryptedL1 // Value used between rounds 2 and 3 in signing process
+	policy   Policy             // Policy that must approve the request before Round1
+	request  *SignRequest       // Request evaluated by the policy
+	msgs1    Round1MessageList  // Messages received on each round, kept for the transcript
+	msgs2    Round2MessageList
+	msgs3    Round3MessageList
+	signers  TranscriptSigners // Positions of the messages combined on each round
 }
 
 // Round1 starts the signing process generating a set of random values and the ZKProof of them.
 // It represents Round 1 and Round 2 in paper, because our implementation doesn't consider the usage of commits.
+// If the policy of the session denies the request, the session can not be used anymore.
 func (state *SigSession) Round1() (msg *Round1Message, err error) {
+	defer startSpan(SpanRound1).end(&err)
 	if state.status != NotInited {
 		err = fmt.Errorf("status should be \"Not Inited\" to use this method")
+		return
+	}
+	if state.policy != nil {
+		if err = state.policy.Evaluate(state.request); err != nil {
+			// A denied session can not continue, so the policy is not evaluated again.
+			state.status = Undefined
+			return
+		}
+	}
+	msg, err = newRound1Message(state.meta)
+	if err != nil {
+		return
 	}
+	state.status = Round1
+	return
+}
+
+// newRound1Message generates a set of random values, their encryptions and the ZKProof of them,
+// returning them as a Round1Message. It is shared by all the signing sessions.
+func newRound1Message(meta *KeyMeta) (msg *Round1Message, err error) {
 	// choose rho_i, k_i random from Z_q, and c_i from [-q^6, q^6]
-	rho, err := RandomInRange(zero, state.meta.Q())
+	rho, err := RandomInRange(zero, meta.Q())
 	if err != nil {
 		return
 	}
-	k, err := RandomInRange(zero, state.meta.Q())
+	k, err := RandomInRange(zero, meta.Q())
 	if err != nil {
 		return
 	}
-	qToSix := new(big.Int).Exp(state.meta.Q(), big.NewInt(6), nil)
+	qToSix := new(big.Int).Exp(meta.Q(), big.NewInt(6), nil)
 	ci, err := RandomInRange(zero, qToSix)
 	if err != nil {
 		return
 	}
-	ri := NewZero().BaseMul(state.meta.Curve(), k)
-	ui, rui, err := state.meta.Encrypt(rho)
+	ri := meta.baseMul(k)
+	ui, rui, err := meta.Encrypt(rho)
 	if err != nil {
 		return
 	}
-	vi, rvi, err := state.meta.Encrypt(k)
+	vi, rvi, err := meta.Encrypt(k)
 	if err != nil {
 		return
 	}
-	wi, rwi, err := state.meta.Encrypt(ci)
+	wi, rwi, err := meta.Encrypt(ci)
 	if err != nil {
 		return
 	}
@@ -78,7 +106,10 @@ func (state *SigSession) Round1() (msg *Round1Message, err error) {
 		RandVi: rvi,
 		RandWi: rwi,
 	}
-	proof, err := NewSigZKProof(state.meta, proofParams)
+	proof, err := NewSigZKProof(meta, proofParams)
+	if err != nil {
+		return
+	}
 
 	msg = &Round1Message{
 		Ri:    ri,
@@ -87,83 +118,100 @@ func (state *SigSession) Round1() (msg *Round1Message, err error) {
 		Wi:    wi,
 		Proof: proof,
 	}
-	state.status = Round1
 	return
 }
 
 // Round2 uses the values generated in Round1 to generate R and u, a value that is needed for GetSignature
 // It is Round 3 in paper.
 func (state *SigSession) Round2(msgs Round1MessageList) (msg *Round2Message, err error) {
+	defer startSpan(SpanRound2).end(&err)
+	return state.round2(msgs, false)
+}
+
+// round2 executes Round2. If verified is true, the messages were already verified, so their proofs are not
+// checked again.
+func (state *SigSession) round2(msgs Round1MessageList, verified bool) (msg *Round2Message, err error) {
 	if state.status != Round1 {
 		err = fmt.Errorf("status should be \"Round1\" to use this method")
-	}
-	R, u, v, w, err := msgs.Join(state.meta)
-	uv, err := state.meta.Mul(v, u)
-	if err != nil {
 		return
 	}
-	qw, err := state.meta.MulConstL1(w, state.meta.Q())
+	var R *Point
+	var u, v, w *l2fhe.EncryptedL1
+	var positions []int
+	if verified {
+		R, u, v, w, err = msgs.join(state.meta)
+		positions = firstPositions(state.meta)
+	} else {
+		R, u, v, w, positions, err = msgs.verifyAndJoin(state.meta)
+	}
 	if err != nil {
 		return
 	}
-	qwL2, err := qw.ToL2(state.meta.PubKey)
+	z, err := newZ(state.meta, u, v, w)
 	if err != nil {
 		return
 	}
-	z, err := state.meta.AddL2(uv, qwL2)
+
+	pdZ, zkp, commitments, err := state.meta.partialDecryptL2(state.share.PaillierShare, z)
 	if err != nil {
 		return
 	}
-
-	pdZ, zkp, err := state.meta.PartialDecryptL2(state.share.PaillierShare, z)
-	r := R.X
+	r := new(big.Int).Mod(R.X, state.meta.Q())
 
 	msg = &Round2Message{
-		PDZ:   pdZ,
-		Proof: zkp,
+		PDZ:         pdZ,
+		Proof:       zkp,
+		Commitments: commitments,
 	}
 	state.z = z
+	state.msgs1 = msgs
+	state.signers.Round1 = positions
 	state.status = Round2
-	state.u, state.r = u, r
+	state.u, state.r, state.bigR = u, r, R
 	return
 }
 
 // Round3 joins the partially decrypted Z of the last round and generates a partial decryption of sigma.
 // It is Round 4 in paper
 func (state *SigSession) Round3(msgs Round2MessageList) (msg *Round3Message, err error) {
+	defer startSpan(SpanRound3).end(&err)
+	return state.round3(msgs, false)
+}
+
+// round3 executes Round3. If verified is true, the messages were already verified, so their proofs are not
+// checked again.
+func (state *SigSession) round3(msgs Round2MessageList, verified bool) (msg *Round3Message, err error) {
 	if state.status != Round2 {
 		err = fmt.Errorf("status should be \"Round2\" to use this method")
-	}
-	nu, err := msgs.Join(state.meta, state.z)
-	if err != nil {
 		return
 	}
-	psi := new(big.Int).ModInverse(nu, state.meta.Q())
-	vHat, err := state.meta.MulConstL1(state.u, psi)
-	if err != nil {
-		return
-	}
-	rAlpha, err := state.meta.MulConstL1(state.share.Alpha, state.r)
-	if err != nil {
-		return
+	var nu *big.Int
+	var positions []int
+	if verified {
+		nu, err = msgs.join(state.meta)
+		positions = firstPositions(state.meta)
+	} else {
+		nu, positions, err = msgs.verifyAndJoin(state.meta, state.z)
 	}
-	rAlphaPlusEncM, err := state.meta.AddL1(rAlpha, state.encM)
 	if err != nil {
 		return
 	}
-	sigma, err := state.meta.Mul(rAlphaPlusEncM, vHat)
+	sigma, err := newSigma(state.meta, state.share.Alpha, state.encM, state.u, state.r, nu)
 	if err != nil {
 		return
 	}
-	pdSigma, zkp, err := state.meta.PartialDecryptL2(state.share.PaillierShare, sigma)
+	pdSigma, zkp, commitments, err := state.meta.partialDecryptL2(state.share.PaillierShare, sigma)
 	if err != nil {
 		return
 	}
 	msg = &Round3Message{
-		PDSigma: pdSigma,
-		Proof:   zkp,
+		PDSigma:     pdSigma,
+		Proof:       zkp,
+		Commitments: commitments,
 	}
 	state.sigma = sigma
+	state.msgs2 = msgs
+	state.signers.Round2 = positions
 	state.status = Round3
 	return
 }
@@ -171,6 +219,7 @@ func (state *SigSession) Round3(msgs Round2MessageList) (msg *Round3Message, err
 // GetSignature joins the last values and returns the Signature.
 // It is described in the paper as the joining process of partially decrypted values.
 func (state *SigSession) GetSignature(msgs Round3MessageList) (r, s *big.Int, err error) {
+	defer startSpan(SpanGetSignature).end(&err)
 	if state.status == Finished {
 		// Ri and S already calculated, return them.
 		r, s = state.r, state.s
@@ -178,13 +227,106 @@ func (state *SigSession) GetSignature(msgs Round3MessageList) (r, s *big.Int, er
 	}
 	if state.status != Round3 {
 		err = fmt.Errorf("status should be \"Round3\" to use this method")
+		return
 	}
-	s, err = msgs.Join(state.meta, state.sigma)
+	s, positions, err := msgs.verifyAndJoin(state.meta, state.sigma)
 	if err != nil {
 		return
 	}
 	r = state.r
 	state.s = s
+	state.msgs3 = msgs
+	state.signers.Round3 = positions
 	state.status = Finished
 	return
 }
+
+// Signers returns the positions, on the lists received by the session, of the messages combined on each round
+// executed so far. They are the first K valid messages of each list.
+func (state *SigSession) Signers() *TranscriptSigners {
+	return &TranscriptSigners{
+		Round1: append([]int{}, state.signers.Round1...),
+		Round2: append([]int{}, state.signers.Round2...),
+		Round3: append([]int{}, state.signers.Round3...),
+	}
+}
+
+// RandomPoint returns a copy of the random point R of the session, whose x coordinate is r, or nil if Round2
+// has not been executed.
+func (state *SigSession) RandomPoint() *Point {
+	if state.bigR == nil {
+		return nil
+	}
+	return state.bigR.Clone()
+}
+
+// GetRecoverableSignature joins the last values and returns the Signature with its recovery id v.
+// The lowest bit of v is the parity of the y coordinate of R, and the second one is set when the x
+// coordinate of R was reduced modulo the curve order to obtain r.
+func (state *SigSession) GetRecoverableSignature(msgs Round3MessageList) (r, s *big.Int, v byte, err error) {
+	r, s, err = state.GetSignature(msgs)
+	if err != nil {
+		return
+	}
+	v = byte(state.bigR.Y.Bit(0))
+	if state.bigR.X.Cmp(state.meta.Q()) >= 0 {
+		v |= 2
+	}
+	return
+}
+
+// GetLowSSignature joins the last values and returns the Signature in canonical low-S form,
+// as required by Bitcoin and Ethereum.
+func (state *SigSession) GetLowSSignature(msgs Round3MessageList) (r, s *big.Int, err error) {
+	r, s, err = state.GetSignature(msgs)
+	if err != nil {
+		return
+	}
+	s, _ = NormalizeLowS(state.meta.Curve(), s)
+	return
+}
+
+// newZ returns the encrypted value z = u*v + q*w, which is partially decrypted in Round 2.
+func newZ(meta *KeyMeta, u, v, w *l2fhe.EncryptedL1) (z *l2fhe.EncryptedL2, err error) {
+	uv, err := meta.Mul(v, u)
+	if err != nil {
+		return
+	}
+	qw, err := meta.MulConstL1(w, meta.Q())
+	if err != nil {
+		return
+	}
+	qwL2, err := qw.ToL2(meta.PubKey)
+	if err != nil {
+		return
+	}
+	return meta.AddL2(uv, qwL2)
+}
+
+// newSigma returns the encrypted value sigma = (r*alpha + m)*(u/nu), which is partially decrypted in Round 3.
+func newSigma(meta *KeyMeta, alpha, encM, u *l2fhe.EncryptedL1, r, nu *big.Int) (sigma *l2fhe.EncryptedL2, err error) {
+	psi := new(big.Int).ModInverse(nu, meta.Q())
+	vHat, err := meta.MulConstL1(u, psi)
+	if err != nil {
+		return
+	}
+	rAlpha, err := meta.MulConstL1(alpha, r)
+	if err != nil {
+		return
+	}
+	rAlphaPlusEncM, err := meta.AddL1(rAlpha, encM)
+	if err != nil {
+		return
+	}
+	return meta.Mul(rAlphaPlusEncM, vHat)
+}
+
+// firstPositions returns the positions of the first K messages of a list, which are the ones combined when
+// the list was already verified.
+func firstPositions(meta *KeyMeta) []int {
+	positions := make([]int, meta.Paillier.K)
+	for i := range positions {
+		positions[i] = i
+	}
+	return positions
+}
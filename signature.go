@@ -0,0 +1,106 @@
+package tcecdsa
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"fmt"
+	"math/big"
+)
+
+// maxDERSignatureSize is the maximum size of a DER signature with 66 bytes integers (P-521),
+// that still uses short form lengths for the integers.
+const maxDERSignatureSize = 3 + 2*(2+67)
+
+// IsLowS returns true if s is lower or equal than half the order of the curve.
+func IsLowS(curve elliptic.Curve, s *big.Int) bool {
+	halfN := new(big.Int).Rsh(curve.Params().N, 1)
+	return s.Cmp(halfN) <= 0
+}
+
+// NormalizeLowS returns the canonical low-S form of s, defined as N - s if s is greater than half the order
+// N of the curve. The second returned value is true if s was changed, meaning that the y parity of the
+// recovery id of the signature must be flipped.
+func NormalizeLowS(curve elliptic.Curve, s *big.Int) (lowS *big.Int, flipped bool) {
+	if IsLowS(curve, s) {
+		return new(big.Int).Set(s), false
+	}
+	return new(big.Int).Sub(curve.Params().N, s), true
+}
+
+// VerifyCanonical verifies an ECDSA signature over a hash h, rejecting it if s is not in low-S form.
+// It returns nil if the signature is valid, or an error describing why it is not.
+func VerifyCanonical(pk *ecdsa.PublicKey, h []byte, r, s *big.Int) error {
+	if !IsLowS(pk.Curve, s) {
+		return fmt.Errorf("s is not in low-S form")
+	}
+	if !ecdsa.Verify(pk, h, r, s) {
+		return fmt.Errorf("signature verification failed")
+	}
+	return nil
+}
+
+// checkStrictDER checks that a signature follows strict DER encoding, as defined in BIP-66:
+// a sequence of two positive integers, with minimal lengths and integer encodings, and no trailing data.
+func checkStrictDER(sig []byte) error {
+	if len(sig) < 8 || len(sig) > maxDERSignatureSize {
+		return fmt.Errorf("invalid signature length %d", len(sig))
+	}
+	if sig[0] != 0x30 {
+		return fmt.Errorf("signature is not a sequence")
+	}
+	seqLen, offset, err := derLength(sig, 1)
+	if err != nil {
+		return err
+	}
+	if offset+seqLen != len(sig) {
+		return fmt.Errorf("sequence length does not match signature length")
+	}
+	for _, name := range []string{"r", "s"} {
+		if offset+2 > len(sig) || sig[offset] != 0x02 {
+			return fmt.Errorf("%s is not an integer", name)
+		}
+		intLen, intOffset, err := derLength(sig, offset+1)
+		if err != nil {
+			return err
+		}
+		if intLen == 0 {
+			return fmt.Errorf("%s has zero length", name)
+		}
+		if intOffset+intLen > len(sig) {
+			return fmt.Errorf("%s length exceeds signature length", name)
+		}
+		if sig[intOffset]&0x80 != 0 {
+			return fmt.Errorf("%s is negative", name)
+		}
+		if intLen > 1 && sig[intOffset] == 0 && sig[intOffset+1]&0x80 == 0 {
+			return fmt.Errorf("%s is not minimally encoded", name)
+		}
+		offset = intOffset + intLen
+	}
+	if offset != len(sig) {
+		return fmt.Errorf("signature has trailing data")
+	}
+	return nil
+}
+
+// derLength parses a DER length starting on offset, returning the length and the offset of the content.
+// It accepts only minimal encodings, with at most one length byte in long form.
+func derLength(b []byte, offset int) (length, contentOffset int, err error) {
+	if offset >= len(b) {
+		err = fmt.Errorf("missing length")
+		return
+	}
+	first := b[offset]
+	if first < 0x80 {
+		return int(first), offset + 1, nil
+	}
+	if first != 0x81 || offset+1 >= len(b) {
+		err = fmt.Errorf("unsupported length encoding")
+		return
+	}
+	if b[offset+1] < 0x80 {
+		err = fmt.Errorf("length is not minimally encoded")
+		return
+	}
+	return int(b[offset+1]), offset + 2, nil
+}
@@ -0,0 +1,77 @@
+package tcecdsa_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/niclabs/tcecdsa"
+	"math/big"
+	"testing"
+)
+
+func TestUnmarshalSignature(t *testing.T) {
+	r, s := big.NewInt(0x1234), big.NewInt(0x80)
+	sig, err := tcecdsa.MarshalSignature(r, s)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	r2, s2, err := tcecdsa.UnmarshalSignature(sig)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if r.Cmp(r2) != 0 || s.Cmp(s2) != 0 {
+		t.Errorf("unmarshaled signature is different to the original one")
+		return
+	}
+
+	invalid := map[string]string{
+		"trailing data":      hex.EncodeToString(sig) + "00",
+		"negative integer":   "3007020212340201ff",
+		"non-minimal int":    "30080203001234020101",
+		"missing zero byte":  "3007020212340201" + "80",
+		"zero integer":       "3006020100020101",
+		"non-minimal length": "30810702021234020101",
+		"wrong sequence len": "3008020212340201" + "01",
+	}
+	for name, encoded := range invalid {
+		b, _ := hex.DecodeString(encoded)
+		if _, _, err := tcecdsa.UnmarshalSignature(b); err == nil {
+			t.Errorf("signature with %s should have been rejected", name)
+		}
+	}
+}
+
+func TestNormalizeLowS(t *testing.T) {
+	curve := elliptic.P256()
+	sk, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	h := make([]byte, 32)
+	r, s, err := ecdsa.Sign(rand.Reader, sk, h)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	highS := s
+	if tcecdsa.IsLowS(curve, s) {
+		highS = new(big.Int).Sub(curve.Params().N, s)
+	}
+	if err := tcecdsa.VerifyCanonical(&sk.PublicKey, h, r, highS); err == nil {
+		t.Errorf("high-S signature should have been rejected")
+		return
+	}
+	lowS, flipped := tcecdsa.NormalizeLowS(curve, highS)
+	if !flipped {
+		t.Errorf("high-S value should have been flipped")
+		return
+	}
+	if err := tcecdsa.VerifyCanonical(&sk.PublicKey, h, r, lowS); err != nil {
+		t.Error(err)
+		return
+	}
+}
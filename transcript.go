@@ -0,0 +1,118 @@
+package tcecdsa
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"github.com/niclabs/tcecdsa/l2fhe"
+	"math/big"
+)
+
+// Transcript represents the public data of a finished signing session: the key metadata, the public values
+// of the key, the signed hash, the messages of every round and the signature. It contains no private values,
+// so it can be exported (for example, JSON encoded) and verified offline with Verify.
+type Transcript struct {
+	Meta   *KeyMeta
+	Alpha  *l2fhe.EncryptedL1 // Encrypted private key
+	Y      *Point             // Public key
+	Digest []byte             // Signed hash
+	Round1 Round1MessageList
+	Round2 Round2MessageList
+	Round3 Round3MessageList
+	R, S   *big.Int
+}
+
+// TranscriptSigners represents the positions of the messages combined on each round of a transcript.
+type TranscriptSigners struct {
+	Round1, Round2, Round3 []int
+}
+
+// Transcript returns the transcript of a finished session.
+func (state *SigSession) Transcript() (t *Transcript, err error) {
+	if state.status != Finished {
+		err = fmt.Errorf("status should be \"Finished\" to use this method")
+		return
+	}
+	t = &Transcript{
+		Meta:   state.meta,
+		Alpha:  state.share.Alpha,
+		Y:      state.share.Y,
+		Digest: state.m,
+		Round1: state.msgs1,
+		Round2: state.msgs2,
+		Round3: state.msgs3,
+		R:      state.r,
+		S:      state.s,
+	}
+	return
+}
+
+// Verify replays the signing session using only the public data of the transcript. It verifies the proofs
+// of every message, recomputes the values combined on each round as SigSession does, and checks that they
+// produce the recorded signature and that it is valid for the recorded public key.
+// It returns the positions of the messages combined on each round.
+func (t *Transcript) Verify() (signers *TranscriptSigners, err error) {
+	if t.Meta == nil || t.Alpha == nil || t.Y == nil || t.R == nil || t.S == nil || len(t.Digest) == 0 {
+		err = fmt.Errorf("transcript fields must be set")
+		return
+	}
+	meta := t.Meta
+	k := int(meta.Paillier.K)
+	if err = t.Y.Validate(meta.Curve()); err != nil {
+		err = fmt.Errorf("invalid public key: %s", err)
+		return
+	}
+	encM, err := meta.EncryptFixedB(HashToInt(t.Digest, meta.Curve()), one, one)
+	if err != nil {
+		return
+	}
+
+	valid1, positions1, err := t.Round1.verified(meta)
+	if err != nil {
+		err = fmt.Errorf("round 1: %s", err)
+		return
+	}
+	R, u, v, w, err := valid1.join(meta)
+	if err != nil {
+		err = fmt.Errorf("round 1: %s", err)
+		return
+	}
+	z, err := newZ(meta, u, v, w)
+	if err != nil {
+		return
+	}
+
+	valid2, positions2 := t.Round2.verified(meta, z)
+	nu, err := valid2.join(meta)
+	if err != nil {
+		err = fmt.Errorf("round 2: %s", err)
+		return
+	}
+	r := new(big.Int).Mod(R.X, meta.Q())
+	sigma, err := newSigma(meta, t.Alpha, encM, u, r, nu)
+	if err != nil {
+		return
+	}
+
+	valid3, positions3 := t.Round3.verified(meta, sigma)
+	s, err := valid3.join(meta)
+	if err != nil {
+		err = fmt.Errorf("round 3: %s", err)
+		return
+	}
+
+	if r.Cmp(t.R) != 0 || s.Cmp(t.S) != 0 {
+		err = fmt.Errorf("recorded signature is not the one produced by the messages")
+		return
+	}
+	pk := &ecdsa.PublicKey{Curve: meta.Curve(), X: t.Y.X, Y: t.Y.Y}
+	if !ecdsa.Verify(pk, t.Digest, r, s) {
+		err = fmt.Errorf("signature verification failed")
+		return
+	}
+	signers = &TranscriptSigners{
+		Round1: positions1[:k],
+		Round2: positions2[:k],
+		Round3: positions3[:k],
+	}
+	return
+}
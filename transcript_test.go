@@ -0,0 +1,113 @@
+package tcecdsa_test
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"github.com/niclabs/tcecdsa"
+	"github.com/niclabs/tcpaillier"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func TestTranscript_Verify(t *testing.T) {
+	params := &tcecdsa.NewKeyParams{
+		PaillierFixed: &tcpaillier.FixedParams{
+			P:  p,
+			P1: p1,
+			Q:  q,
+			Q1: q1,
+		},
+	}
+	shares, keyMeta, err := tcecdsa.NewKey(L, K, Curve, params)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := initKey(shares, keyMeta); err != nil {
+		t.Error(err)
+		return
+	}
+	h := sha256.Sum256(exampleText)
+	states := make([]*tcecdsa.SigSession, 0)
+	for _, share := range shares[:K] {
+		state, err := share.NewSigSession(keyMeta, h[:])
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		states = append(states, state)
+	}
+	if _, err := states[0].Transcript(); err == nil {
+		t.Error("transcript of an unfinished session should fail")
+		return
+	}
+	round3Messages, err := runRounds(states)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if _, _, err := states[0].GetSignature(round3Messages); err != nil {
+		t.Error(err)
+		return
+	}
+	transcript, err := states[0].Transcript()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	b, err := json.Marshal(transcript)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	// decode returns a new copy of the exported transcript.
+	decode := func() *tcecdsa.Transcript {
+		var decoded tcecdsa.Transcript
+		if err := json.Unmarshal(b, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		return &decoded
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		signers, err := decode().Verify()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		expected := []int{0, 1, 2}
+		if !reflect.DeepEqual(signers.Round1, expected) || !reflect.DeepEqual(signers.Round2, expected) || !reflect.DeepEqual(signers.Round3, expected) {
+			t.Errorf("unexpected signers %+v", signers)
+			return
+		}
+	})
+
+	t.Run("OtherSignature", func(t *testing.T) {
+		other := decode()
+		other.S = new(big.Int).Add(other.S, big.NewInt(1))
+		if _, err := other.Verify(); err == nil {
+			t.Error("transcript with another signature should fail")
+			return
+		}
+	})
+
+	t.Run("OtherDigest", func(t *testing.T) {
+		other := decode()
+		h := sha256.Sum256([]byte("other text"))
+		other.Digest = h[:]
+		if _, err := other.Verify(); err == nil {
+			t.Error("transcript with another digest should fail")
+			return
+		}
+	})
+
+	t.Run("TamperedMessage", func(t *testing.T) {
+		other := decode()
+		other.Round1[0].Ri = other.Round1[1].Ri
+		if _, err := other.Verify(); err == nil {
+			t.Error("transcript with a tampered message should fail")
+			return
+		}
+	})
+}
@@ -17,10 +17,11 @@ type Signature struct {
 }
 
 var CurveNameToCurve = map[string]elliptic.Curve{
-	"P-224": elliptic.P224(),
-	"P-256": elliptic.P256(),
-	"P-384": elliptic.P384(),
-	"P-521": elliptic.P521(),
+	"P-224":     elliptic.P224(),
+	"P-256":     elliptic.P256(),
+	"P-384":     elliptic.P384(),
+	"P-521":     elliptic.P521(),
+	"secp256k1": Secp256k1(),
 }
 
 // RandomFieldElement returns A random element of the field underlying the given
@@ -84,14 +85,22 @@ func MarshalSignature(r, s *big.Int) ([]byte, error) {
 	return asn1.Marshal(Signature{r, s})
 }
 
+// UnmarshalSignature parses a DER encoded signature. It rejects encodings that are not strict DER,
+// like the ones with trailing data, non-minimal lengths or integers, and negative or zero values.
 func UnmarshalSignature(sigByte []byte) (r, s *big.Int, err error) {
+	if err = checkStrictDER(sigByte); err != nil {
+		return
+	}
 	var sig Signature
 	rest, err := asn1.Unmarshal(sigByte, &sig)
+	if err != nil {
+		return
+	}
 	if len(rest) > 0 {
 		return nil, nil, fmt.Errorf("rest should be empty")
 	}
-	if err != nil {
-		return
+	if sig.R.Sign() <= 0 || sig.S.Sign() <= 0 {
+		return nil, nil, fmt.Errorf("r and s should be positive")
 	}
 	r, s = sig.R, sig.S
 	return
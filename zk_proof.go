@@ -9,8 +9,6 @@ import (
 	"math/big"
 )
 
-var hash = sha256.New()
-
 
 // ZKProofMeta contains the RSA parameters required to create ZKProofs.
 type ZKProofMeta struct {
@@ -71,13 +69,12 @@ func genZKProofMeta() (*ZKProofMeta, error) {
 
 // newKeyGenZKProof creates the Key Generation ZKProof used by the protocol.
 func newKeyGenZKProof(meta *KeyMeta, xi *big.Int, yi *Point, wFHE *l2fhe.EncryptedL1, r *big.Int) (proof *KeyGenZKProof, err error) {
+	defer startSpan(SpanNewKeyGenProof).end(&err)
 	n := meta.Paillier.N
 	q := meta.Q()
 
 	cache := meta.Paillier.Cache()
-	nPlusOne := cache.NPlusOne
 	nToSPlusOne := cache.NToSPlusOne
-	h1, h2 := meta.H1, meta.H2
 	qToThree := new(big.Int).Mul(q, new(big.Int).Mul(q, q))
 	nTilde := meta.NTilde
 	qnTilde := new(big.Int).Mul(q, nTilde)
@@ -100,25 +97,22 @@ func newKeyGenZKProof(meta *KeyMeta, xi *big.Int, yi *Point, wFHE *l2fhe.Encrypt
 		return
 	}
 
-	z := new(big.Int).Exp(h1, xi, nTilde)
-	z.Mul(z, new(big.Int).Exp(h2, rho, nTilde)).Mod(z, nTilde)
+	z := meta.expH1H2(xi, rho)
 
-	u1 := NewZero().BaseMul(meta.Curve(), alpha)
+	u1 := meta.baseMul(alpha)
 
-	u2 := new(big.Int).Exp(nPlusOne, alpha, nToSPlusOne)
+	u2 := meta.expGenerator(alpha)
 	u2.Mul(u2, new(big.Int).Exp(beta, n, nToSPlusOne)).
 		Mod(u2, nToSPlusOne)
 
-	u3 := new(big.Int).Exp(h1, alpha, nTilde)
-	u3.Mul(u3, new(big.Int).Exp(h2, gamma, nTilde)).
-		Mod(u3, nTilde)
+	u3 := meta.expH1H2(alpha, gamma)
 
 	w, err := wFHE.ToPaillier(meta.PubKey.Paillier)
 	if err != nil {
 		return
 	}
 
-	hash.Reset()
+	hash := sha256.New()
 	hash.Write(meta.G().Bytes(meta.Curve()))
 	hash.Write(yi.Bytes(meta.Curve()))
 	hash.Write(w.Bytes())
@@ -154,7 +148,8 @@ func newKeyGenZKProof(meta *KeyMeta, xi *big.Int, yi *Point, wFHE *l2fhe.Encrypt
 
 // Verify verifies a ZKProof of KeyGenZKProof type. It receives the key metainfo and 2 arguments, representing
 // the public key share (a point), and the encrypted private key share.
-func (p *KeyGenZKProof) Verify(meta *KeyMeta, vals ...interface{}) error {
+func (p *KeyGenZKProof) Verify(meta *KeyMeta, vals ...interface{}) (err error) {
+	defer startSpan(SpanVerifyKeyGenProof).end(&err)
 	if len(vals) != 2 {
 		return fmt.Errorf("the verification requires three values: yi (*Point) and w (*l2fhe.EncryptedL1)")
 	}
@@ -166,12 +161,16 @@ func (p *KeyGenZKProof) Verify(meta *KeyMeta, vals ...interface{}) error {
 	if !ok {
 		return fmt.Errorf("decryption share verification requires a *EncryptedL1 as second argument")
 	}
+	if err = yi.Validate(meta.Curve()); err != nil {
+		return fmt.Errorf("invalid yi: %s", err)
+	}
+	if err = p.U1.Validate(meta.Curve()); err != nil {
+		return fmt.Errorf("invalid U1: %s", err)
+	}
 
 	n := meta.Paillier.N
 	cache := meta.Paillier.Cache()
-	nPlusOne := cache.NPlusOne
 	nToSPlusOne := cache.NToSPlusOne
-	h1, h2 := meta.H1, meta.H2
 	nTilde := meta.NTilde
 
 	w, err := wFHE.ToPaillier(meta.PubKey.Paillier)
@@ -179,22 +178,20 @@ func (p *KeyGenZKProof) Verify(meta *KeyMeta, vals ...interface{}) error {
 		return err
 	}
 
-	u1 := NewZero().BaseMul(meta.Curve(), p.S1)
+	u1 := meta.baseMul(p.S1)
 	pu1 := NewZero().Add(meta.Curve(), p.U1, NewZero().Mul(meta.Curve(), yi, p.E))
 
-	u2 := new(big.Int).Exp(nPlusOne, p.S1, nToSPlusOne)
+	u2 := meta.expGenerator(p.S1)
 	u2.Mul(u2, new(big.Int).Exp(p.S2, n, nToSPlusOne)).
 		Mod(u2, nToSPlusOne)
 	pu2 := new(big.Int).Mul(p.U2, new(big.Int).Exp(w, p.E, nToSPlusOne))
 	pu2.Mod(pu2, nToSPlusOne)
 
-	u3 := new(big.Int).Exp(h1, p.S1, nTilde)
-	u3.Mul(u3, new(big.Int).Exp(h2, p.S3, nTilde)).
-		Mod(u3, nTilde)
+	u3 := meta.expH1H2(p.S1, p.S3)
 	pu3 := new(big.Int).Mul(p.U3, new(big.Int).Exp(p.Z, p.E, nTilde))
 	pu3.Mod(pu3, nTilde)
 
-	hash.Reset()
+	hash := sha256.New()
 	hash.Write(meta.G().Bytes(meta.Curve()))
 	hash.Write(yi.Bytes(meta.Curve()))
 	hash.Write(w.Bytes())
@@ -230,15 +227,13 @@ func (p *KeyGenZKProof) Verify(meta *KeyMeta, vals ...interface{}) error {
 // NewSigZKProof creates the SigZKProof used by the protocol. This implementation is based on the original one by the
 // authors of the paper.
 func NewSigZKProof(meta *KeyMeta, p *SigZKProofParams) (proof *SigZKProof, err error) {
+	defer startSpan(SpanNewSigProof).end(&err)
 	cache := meta.Paillier.Cache()
 	n := meta.Paillier.N
 
 	q := meta.Q()
 	nToSPlusOne := cache.NToSPlusOne
 	nTilde := meta.NTilde
-	h1 := meta.H1
-	h2 := meta.H2
-	nPlusOne := cache.NPlusOne
 
 	w1, err := p.EncVi.ToPaillier(meta.PubKey.Paillier)
 	if err != nil {
@@ -314,30 +309,24 @@ func NewSigZKProof(meta *KeyMeta, p *SigZKProofParams) (proof *SigZKProof, err e
 		return
 	}
 
-	z1 := new(big.Int).Exp(h1, p.Eta1, nTilde)
-	z1.Mul(z1, new(big.Int).Exp(h2, rho1, nTilde)).Mod(z1, nTilde)
-	z2 := new(big.Int).Exp(h1, p.Eta2, nTilde)
-	z2.Mul(z2, new(big.Int).Exp(h2, rho2, nTilde)).Mod(z2, nTilde)
-	z3 := new(big.Int).Exp(h1, p.Eta3, nTilde)
-	z3.Mul(z3, new(big.Int).Exp(h2, rho3, nTilde)).Mod(z3, nTilde)
+	z1 := meta.expH1H2(p.Eta1, rho1)
+	z2 := meta.expH1H2(p.Eta2, rho2)
+	z3 := meta.expH1H2(p.Eta3, rho3)
 
-	u1 := NewZero().BaseMul(meta.Curve(), alpha1)
+	u1 := meta.baseMul(alpha1)
 
-	u2 := new(big.Int).Exp(nPlusOne, alpha1, nToSPlusOne)
+	u2 := meta.expGenerator(alpha1)
 	u2.Mul(u2, new(big.Int).Exp(beta1, n, nToSPlusOne)).Mod(u2, nToSPlusOne)
-	u3 := new(big.Int).Exp(nPlusOne, alpha2, nToSPlusOne)
+	u3 := meta.expGenerator(alpha2)
 	u3.Mul(u3, new(big.Int).Exp(beta2, n, nToSPlusOne)).Mod(u3, nToSPlusOne)
-	u4 := new(big.Int).Exp(nPlusOne, alpha3, nToSPlusOne)
+	u4 := meta.expGenerator(alpha3)
 	u4.Mul(u4, new(big.Int).Exp(beta3, n, nToSPlusOne)).Mod(u4, nToSPlusOne)
 
-	v1 := new(big.Int).Exp(h1, alpha1, nTilde)
-	v1.Mul(v1, new(big.Int).Exp(h2, gamma1, nTilde)).Mod(v1, nTilde)
-	v2 := new(big.Int).Exp(h1, alpha2, nTilde)
-	v2.Mul(v2, new(big.Int).Exp(h2, gamma2, nTilde)).Mod(v2, nTilde)
-	v3 := new(big.Int).Exp(h1, alpha3, nTilde)
-	v3.Mul(v3, new(big.Int).Exp(h2, gamma3, nTilde)).Mod(v3, nTilde)
+	v1 := meta.expH1H2(alpha1, gamma1)
+	v2 := meta.expH1H2(alpha2, gamma2)
+	v3 := meta.expH1H2(alpha3, gamma3)
 
-	hash.Reset()
+	hash := sha256.New()
 	hash.Write(meta.G().Bytes(meta.Curve()))
 	hash.Write(p.Ri.Bytes(meta.Curve()))
 	hash.Write(w1.Bytes())
@@ -403,7 +392,8 @@ func NewSigZKProof(meta *KeyMeta, p *SigZKProofParams) (proof *SigZKProof, err e
 // Verify verifies a ZKProof of SigZKProof type. It receives the key metainfo and 4 arguments, representing
 // a random point share used in the signing process, and a three random values encrypted and used as shares of other
 // values of the protocol.
-func (p *SigZKProof) Verify(meta *KeyMeta, vals ...interface{}) error {
+func (p *SigZKProof) Verify(meta *KeyMeta, vals ...interface{}) (err error) {
+	defer startSpan(SpanVerifySigProof).end(&err)
 	if len(vals) != 4 {
 		return fmt.Errorf("the verification requires three values: Ri (*Point), vi, ui and wi (*l2fhe.EncryptedL1)")
 	}
@@ -423,19 +413,20 @@ func (p *SigZKProof) Verify(meta *KeyMeta, vals ...interface{}) error {
 	if !ok {
 		return fmt.Errorf("decryption share verification requires a *EncryptedL1 as fourth  argument")
 	}
+	if err = r.Validate(meta.Curve()); err != nil {
+		return fmt.Errorf("invalid Ri: %s", err)
+	}
+	if err = p.U1.Validate(meta.Curve()); err != nil {
+		return fmt.Errorf("invalid U1: %s", err)
+	}
 
 	cache := meta.Paillier.Cache()
 	n := meta.Paillier.N
 
 	nToSPlusOne := cache.NToSPlusOne
 	nTilde := meta.NTilde
-	h1 := meta.H1
-	h2 := meta.H2
-	nPlusOne := cache.NPlusOne
 	minusE := new(big.Int).Neg(p.E)
 
-	g := meta.G()
-
 	ui, err := uiFHE.ToPaillier(meta.PubKey.Paillier)
 	if err != nil {
 		return err
@@ -449,13 +440,13 @@ func (p *SigZKProof) Verify(meta *KeyMeta, vals ...interface{}) error {
 		return err
 	}
 
-	u1 := NewZero().Mul(meta.Curve(), g, p.S1)
+	u1 := meta.baseMul(p.S1)
 	pu1 := NewZero().Add(meta.Curve(), p.U1, NewZero().Mul(meta.Curve(), r, p.E))
 
 	if pu1.Cmp(u1) != 0 {
 		return fmt.Errorf("zkproof failed (U1)")
 	}
-	u2 := new(big.Int).Exp(nPlusOne, p.S1, nToSPlusOne)
+	u2 := meta.expGenerator(p.S1)
 	u2.Mul(u2, new(big.Int).Exp(p.T1, n, nToSPlusOne)).
 		Mul(u2, new(big.Int).Exp(vi, minusE, nToSPlusOne)).
 		Mod(u2, nToSPlusOne)
@@ -464,7 +455,7 @@ func (p *SigZKProof) Verify(meta *KeyMeta, vals ...interface{}) error {
 		return fmt.Errorf("zkproof failed (U2)")
 	}
 
-	u3 := new(big.Int).Exp(nPlusOne, p.S4, nToSPlusOne)
+	u3 := meta.expGenerator(p.S4)
 	u3.Mul(u3, new(big.Int).Exp(p.T2, n, nToSPlusOne)).
 		Mul(u3, new(big.Int).Exp(ui, minusE, nToSPlusOne)).
 		Mod(u3, nToSPlusOne)
@@ -473,7 +464,7 @@ func (p *SigZKProof) Verify(meta *KeyMeta, vals ...interface{}) error {
 		return fmt.Errorf("zkproof failed (U3)")
 	}
 
-	u4 := new(big.Int).Exp(nPlusOne, p.S6, nToSPlusOne)
+	u4 := meta.expGenerator(p.S6)
 	u4.Mul(u4, new(big.Int).Exp(p.T3, n, nToSPlusOne)).
 		Mul(u4, new(big.Int).Exp(wi, minusE, nToSPlusOne)).
 		Mod(u4, nToSPlusOne)
@@ -482,35 +473,42 @@ func (p *SigZKProof) Verify(meta *KeyMeta, vals ...interface{}) error {
 		return fmt.Errorf("zkproof failed (U4)")
 	}
 
-	v1 := new(big.Int).Exp(h1, p.S1, nTilde)
-	v1.Mul(v1, new(big.Int).Exp(h2, p.S3, nTilde)).
-		Mul(v1, new(big.Int).Exp(p.Z1, minusE, nTilde)).
+	v1 := meta.expH1H2(p.S1, p.S3)
+	v1.Mul(v1, new(big.Int).Exp(p.Z1, minusE, nTilde)).
 		Mod(v1, nTilde)
 
 	if p.V1.Cmp(v1) != 0 {
 		return fmt.Errorf("zkproof failed (V1)")
 	}
 
-	v2 := new(big.Int).Exp(h1, p.S4, nTilde)
-	v2.Mul(v2, new(big.Int).Exp(h2, p.S5, nTilde)).
-		Mul(v2, new(big.Int).Exp(p.Z2, minusE, nTilde)).
+	v2 := meta.expH1H2(p.S4, p.S5)
+	v2.Mul(v2, new(big.Int).Exp(p.Z2, minusE, nTilde)).
 		Mod(v2, nTilde)
 
 	if p.V2.Cmp(v2) != 0 {
 		return fmt.Errorf("zkproof failed (V2)")
 	}
 
-	v3 := new(big.Int).Exp(h1, p.S6, nTilde)
-	v3.Mul(v3, new(big.Int).Exp(h2, p.S7, nTilde)).
-		Mul(v3, new(big.Int).Exp(p.Z3, minusE, nTilde)).
+	v3 := meta.expH1H2(p.S6, p.S7)
+	v3.Mul(v3, new(big.Int).Exp(p.Z3, minusE, nTilde)).
 		Mod(v3, nTilde)
 
 	if p.V3.Cmp(v3) != 0 {
 		return fmt.Errorf("zkproof failed (V3)")
 	}
 
-	hash.Reset()
-	hash.Write(g.Bytes(meta.Curve()))
+	e := sigZKProofChallenge(meta, p, r, vi, ui, wi)
+	if p.E.Cmp(e) != 0 {
+		return fmt.Errorf("zkproof failed (hash)")
+	}
+	return nil
+}
+
+// sigZKProofChallenge returns the challenge of a SigZKProof, which is the hash of the public values and the
+// commitments of the proof.
+func sigZKProofChallenge(meta *KeyMeta, p *SigZKProof, r *Point, vi, ui, wi *big.Int) *big.Int {
+	hash := sha256.New()
+	hash.Write(meta.G().Bytes(meta.Curve()))
 	hash.Write(r.Bytes(meta.Curve()))
 	hash.Write(vi.Bytes())
 	hash.Write(ui.Bytes())
@@ -524,12 +522,6 @@ func (p *SigZKProof) Verify(meta *KeyMeta, vals ...interface{}) error {
 	hash.Write(p.V1.Bytes())
 	hash.Write(p.V2.Bytes())
 	hash.Write(p.V3.Bytes())
-
 	eHash := hash.Sum(nil)
-	e := new(big.Int).SetBytes(eHash)
-
-	if p.E.Cmp(e) != 0 {
-		return fmt.Errorf("zkproof failed (hash)")
-	}
-	return nil
+	return new(big.Int).SetBytes(eHash)
 }